@@ -31,8 +31,12 @@ import (
 )
 
 var (
-	DeploymentKind = apps.SchemeGroupVersion.WithKind("Deployment")
-	CloneSetKind   = kruiseappsv1alpha1.SchemeGroupVersion.WithKind("CloneSet")
+	DeploymentKind          = apps.SchemeGroupVersion.WithKind("Deployment")
+	StatefulSetKind         = apps.SchemeGroupVersion.WithKind("StatefulSet")
+	DaemonSetKind           = apps.SchemeGroupVersion.WithKind("DaemonSet")
+	CloneSetKind            = kruiseappsv1alpha1.SchemeGroupVersion.WithKind("CloneSet")
+	AdvancedStatefulSetKind = kruiseappsv1beta1.SchemeGroupVersion.WithKind("StatefulSet")
+	AdvancedDaemonSetKind   = kruiseappsv1alpha1.SchemeGroupVersion.WithKind("DaemonSet")
 )
 
 var Scheme = scheme.Scheme
@@ -52,13 +56,13 @@ func GetScheme() *runtime.Scheme {
 
 type ResourceRef struct {
 	// API version of the object.
-	APIVersion string
+	APIVersion string `json:"apiVersion"`
 	// Kind of the object.
-	Kind string
+	Kind string `json:"kind"`
 	// Namespace of the object.
-	Namespace string
+	Namespace string `json:"namespace"`
 	// Name of the object.
-	Name string
+	Name string `json:"name"`
 }
 
 func (rf *ResourceRef) GetGroupVersionKind() schema.GroupVersionKind {
@@ -86,3 +90,39 @@ func NewCloneSetRef(namespace, name string) ResourceRef {
 		Name:       name,
 	}
 }
+
+func NewStatefulSetRef(namespace, name string) ResourceRef {
+	return ResourceRef{
+		APIVersion: StatefulSetKind.GroupVersion().String(),
+		Kind:       StatefulSetKind.Kind,
+		Namespace:  namespace,
+		Name:       name,
+	}
+}
+
+func NewAdvancedStatefulSetRef(namespace, name string) ResourceRef {
+	return ResourceRef{
+		APIVersion: AdvancedStatefulSetKind.GroupVersion().String(),
+		Kind:       AdvancedStatefulSetKind.Kind,
+		Namespace:  namespace,
+		Name:       name,
+	}
+}
+
+func NewDaemonSetRef(namespace, name string) ResourceRef {
+	return ResourceRef{
+		APIVersion: DaemonSetKind.GroupVersion().String(),
+		Kind:       DaemonSetKind.Kind,
+		Namespace:  namespace,
+		Name:       name,
+	}
+}
+
+func NewAdvancedDaemonSetRef(namespace, name string) ResourceRef {
+	return ResourceRef{
+		APIVersion: AdvancedDaemonSetKind.GroupVersion().String(),
+		Kind:       AdvancedDaemonSetKind.Kind,
+		Namespace:  namespace,
+		Name:       name,
+	}
+}
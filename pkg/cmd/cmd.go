@@ -23,6 +23,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/openkruise/kruise-tools/pkg/cmd/convert"
 	"github.com/openkruise/kruise-tools/pkg/cmd/create"
 	"github.com/openkruise/kruise-tools/pkg/cmd/describe"
 	cmdexec "github.com/openkruise/kruise-tools/pkg/cmd/exec"
@@ -391,6 +392,8 @@ func NewKubectlCommand(in io.Reader, out, err io.Writer) *cobra.Command {
 				krollout.NewCmdRollout(f, ioStreams),
 				kset.NewCmdSet(f, ioStreams),
 				migrate.NewCmdMigrate(f, ioStreams),
+				migrate.NewCmdMigrationServer(f, ioStreams),
+				convert.NewCmdConvert(f, ioStreams),
 			},
 		},
 		{
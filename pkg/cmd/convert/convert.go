@@ -0,0 +1,243 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/conversion"
+	"github.com/spf13/cobra"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// manifestExtensions are the extensions of the files read from a directory.
+var manifestExtensions = []string{".yaml", ".yml", ".json"}
+
+type ConvertOptions struct {
+	To                []string
+	ConversionProfile string
+
+	toKinds []schema.GroupVersionKind
+	profile conversion.CloneSetProfile
+
+	resource.FilenameOptions
+	genericclioptions.IOStreams
+}
+
+func NewConvertOptions(ioStreams genericclioptions.IOStreams) *ConvertOptions {
+	return &ConvertOptions{IOStreams: ioStreams}
+}
+
+func NewCmdConvert(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	o := NewConvertOptions(ioStreams)
+
+	cmd := &cobra.Command{
+		Use:                   "convert -f FILENAME [--to KIND]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Convert manifests of K8s original workloads to Kruise workloads",
+		Long: `Convert manifests of K8s original workloads to Kruise workloads without accessing the cluster.
+
+Deployments are converted to CloneSets, StatefulSets to Advanced StatefulSets and DaemonSets to Advanced DaemonSets.
+The other objects are written as they are, and the comments and the order of the fields kept by the conversion are preserved.`,
+		Example: `
+	# Convert the Deployments in deploy.yaml to CloneSets.
+	kubectl-kruise convert -f deploy.yaml --to CloneSet
+
+	# Convert the workloads in all manifests of a directory to their Kruise equivalents.
+	kubectl-kruise convert -f ./manifests -R > ./kruise-manifests.yaml
+
+	# Convert the StatefulSets of a kustomization to Advanced StatefulSets.
+	kubectl-kruise convert -k ./overlays/prod --to StatefulSet.apps.kruise.io
+
+	# Convert the Deployments read from stdin to CloneSets that update pods in place.
+	cat deploy.yaml | kubectl-kruise convert -f - --to CloneSet --conversion-profile profile.yaml
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(cmd, args))
+			cmdutil.CheckErr(o.Run())
+		},
+	}
+
+	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, "to read the manifests to convert from")
+	cmd.Flags().StringSliceVar(&o.To, "to", nil, "Kinds to convert to (e.g. CloneSet, StatefulSet.apps.kruise.io, DaemonSet.apps.kruise.io), defaults to all of them.")
+	cmd.Flags().StringVar(&o.ConversionProfile, "conversion-profile", "", "Path of a YAML or JSON file with the fields of the CloneSets that Deployments have no counterpart for.")
+
+	return cmd
+}
+
+func (o *ConvertOptions) Complete(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return cmdutil.UsageErrorf(cmd, "unexpected args: %v", args)
+	} else if err := o.FilenameOptions.RequireFilenameOrKustomize(); err != nil {
+		return err
+	}
+
+	if len(o.To) == 0 {
+		o.To = []string{"CloneSet", "StatefulSet.apps.kruise.io", "DaemonSet.apps.kruise.io"}
+	}
+	for _, to := range o.To {
+		switch to {
+		case "CloneSet", "cloneset", "clone":
+			o.toKinds = append(o.toKinds, api.CloneSetKind)
+		case "StatefulSet.apps.kruise.io", "statefulset.apps.kruise.io", "AdvancedStatefulSet", "advancedstatefulset", "asts":
+			o.toKinds = append(o.toKinds, api.AdvancedStatefulSetKind)
+		case "DaemonSet.apps.kruise.io", "daemonset.apps.kruise.io", "AdvancedDaemonSet", "advanceddaemonset", "ads", "daemon":
+			o.toKinds = append(o.toKinds, api.AdvancedDaemonSetKind)
+		default:
+			return fmt.Errorf("currently only supported CloneSet, StatefulSet.apps.kruise.io and DaemonSet.apps.kruise.io to convert to")
+		}
+	}
+
+	if len(o.ConversionProfile) > 0 {
+		profile, err := conversion.LoadCloneSetProfile(o.ConversionProfile)
+		if err != nil {
+			return err
+		}
+		o.profile = profile
+	}
+	return nil
+}
+
+func (o *ConvertOptions) Run() error {
+	nodes, err := o.readManifests()
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		for _, to := range o.toKinds {
+			from := conversion.ManifestKinds[to]
+			name := node.GetName()
+			converted, report, err := conversion.ConvertManifest(node, to, &o.profile)
+			if err != nil {
+				return err
+			} else if !converted {
+				continue
+			}
+			for _, field := range report {
+				if field.Action == conversion.FieldDropped {
+					fmt.Fprintf(o.ErrOut, "Warning: %s/%s: dropped %s, %s\n", from.Kind, name, field.Field, field.Detail)
+				}
+			}
+			break
+		}
+	}
+
+	return kio.ByteWriter{Writer: o.Out}.Write(nodes)
+}
+
+// readManifests reads the objects from the kustomization, or from the files, directories and stdin.
+func (o *ConvertOptions) readManifests() ([]*yaml.RNode, error) {
+	if len(o.Kustomize) > 0 {
+		resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(filesys.MakeFsOnDisk(), o.Kustomize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build kustomization %s: %v", o.Kustomize, err)
+		}
+		data, err := resources.AsYaml()
+		if err != nil {
+			return nil, err
+		}
+		return parseManifests(data, o.Kustomize)
+	}
+
+	var nodes []*yaml.RNode
+	for _, filename := range o.Filenames {
+		var files []string
+		switch info, err := os.Stat(filename); {
+		case filename == "-":
+			data, err := io.ReadAll(o.In)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read stdin: %v", err)
+			}
+			fileNodes, err := parseManifests(data, "stdin")
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, fileNodes...)
+			continue
+		case strings.HasPrefix(filename, "http://") || strings.HasPrefix(filename, "https://"):
+			return nil, fmt.Errorf("can not read %s, only local files are supported", filename)
+		case err != nil:
+			return nil, err
+		case info.IsDir():
+			if files, err = manifestFiles(filename, o.Recursive); err != nil {
+				return nil, err
+			}
+		default:
+			files = []string{filename}
+		}
+
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			fileNodes, err := parseManifests(data, file)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, fileNodes...)
+		}
+	}
+	return nodes, nil
+}
+
+// manifestFiles returns the manifest files in the directory, and in its subdirectories if recursive.
+func manifestFiles(dir string, recursive bool) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		for _, ext := range manifestExtensions {
+			if filepath.Ext(path) == ext {
+				files = append(files, path)
+				break
+			}
+		}
+		return nil
+	})
+	return files, err
+}
+
+func parseManifests(data []byte, source string) ([]*yaml.RNode, error) {
+	nodes, err := (&kio.ByteReader{Reader: bytes.NewReader(data), OmitReaderAnnotations: true}).Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifests from %s: %v", source, err)
+	}
+	return nodes, nil
+}
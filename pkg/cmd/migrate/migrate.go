@@ -17,11 +17,22 @@ limitations under the License.
 package migrate
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/conversion"
+	"github.com/openkruise/kruise-tools/pkg/creation"
+	"github.com/openkruise/kruise-tools/pkg/migration"
 	"github.com/spf13/cobra"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 )
@@ -39,14 +50,36 @@ type migrateOptions struct {
 	IsCreate       bool
 	IsCopy         bool
 	Replicas       int32
-	MaxSurge       int32
+	MaxSurge       string
+	MaxUnavailable string
 	TimeoutSeconds int32
+	NodeSelector   string
+
+	ConversionProfile         string
+	UpdateStrategyType        string
+	InPlaceGracePeriodSeconds int32
+	KeepFinalizers            bool
+	CloneSetProfile           conversion.CloneSetProfile
+
+	Resume   string
+	Rollback string
+
+	Selector        string
+	AllNamespaces   bool
+	Concurrency     int
+	MaxPerNamespace int
+
+	DryRunStrategy cmdutil.DryRunStrategy
+	PrintFlags     *genericclioptions.PrintFlags
 
 	genericclioptions.IOStreams
 }
 
 func newMigrateOptions(ioStreams genericclioptions.IOStreams) *migrateOptions {
-	return &migrateOptions{IOStreams: ioStreams}
+	return &migrateOptions{
+		PrintFlags: genericclioptions.NewPrintFlags("created").WithTypeSetter(api.GetScheme()),
+		IOStreams:  ioStreams,
+	}
 }
 
 func NewCmdMigrate(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
@@ -56,7 +89,7 @@ func NewCmdMigrate(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *co
 		Use:                   "migrate [DST_KIND] --from [SRC_KIND] [flags]",
 		DisableFlagsInUseLine: true,
 		Short:                 "Migrate from K8s original workloads to Kruise workloads",
-		Long:                  "Migrate from K8s original workloads to Kruise workloads",
+		Long:                  "Migrate from K8s original workloads to Kruise workloads, or from CloneSet back to Deployment",
 		Example: `
 	# Create an empty CloneSet from an existing Deployment.
 	kubectl-kruise migrate CloneSet --from Deployment -n default --dst-name deployment-name --create
@@ -64,8 +97,47 @@ func NewCmdMigrate(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *co
 	# Create a same replicas CloneSet from an existing Deployment.
 	kubectl-kruise migrate CloneSet --from Deployment -n default --dst-name deployment-name --create --copy
 
+	# Create a CloneSet that updates pods in place from an existing Deployment, with the rest configured by a profile file.
+	kubectl-kruise migrate CloneSet --from Deployment -n default --src-name deployment-name --dst-name cloneset-name --create --update-strategy-type=InPlaceIfPossible --conversion-profile=profile.yaml
+
 	# Migrate replicas from an existing Deployment to an existing CloneSet.
 	kubectl-kruise migrate CloneSet --from Deployment -n default --src-name cloneset-name --dst-name deployment-name --replicas 10 --max-surge=2
+
+	# Migrate replicas from a CloneSet back to an existing Deployment.
+	kubectl-kruise migrate Deployment --from CloneSet -n default --src-name cloneset-name --dst-name deployment-name --max-surge=2
+
+	# Create an empty Advanced StatefulSet with the same name as an existing StatefulSet.
+	kubectl-kruise migrate StatefulSet.apps.kruise.io --from StatefulSet -n default --src-name statefulset-name --create
+
+	# Migrate ordinals from an existing StatefulSet to the Advanced StatefulSet, two at a time.
+	kubectl-kruise migrate StatefulSet.apps.kruise.io --from StatefulSet -n default --src-name statefulset-name --max-surge=2
+
+	# Create an Advanced DaemonSet from an existing DaemonSet, running on no node yet.
+	kubectl-kruise migrate DaemonSet.apps.kruise.io --from DaemonSet -n default --src-name daemonset-name --dst-name advanced-daemonset-name --create
+
+	# Hand the nodes in zone-a over from the DaemonSet to the Advanced DaemonSet, five nodes at a time.
+	kubectl-kruise migrate DaemonSet.apps.kruise.io --from DaemonSet -n default --src-name daemonset-name --dst-name advanced-daemonset-name --node-selector zone=zone-a --max-surge=5
+
+	# Print the steps of migrating replicas from an existing Deployment to an existing CloneSet, without changing them.
+	kubectl-kruise migrate CloneSet --from Deployment -n default --src-name deployment-name --dst-name cloneset-name --max-surge=2 --dry-run=client
+
+	# Validate the CloneSet generated from an existing Deployment against the API server, and print it.
+	kubectl-kruise migrate CloneSet --from Deployment -n default --src-name deployment-name --dst-name cloneset-name --create --dry-run=server -o yaml
+
+	# Migrate replicas from an existing Deployment to an existing CloneSet, printing a JSON object for each change of the progress.
+	kubectl-kruise migrate CloneSet --from Deployment -n default --src-name deployment-name --dst-name cloneset-name --max-surge=2 -o json
+
+	# Resume a migration task recorded in the cluster, e.g. after the previous process exited.
+	kubectl-kruise migrate -n default --resume 1f8a5c62-0d1e-4b8c-9a61-3c2b7e0f4d55
+
+	# Roll a migration task back, scaling the source back up and the destination back down.
+	kubectl-kruise migrate -n default --rollback 1f8a5c62-0d1e-4b8c-9a61-3c2b7e0f4d55
+
+	# Create a CloneSet for each Deployment of the payments team in all namespaces and migrate them, three at a time.
+	kubectl-kruise migrate CloneSet --from Deployment -l team=payments --all-namespaces --concurrency=3
+
+	# List the migration tasks recorded in the namespace.
+	kubectl-kruise migrate list -n default
 `,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmdutil.CheckErr(o.Complete(f, cmd, args))
@@ -73,15 +145,33 @@ func NewCmdMigrate(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *co
 		},
 	}
 
-	cmd.Flags().StringVar(&o.From, "from", "", "Type of the source workload (e.g. Deployment).")
+	cmd.Flags().StringVar(&o.From, "from", "", "Type of the source workload (e.g. Deployment, StatefulSet, DaemonSet, CloneSet).")
 	cmd.Flags().StringVar(&o.SrcName, "src-name", "", "Name of the source workload.")
 	cmd.Flags().StringVar(&o.DstName, "dst-name", "", "Name of the destination workload.")
 
 	cmd.Flags().BoolVar(&o.IsCreate, "create", false, "Create dst workload with replicas=0 from src workload.")
 	cmd.Flags().BoolVar(&o.IsCopy, "copy", false, "Copy replicas from src workload when create.")
-	cmd.Flags().Int32Var(&o.Replicas, "replicas", -1, "The replicas needs to migrate, -1 indicates all replicas in src workload.")
-	cmd.Flags().Int32Var(&o.MaxSurge, "max-surge", 1, "Max surge during migration.")
+	cmd.Flags().Int32Var(&o.Replicas, "replicas", -1, "The replicas needs to migrate, -1 indicates all replicas in src workload. It counts nodes for DaemonSet.")
+	cmd.Flags().StringVar(&o.MaxSurge, "max-surge", "", "Max surge during migration as a number or a percentage of replicas, defaults to 1 if --max-unavailable is not set. It is the number of nodes handed over in each step for DaemonSet.")
+	cmd.Flags().StringVar(&o.MaxUnavailable, "max-unavailable", "", "Max unavailable during migration as a number or a percentage of replicas, which allows scaling in src before dst is available. It is used when --max-surge is 0 for DaemonSet.")
 	cmd.Flags().Int32Var(&o.TimeoutSeconds, "timeout-seconds", -1, "Timeout seconds for migration, -1 indicates no limited.")
+	cmd.Flags().StringVar(&o.NodeSelector, "node-selector", "", "Label selector of the nodes to hand over for DaemonSet, defaults to all nodes running src workload.")
+	cmd.Flags().StringVar(&o.ConversionProfile, "conversion-profile", "", "Path of a YAML or JSON file with the fields of the CloneSet created from a Deployment that have no counterpart in it: updateStrategyType, inPlaceUpdateStrategy, scaleStrategy, lifecycle and keepFinalizers.")
+	cmd.Flags().StringVar(&o.UpdateStrategyType, "update-strategy-type", "", "Update strategy type of the CloneSet created from a Deployment, one of ReCreate, InPlaceIfPossible and InPlaceOnly. Overrides the conversion profile.")
+	cmd.Flags().Int32Var(&o.InPlaceGracePeriodSeconds, "in-place-grace-period-seconds", 0, "Grace period seconds of in-place update of the CloneSet created from a Deployment. Overrides the conversion profile.")
+	cmd.Flags().BoolVar(&o.KeepFinalizers, "keep-finalizers", false, "Copy the finalizers of the Deployment into the CloneSet created from it. Overrides the conversion profile.")
+	cmd.Flags().StringVarP(&o.Selector, "selector", "l", "", "Label selector of the source workloads to migrate, each into a destination workload with the same name which is created if it does not exist.")
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "Select the source workloads in all namespaces with --selector.")
+	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Max number of migrations running at the same time with --selector.")
+	cmd.Flags().IntVar(&o.MaxPerNamespace, "max-per-namespace", 0, "Max number of migrations running in a namespace at the same time with --selector, 0 indicates no limited.")
+	cmd.Flags().StringVar(&o.Resume, "resume", "", "ID of a migration task recorded in the cluster to resume, other flags and args are ignored.")
+	cmd.Flags().StringVar(&o.Rollback, "rollback", "", "ID of a migration task recorded in the cluster to roll back, other flags and args are ignored.")
+
+	cmdutil.AddDryRunFlag(cmd)
+	o.PrintFlags.AddFlags(cmd)
+
+	cmd.AddCommand(NewCmdMigrateList(f, ioStreams))
+	cmd.AddCommand(NewCmdMigrateStatus(f, ioStreams))
 
 	return cmd
 }
@@ -90,11 +180,34 @@ func (o *migrateOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []
 	namespace, explicitNamespace, err := f.ToRawKubeConfigLoader().Namespace()
 	if err != nil {
 		return err
+	} else if o.AllNamespaces && len(o.Selector) > 0 {
+		namespace = ""
+	} else if o.AllNamespaces {
+		return fmt.Errorf("--all-namespaces is only supported with --selector")
 	} else if !explicitNamespace {
 		return fmt.Errorf("must specify namespace by -n or --namespace")
 	}
 	o.Namespace = namespace
 
+	o.DryRunStrategy, err = cmdutil.GetDryRunStrategy(cmd)
+	if err != nil {
+		return err
+	}
+	cmdutil.PrintFlagsWithDryRunStrategy(o.PrintFlags, o.DryRunStrategy)
+
+	if (len(o.Resume) > 0 || len(o.Rollback) > 0) && o.DryRunStrategy != cmdutil.DryRunNone {
+		return fmt.Errorf("can not dry run --resume or --rollback")
+	} else if len(o.Resume) > 0 && len(o.Rollback) > 0 {
+		return fmt.Errorf("can not specify both --resume and --rollback")
+	} else if len(o.Resume) > 0 || len(o.Rollback) > 0 {
+		if len(args) > 0 {
+			return fmt.Errorf("can not specify workload type with --resume or --rollback")
+		} else if o.PrintFlags.OutputFlagSpecified() && !o.streamsProgress() {
+			return fmt.Errorf("--output of --resume or --rollback only supports json to stream the progress")
+		}
+		return nil
+	}
+
 	if len(args) == 0 {
 		return fmt.Errorf("must specify workload type like CloneSet")
 	} else if len(args) > 1 {
@@ -104,36 +217,304 @@ func (o *migrateOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []
 	if len(o.From) == 0 {
 		return fmt.Errorf("must specify --from")
 	}
-	if len(o.SrcName) == 0 {
+	if len(o.Selector) > 0 {
+		if err := o.completeBatch(); err != nil {
+			return err
+		}
+	} else if len(o.SrcName) == 0 {
 		return fmt.Errorf("must specify --src-name")
 	}
-	if len(o.DstName) == 0 && !o.IsCreate {
-		return fmt.Errorf("must specify --dst-name")
-	}
 
 	switch args[0] {
 	case "CloneSet", "cloneset", "clone":
 		o.To = "CloneSet"
-		o.DstRef = api.NewCloneSetRef(namespace, o.DstName)
+	case "StatefulSet.apps.kruise.io", "statefulset.apps.kruise.io", "AdvancedStatefulSet", "advancedstatefulset", "asts":
+		o.To = "AdvancedStatefulSet"
+		// Advanced StatefulSet takes over the pods and PVCs of the StatefulSet, which are named after it.
+		if len(o.DstName) == 0 {
+			o.DstName = o.SrcName
+		}
+	case "DaemonSet.apps.kruise.io", "daemonset.apps.kruise.io", "AdvancedDaemonSet", "advanceddaemonset", "ads", "daemon":
+		o.To = "AdvancedDaemonSet"
+	case "Deployment", "deployment", "deploy":
+		o.To = "Deployment"
 	default:
-		return fmt.Errorf("currently only supported CloneSet as dst type")
+		return fmt.Errorf("currently only supported CloneSet, StatefulSet.apps.kruise.io, DaemonSet.apps.kruise.io and Deployment as dst type")
+	}
+	if len(o.DstName) == 0 && !o.IsCreate && len(o.Selector) == 0 {
+		return fmt.Errorf("must specify --dst-name")
 	}
 
 	switch o.From {
 	case "Deployment", "deployment":
 		o.From = "Deployment"
+	case "StatefulSet", "statefulset", "sts":
+		o.From = "StatefulSet"
+	case "DaemonSet", "daemonset", "ds":
+		o.From = "DaemonSet"
+	case "CloneSet", "cloneset", "clone":
+		o.From = "CloneSet"
+	default:
+		return fmt.Errorf("currently only supported Deployment, StatefulSet, DaemonSet and CloneSet as src type")
+	}
+
+	switch {
+	case o.To == "CloneSet" && o.From == "Deployment":
 		o.SrcRef = api.NewDeploymentRef(namespace, o.SrcName)
+		o.DstRef = api.NewCloneSetRef(namespace, o.DstName)
+	case o.To == "AdvancedStatefulSet" && o.From == "StatefulSet":
+		o.SrcRef = api.NewStatefulSetRef(namespace, o.SrcName)
+		o.DstRef = api.NewAdvancedStatefulSetRef(namespace, o.DstName)
+	case o.To == "AdvancedDaemonSet" && o.From == "DaemonSet":
+		o.SrcRef = api.NewDaemonSetRef(namespace, o.SrcName)
+		o.DstRef = api.NewAdvancedDaemonSetRef(namespace, o.DstName)
+	case o.To == "Deployment" && o.From == "CloneSet":
+		o.SrcRef = api.NewCloneSetRef(namespace, o.SrcName)
+		o.DstRef = api.NewDeploymentRef(namespace, o.DstName)
 	default:
-		return fmt.Errorf("currently only supported Deployment as src type")
+		return fmt.Errorf("unsupported migration from %s to %s", o.From, o.To)
+	}
+
+	for _, name := range []string{"conversion-profile", "update-strategy-type", "in-place-grace-period-seconds", "keep-finalizers"} {
+		if cmd.Flags().Changed(name) && ((!o.IsCreate && len(o.Selector) == 0) || o.To != "CloneSet") {
+			return fmt.Errorf("--%s is only supported when creating CloneSet from Deployment", name)
+		}
+	}
+	if (o.IsCreate || len(o.Selector) > 0) && o.To == "CloneSet" {
+		if o.CloneSetProfile, err = o.cloneSetProfile(cmd); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
 func (o *migrateOptions) Run(f cmdutil.Factory, cmd *cobra.Command) error {
+	if len(o.Resume) > 0 || len(o.Rollback) > 0 {
+		return o.resumeMigration(f)
+	} else if len(o.Selector) > 0 {
+		return o.runBatchMigration(f)
+	}
+
 	switch o.To {
 	case "CloneSet":
 		return o.migrateCloneSet(f, cmd)
+	case "AdvancedStatefulSet":
+		return o.migrateAdvancedStatefulSet(f, cmd)
+	case "AdvancedDaemonSet":
+		return o.migrateAdvancedDaemonSet(f, cmd)
+	case "Deployment":
+		return o.migrateDeployment(f, cmd)
+	}
+	return nil
+}
+
+func (o *migrateOptions) runCreation(ctrl creation.Control) error {
+	opts := creation.Options{CopyReplicas: o.IsCopy, CloneSetProfile: o.CloneSetProfile}
+	switch o.DryRunStrategy {
+	case cmdutil.DryRunClient:
+		opts.DryRun = creation.DryRunClient
+	case cmdutil.DryRunServer:
+		opts.DryRun = creation.DryRunServer
+	}
+	obj, report, err := ctrl.Create(o.SrcRef, o.DstRef, opts)
+	if err != nil {
+		return err
+	}
+
+	if o.DryRunStrategy != cmdutil.DryRunNone || o.PrintFlags.OutputFlagSpecified() {
+		// keep the printed object parsable
+		printConversionReport(o.ErrOut, report)
+		printer, err := o.PrintFlags.ToPrinter()
+		if err != nil {
+			return err
+		}
+		return printer.PrintObj(obj, o.Out)
 	}
+	printConversionReport(o.Out, report)
+	internalcmdutil.Print(fmt.Sprintf("Successfully created from %s/%s to %s/%s", o.From, o.SrcName, o.To, o.DstName))
 	return nil
 }
+
+func (o *migrateOptions) runMigration(ctrl migration.Control) error {
+	opts, err := o.migrationOptions()
+	if err != nil {
+		return err
+	}
+
+	if o.DryRunStrategy != cmdutil.DryRunNone {
+		plan, err := ctrl.Plan(o.SrcRef, o.DstRef, opts, o.DryRunStrategy == cmdutil.DryRunServer)
+		if err != nil {
+			return err
+		}
+		return o.printPlan(plan)
+	} else if o.PrintFlags.OutputFlagSpecified() && !o.streamsProgress() {
+		return fmt.Errorf("--output of a migration only supports json to stream its progress, other formats are only supported with --create or --dry-run")
+	}
+
+	if err := o.validate(ctrl, o.SrcRef, o.DstRef, opts); err != nil {
+		return err
+	}
+	result, err := ctrl.Submit(o.SrcRef, o.DstRef, opts)
+	if err != nil {
+		return err
+	}
+	internalcmdutil.Print(fmt.Sprintf("Migration task %v submitted, interrupt to roll it back", result.ID))
+	return o.waitForMigration(ctrl, result)
+}
+
+// validate prints the warnings of the pre-flight validation of a task if ctrl supports it,
+// and returns an error if the task would be refused.
+func (o *migrateOptions) validate(ctrl migration.Control, src, dst api.ResourceRef, opts migration.Options) error {
+	validator, ok := ctrl.(migration.Validator)
+	if !ok {
+		return nil
+	}
+	validation, err := validator.Validate(src, dst, opts)
+	if err != nil {
+		return err
+	}
+	printWarnings(o.ErrOut, src, validation.Warnings)
+	return validation.Err()
+}
+
+// controlOptions returns the options of the migration control from the flags.
+func (o *migrateOptions) controlOptions() migration.ControlOptions {
+	opts := migration.ControlOptions{MaxRunningTasksPerNamespace: o.MaxPerNamespace}
+	if len(o.Selector) > 0 {
+		// a worker for each task that may run at the same time
+		opts.MaxConcurrentReconciles = o.Concurrency
+	}
+	return opts
+}
+
+// migrationOptions returns the options of a migration task from the flags.
+func (o *migrateOptions) migrationOptions() (migration.Options, error) {
+	opts := migration.Options{}
+	if o.Replicas >= 0 {
+		opts.Replicas = &o.Replicas
+	}
+	if len(o.MaxSurge) > 0 {
+		maxSurge := intstr.Parse(o.MaxSurge)
+		opts.MaxSurge = &maxSurge
+	}
+	if len(o.MaxUnavailable) > 0 {
+		maxUnavailable := intstr.Parse(o.MaxUnavailable)
+		opts.MaxUnavailable = &maxUnavailable
+	}
+	if o.TimeoutSeconds > 0 {
+		opts.TimeoutSeconds = &o.TimeoutSeconds
+	}
+	if len(o.NodeSelector) > 0 {
+		nodeSelector, err := metav1.ParseToLabelSelector(o.NodeSelector)
+		if err != nil {
+			return opts, err
+		}
+		opts.NodeSelector = nodeSelector
+	}
+	return opts, nil
+}
+
+// waitForMigration prints the progress of the task until it finishes. The first interrupt rolls
+// the task back, and the second one aborts it, so that both workloads are not left mid-flight.
+func (o *migrateOptions) waitForMigration(ctrl migration.Control, oldResult migration.Result) error {
+	interrupts := make(chan os.Signal, 2)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupts)
+
+	if err := o.printProgress(oldResult); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-interrupts:
+			if oldResult.State == migration.MigrateRollingBack {
+				result, err := ctrl.Abort(oldResult.ID)
+				if err != nil {
+					return err
+				}
+				if err := o.printProgress(result); err != nil {
+					return err
+				}
+				return fmt.Errorf("migration task %v aborted with %s/%s scale in %d, %s/%s scale out %d, continue it with --resume or --rollback",
+					result.ID, o.From, o.SrcName, result.SrcMigratedReplicas, o.To, o.DstName, result.DstMigratedReplicas)
+			}
+
+			internalcmdutil.Print(fmt.Sprintf("Interrupted, rolling back migration task %v, interrupt again to abort it", oldResult.ID))
+			result, err := ctrl.Rollback(oldResult.ID)
+			if err != nil {
+				return err
+			}
+			if err := o.printProgress(result); err != nil {
+				return err
+			}
+			oldResult = result
+			continue
+		case <-ticker.C:
+		}
+
+		newResult, err := ctrl.Query(oldResult.ID)
+		if err != nil {
+			return err
+		}
+		if newResult != oldResult {
+			if err := o.printProgress(newResult); err != nil {
+				return err
+			}
+		}
+
+		if newResult.SrcMigratedReplicas != oldResult.SrcMigratedReplicas || newResult.DstMigratedReplicas != oldResult.DstMigratedReplicas {
+			progress := "Migration"
+			if newResult.State == migration.MigrateRollingBack {
+				progress = "Rollback"
+			}
+			internalcmdutil.Print(fmt.Sprintf("%s progress: %s/%s scale in %d, %s/%s scale out %d",
+				progress, o.From, o.SrcName, newResult.SrcMigratedReplicas, o.To, o.DstName, newResult.DstMigratedReplicas))
+		}
+
+		switch newResult.State {
+		case migration.MigrateSucceeded:
+			internalcmdutil.Print(fmt.Sprintf("Successfully migrated %v replicas from %s/%s to %s/%s",
+				newResult.DstMigratedReplicas, o.From, o.SrcName, o.To, o.DstName))
+			return nil
+		case migration.MigrateRolledBack:
+			internalcmdutil.Print(fmt.Sprintf("Successfully rolled back %s/%s and %s/%s", o.From, o.SrcName, o.To, o.DstName))
+			return nil
+		case migration.MigrateFailed:
+			return fmt.Errorf("failed to migrate: %v", newResult.Message)
+		case migration.MigrateAborted:
+			return fmt.Errorf("migration task %v aborted: %v", newResult.ID, newResult.Message)
+		}
+
+		oldResult = newResult
+	}
+}
+
+// progressEvent is an object of the progress stream printed by a migration with -o json.
+type progressEvent struct {
+	Time metav1.Time     `json:"time"`
+	Src  api.ResourceRef `json:"src"`
+	Dst  api.ResourceRef `json:"dst"`
+	migration.Result
+}
+
+// streamsProgress returns whether the progress of a migration is printed as a stream of JSON objects,
+// one for each change of its result.
+func (o *migrateOptions) streamsProgress() bool {
+	return o.PrintFlags.OutputFormat != nil && *o.PrintFlags.OutputFormat == "json"
+}
+
+// printProgress prints result to the progress stream if it is enabled.
+func (o *migrateOptions) printProgress(result migration.Result) error {
+	if !o.streamsProgress() {
+		return nil
+	}
+	data, err := json.Marshal(progressEvent{Time: metav1.Now(), Src: o.SrcRef, Dst: o.DstRef, Result: result})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(o.Out, "%s\n", data)
+	return err
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	statefulsetcreation "github.com/openkruise/kruise-tools/pkg/creation/statefulset"
+	statefulsetmigration "github.com/openkruise/kruise-tools/pkg/migration/statefulset"
+	"github.com/spf13/cobra"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+func (o *migrateOptions) migrateAdvancedStatefulSet(f cmdutil.Factory, cmd *cobra.Command) error {
+	cfg, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+
+	if o.IsCreate {
+		ctrl, err := statefulsetcreation.NewControl(cfg)
+		if err != nil {
+			return err
+		}
+		return o.runCreation(ctrl)
+	}
+
+	stopChan := make(chan struct{})
+	ctrl, err := statefulsetmigration.NewControl(cfg, stopChan, o.controlOptions())
+	if err != nil {
+		return err
+	}
+	return o.runMigration(ctrl)
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/creation"
+	clonesetcreation "github.com/openkruise/kruise-tools/pkg/creation/cloneset"
+	daemonsetcreation "github.com/openkruise/kruise-tools/pkg/creation/daemonset"
+	deploymentcreation "github.com/openkruise/kruise-tools/pkg/creation/deployment"
+	statefulsetcreation "github.com/openkruise/kruise-tools/pkg/creation/statefulset"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/rest"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// batchPollInterval is how often the running tasks of a batch are queried.
+var batchPollInterval = time.Second
+
+// batchTask is the migration of one of the source workloads selected by --selector.
+type batchTask struct {
+	src     api.ResourceRef
+	dst     api.ResourceRef
+	created bool
+	result  migration.Result
+	err     error
+}
+
+func (o *migrateOptions) completeBatch() error {
+	if _, err := labels.Parse(o.Selector); err != nil {
+		return fmt.Errorf("invalid selector %q: %v", o.Selector, err)
+	}
+	switch {
+	case len(o.SrcName) > 0 || len(o.DstName) > 0:
+		return fmt.Errorf("can not specify --src-name or --dst-name with --selector")
+	case o.Replicas >= 0:
+		return fmt.Errorf("can not specify --replicas with --selector, all replicas of each workload are migrated")
+	case o.IsCopy:
+		return fmt.Errorf("can not specify --copy with --selector")
+	case o.DryRunStrategy != cmdutil.DryRunNone || o.PrintFlags.OutputFlagSpecified():
+		return fmt.Errorf("--dry-run and --output are not supported with --selector")
+	case o.Concurrency <= 0:
+		return fmt.Errorf("invalid concurrency %d", o.Concurrency)
+	case o.MaxPerNamespace < 0:
+		return fmt.Errorf("invalid max per namespace %d", o.MaxPerNamespace)
+	}
+	return nil
+}
+
+// runBatchMigration creates the destination workload for each source workload selected by --selector,
+// and migrates them with at most --concurrency tasks running at the same time.
+func (o *migrateOptions) runBatchMigration(f cmdutil.Factory) error {
+	cfg, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(cfg, client.Options{Scheme: api.GetScheme()})
+	if err != nil {
+		return err
+	}
+
+	tasks, err := o.listBatchTasks(c)
+	if err != nil {
+		return err
+	} else if len(tasks) == 0 {
+		internalcmdutil.Print(fmt.Sprintf("No %s found with selector %s", o.From, o.Selector))
+		return nil
+	}
+
+	creationCtrl, err := newCreationControl(cfg, o.DstRef)
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		if t.err = o.ensureDst(c, creationCtrl, t); t.err == nil && t.created {
+			internalcmdutil.Print(fmt.Sprintf("Created %s %s/%s", o.To, t.dst.Namespace, t.dst.Name))
+		}
+	}
+	if o.IsCreate {
+		return o.printBatchSummary(tasks)
+	}
+
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	ctrl, err := newMigrationControl(cfg, o.DstRef, stopChan, o.controlOptions())
+	if err != nil {
+		return err
+	}
+	o.runBatchTasks(ctrl, tasks)
+	return o.printBatchSummary(tasks)
+}
+
+// listBatchTasks lists the source workloads selected by --selector.
+func (o *migrateOptions) listBatchTasks(c client.Client) ([]*batchTask, error) {
+	selector, err := labels.Parse(o.Selector)
+	if err != nil {
+		return nil, err
+	}
+	gvk := o.SrcRef.GetGroupVersionKind()
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := c.List(context.TODO(), list, client.InNamespace(o.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list %s: %v", o.From, err)
+	}
+
+	var tasks []*batchTask
+	for i := range list.Items {
+		item := &list.Items[i]
+		src, dst := o.SrcRef, o.DstRef
+		src.Namespace, src.Name = item.GetNamespace(), item.GetName()
+		dst.Namespace, dst.Name = item.GetNamespace(), item.GetName()
+		tasks = append(tasks, &batchTask{src: src, dst: dst})
+	}
+	return tasks, nil
+}
+
+// ensureDst creates the destination workload of the task with no replicas if it does not exist.
+func (o *migrateOptions) ensureDst(c client.Client, ctrl creation.Control, t *batchTask) error {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(t.dst.GetGroupVersionKind())
+	if err := c.Get(context.TODO(), t.dst.GetNamespacedName(), obj); err == nil {
+		return nil
+	} else if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get %v: %v", t.dst, err)
+	}
+
+	if _, _, err := ctrl.Create(t.src, t.dst, creation.Options{CloneSetProfile: o.CloneSetProfile}); err != nil {
+		return err
+	}
+	t.created = true
+	return nil
+}
+
+// runBatchTasks submits the tasks to ctrl and waits for them to finish. The first interrupt stops
+// submitting and rolls back the running tasks, and the second one aborts them.
+func (o *migrateOptions) runBatchTasks(ctrl migration.Control, tasks []*batchTask) {
+	interrupts := make(chan os.Signal, 2)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupts)
+
+	running := make(map[types.UID]*batchTask)
+	next := 0
+	interrupted := false
+	ticker := time.NewTicker(batchPollInterval)
+	defer ticker.Stop()
+	for {
+		for ; !interrupted && len(running) < o.Concurrency && next < len(tasks); next++ {
+			t := tasks[next]
+			if t.err != nil {
+				continue
+			}
+			opts, err := o.migrationOptions()
+			if err == nil {
+				err = o.validate(ctrl, t.src, t.dst, opts)
+			}
+			if err == nil {
+				t.result, err = ctrl.Submit(t.src, t.dst, opts)
+			}
+			if t.err = err; err != nil {
+				internalcmdutil.Print(fmt.Sprintf("Failed to submit migration from %s/%s: %v", t.src.Namespace, t.src.Name, err))
+				continue
+			}
+			running[t.result.ID] = t
+			internalcmdutil.Print(fmt.Sprintf("Migration task %v submitted from %s %s/%s to %s %s/%s", t.result.ID,
+				o.From, t.src.Namespace, t.src.Name, o.To, t.dst.Namespace, t.dst.Name))
+		}
+		if len(running) == 0 {
+			return
+		}
+
+		select {
+		case <-interrupts:
+			if interrupted {
+				for id, t := range running {
+					if result, err := ctrl.Abort(id); err == nil {
+						t.result = result
+					}
+				}
+				return
+			}
+			interrupted = true
+			internalcmdutil.Print("Interrupted, rolling back the running migration tasks, interrupt again to abort them")
+			for id, t := range running {
+				if result, err := ctrl.Rollback(id); err != nil {
+					t.err = err
+				} else {
+					t.result = result
+				}
+			}
+			continue
+		case <-ticker.C:
+		}
+
+		for id, t := range running {
+			result, err := ctrl.Query(id)
+			if err != nil {
+				t.err = err
+				delete(running, id)
+				continue
+			}
+			t.result = result
+			if !result.State.IsRunning() {
+				delete(running, id)
+				internalcmdutil.Print(fmt.Sprintf("Migration task %v from %s %s/%s is %s", id, o.From, t.src.Namespace, t.src.Name, result.State))
+			}
+		}
+	}
+}
+
+// printBatchSummary prints the result of each task, and returns an error if any of them did not succeed.
+func (o *migrateOptions) printBatchSummary(tasks []*batchTask) error {
+	failed := 0
+	w := printers.GetNewTabWriter(o.Out)
+	fmt.Fprintf(w, "NAMESPACE\tSRC\tDST\tCREATED\tSTATE\tSRC-MIGRATED\tDST-MIGRATED\tMESSAGE\n")
+	for _, t := range tasks {
+		state, message := string(t.result.State), t.result.Message
+		switch {
+		case t.err != nil:
+			state, message = "Error", t.err.Error()
+			failed++
+		case o.IsCreate:
+			state = "-"
+		case len(t.result.ID) == 0:
+			// not submitted after an interrupt
+			state = "NotStarted"
+			failed++
+		case t.result.State != migration.MigrateSucceeded:
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s/%s\t%s/%s\t%v\t%s\t%d\t%d\t%s\n", t.src.Namespace, o.From, t.src.Name, o.To, t.dst.Name,
+			t.created, state, t.result.SrcMigratedReplicas, t.result.DstMigratedReplicas, message)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d migrations did not succeed", failed, len(tasks))
+	}
+	return nil
+}
+
+// newCreationControl returns the control that creates the given destination.
+func newCreationControl(cfg *rest.Config, dst api.ResourceRef) (creation.Control, error) {
+	switch dst.GetGroupVersionKind() {
+	case api.CloneSetKind:
+		return clonesetcreation.NewControl(cfg)
+	case api.DeploymentKind:
+		return deploymentcreation.NewControl(cfg)
+	case api.AdvancedStatefulSetKind:
+		return statefulsetcreation.NewControl(cfg)
+	case api.AdvancedDaemonSetKind:
+		return daemonsetcreation.NewControl(cfg)
+	}
+	return nil, fmt.Errorf("unsupported dst type %v", dst.GetGroupVersionKind())
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+	"github.com/stretchr/testify/assert"
+
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeControl finishes each task on the second query, and records how many tasks ran at the same time.
+type fakeControl struct {
+	mu         sync.Mutex
+	failed     map[string]bool
+	queries    map[types.UID]int
+	running    int
+	maxRunning int
+	submitted  []string
+}
+
+var _ migration.Control = &fakeControl{}
+
+func (c *fakeControl) Submit(src api.ResourceRef, dst api.ResourceRef, opts migration.Options) (migration.Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failed[src.Name] {
+		return migration.Result{}, fmt.Errorf("replicas must be set")
+	}
+	c.submitted = append(c.submitted, src.Namespace+"/"+src.Name)
+	c.running++
+	if c.running > c.maxRunning {
+		c.maxRunning = c.running
+	}
+	return migration.Result{ID: types.UID(src.Namespace + "/" + src.Name), State: migration.MigrateExecuting}, nil
+}
+
+func (c *fakeControl) Plan(src api.ResourceRef, dst api.ResourceRef, opts migration.Options, serverDryRun bool) (migration.Plan, error) {
+	return migration.Plan{}, nil
+}
+
+func (c *fakeControl) Resume(dst api.ResourceRef) (migration.Result, error) {
+	return migration.Result{}, fmt.Errorf("not supported")
+}
+
+func (c *fakeControl) Query(ID types.UID) (migration.Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queries[ID]++
+	if c.queries[ID] < 2 {
+		return migration.Result{ID: ID, State: migration.MigrateExecuting, SrcMigratedReplicas: 1, DstMigratedReplicas: 1}, nil
+	}
+	c.running--
+	return migration.Result{ID: ID, State: migration.MigrateSucceeded, SrcMigratedReplicas: 2, DstMigratedReplicas: 2}, nil
+}
+
+func (c *fakeControl) Abort(ID types.UID) (migration.Result, error) {
+	return migration.Result{}, fmt.Errorf("not supported")
+}
+
+func (c *fakeControl) Rollback(ID types.UID) (migration.Result, error) {
+	return migration.Result{}, fmt.Errorf("not supported")
+}
+
+func newBatchOptions(namespace, selector string) *migrateOptions {
+	return &migrateOptions{
+		Namespace: namespace,
+		From:      "Deployment",
+		To:        "CloneSet",
+		SrcRef:    api.NewDeploymentRef("", ""),
+		DstRef:    api.NewCloneSetRef("", ""),
+		Replicas:  -1,
+		Selector:  selector,
+	}
+}
+
+func TestListBatchTasks(t *testing.T) {
+	newDeployment := func(namespace, name string, labels map[string]string) *apps.Deployment {
+		return &apps.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels}}
+	}
+	c := fake.NewClientBuilder().WithScheme(api.GetScheme()).WithObjects(
+		newDeployment("default", "web", map[string]string{"tier": "frontend"}),
+		newDeployment("default", "api", map[string]string{"tier": "backend"}),
+		newDeployment("default", "unlabeled", nil),
+		newDeployment("other", "web", map[string]string{"tier": "frontend"}),
+	).Build()
+
+	testCases := []struct {
+		name      string
+		namespace string
+		selector  string
+		expected  []string
+	}{
+		{
+			name:      "selected in namespace",
+			namespace: "default",
+			selector:  "tier=frontend",
+			expected:  []string{"default/web"},
+		},
+		{
+			name:     "selected in all namespaces",
+			selector: "tier=frontend",
+			expected: []string{"default/web", "other/web"},
+		},
+		{
+			name:      "set based selector",
+			namespace: "default",
+			selector:  "tier in (frontend,backend)",
+			expected:  []string{"default/api", "default/web"},
+		},
+		{
+			name:      "nothing selected",
+			namespace: "default",
+			selector:  "tier=database",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tasks, err := newBatchOptions(tc.namespace, tc.selector).listBatchTasks(c)
+			assert.NoError(t, err)
+			var names []string
+			for _, task := range tasks {
+				assert.Equal(t, api.DeploymentKind, task.src.GetGroupVersionKind())
+				assert.Equal(t, api.CloneSetKind, task.dst.GetGroupVersionKind())
+				assert.Equal(t, task.src.GetNamespacedName(), task.dst.GetNamespacedName())
+				names = append(names, task.src.Namespace+"/"+task.src.Name)
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestRunBatchTasks(t *testing.T) {
+	interval := batchPollInterval
+	batchPollInterval = 10 * time.Millisecond
+	defer func() { batchPollInterval = interval }()
+
+	var tasks []*batchTask
+	for i := 0; i < 5; i++ {
+		src, dst := api.NewDeploymentRef("default", fmt.Sprintf("demo-%d", i)), api.NewCloneSetRef("default", fmt.Sprintf("demo-%d", i))
+		tasks = append(tasks, &batchTask{src: src, dst: dst})
+	}
+	// the destination of demo-1 failed to be created, and demo-3 fails to be submitted
+	tasks[1].err = fmt.Errorf("failed to create")
+
+	ctrl := &fakeControl{failed: map[string]bool{"demo-3": true}, queries: map[types.UID]int{}}
+	o := newBatchOptions("default", "app=demo")
+	o.Concurrency = 2
+	o.IOStreams, _, _, _ = genericclioptions.NewTestIOStreams()
+	o.runBatchTasks(ctrl, tasks)
+
+	assert.Equal(t, 2, ctrl.maxRunning)
+	assert.Equal(t, []string{"default/demo-0", "default/demo-2", "default/demo-4"}, ctrl.submitted)
+	for _, i := range []int{0, 2, 4} {
+		assert.NoError(t, tasks[i].err)
+		assert.Equal(t, migration.MigrateSucceeded, tasks[i].result.State)
+	}
+	assert.EqualError(t, tasks[1].err, "failed to create")
+	assert.EqualError(t, tasks[3].err, "replicas must be set")
+}
+
+func TestPrintBatchSummary(t *testing.T) {
+	newTask := func(name string, created bool, result migration.Result, err error) *batchTask {
+		return &batchTask{src: api.NewDeploymentRef("default", name), dst: api.NewCloneSetRef("default", name),
+			created: created, result: result, err: err}
+	}
+
+	testCases := []struct {
+		name        string
+		isCreate    bool
+		tasks       []*batchTask
+		expected    []string
+		expectedErr string
+	}{
+		{
+			name: "all succeeded",
+			tasks: []*batchTask{
+				newTask("web", true, migration.Result{ID: "1", State: migration.MigrateSucceeded, SrcMigratedReplicas: 2, DstMigratedReplicas: 2}, nil),
+			},
+			expected: []string{
+				"NAMESPACE   SRC              DST            CREATED   STATE       SRC-MIGRATED   DST-MIGRATED   MESSAGE",
+				"default     Deployment/web   CloneSet/web   true      Succeeded   2              2              ",
+			},
+		},
+		{
+			name: "failed, rolled back and not started",
+			tasks: []*batchTask{
+				newTask("web", false, migration.Result{ID: "1", State: migration.MigrateFailed, Message: "timeout"}, nil),
+				newTask("api", true, migration.Result{ID: "2", State: migration.MigrateRolledBack}, nil),
+				newTask("db", false, migration.Result{}, nil),
+				newTask("cache", false, migration.Result{}, fmt.Errorf("failed to create")),
+			},
+			expected: []string{
+				"NAMESPACE   SRC                DST              CREATED   STATE        SRC-MIGRATED   DST-MIGRATED   MESSAGE",
+				"default     Deployment/web     CloneSet/web     false     Failed       0              0              timeout",
+				"default     Deployment/api     CloneSet/api     true      RolledBack   0              0              ",
+				"default     Deployment/db      CloneSet/db      false     NotStarted   0              0              ",
+				"default     Deployment/cache   CloneSet/cache   false     Error        0              0              failed to create",
+			},
+			expectedErr: "4 of 4 migrations did not succeed",
+		},
+		{
+			name:     "create only",
+			isCreate: true,
+			tasks: []*batchTask{
+				newTask("web", true, migration.Result{}, nil),
+			},
+			expected: []string{
+				"NAMESPACE   SRC              DST            CREATED   STATE   SRC-MIGRATED   DST-MIGRATED   MESSAGE",
+				"default     Deployment/web   CloneSet/web   true      -       0              0              ",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := newBatchOptions("default", "app=demo")
+			o.IsCreate = tc.isCreate
+			var out *bytes.Buffer
+			o.IOStreams, _, out, _ = genericclioptions.NewTestIOStreams()
+
+			err := o.printBatchSummary(tc.tasks)
+			if len(tc.expectedErr) > 0 {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expected, strings.Split(strings.TrimRight(out.String(), "\n"), "\n"))
+		})
+	}
+}
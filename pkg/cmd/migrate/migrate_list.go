@@ -0,0 +1,186 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+	"github.com/spf13/cobra"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type migrateListOptions struct {
+	Namespace     string
+	AllNamespaces bool
+	ID            string
+
+	reader client.Reader
+
+	genericclioptions.IOStreams
+}
+
+// NewCmdMigrateList lists the migration tasks recorded in destination workloads.
+func NewCmdMigrateList(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	o := &migrateListOptions{IOStreams: ioStreams}
+
+	cmd := &cobra.Command{
+		Use:                   "list [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "List migration tasks recorded in the cluster",
+		Example: `
+	# List the migration tasks in the default namespace.
+	kubectl-kruise migrate list -n default
+
+	# List the migration tasks in all namespaces.
+	kubectl-kruise migrate list -A
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.RunList())
+		},
+	}
+
+	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", false, "List the migration tasks across all namespaces.")
+	return cmd
+}
+
+// NewCmdMigrateStatus prints the progress of a migration task recorded in the cluster.
+func NewCmdMigrateStatus(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	o := &migrateListOptions{IOStreams: ioStreams}
+
+	cmd := &cobra.Command{
+		Use:                   "status ID [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Show the progress of a migration task recorded in the cluster",
+		Example: `
+	# Show the progress of a migration task in the default namespace.
+	kubectl-kruise migrate status 1f8a5c62-0d1e-4b8c-9a61-3c2b7e0f4d55 -n default
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(o.Complete(f, args))
+			cmdutil.CheckErr(o.RunStatus())
+		},
+	}
+	return cmd
+}
+
+func (o *migrateListOptions) Complete(f cmdutil.Factory, args []string) error {
+	namespace, _, err := f.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+	o.Namespace = namespace
+	if o.AllNamespaces {
+		o.Namespace = metav1.NamespaceAll
+	}
+
+	if len(args) > 1 {
+		return fmt.Errorf("more than one given args")
+	} else if len(args) == 1 {
+		o.ID = args[0]
+	}
+
+	cfg, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	o.reader, err = client.New(cfg, client.Options{Scheme: api.GetScheme()})
+	return err
+}
+
+func (o *migrateListOptions) RunList() error {
+	states, err := migration.ListStates(o.reader, o.Namespace)
+	if err != nil {
+		return err
+	}
+	if len(states) == 0 {
+		fmt.Fprintf(o.ErrOut, "No migration tasks found.\n")
+		return nil
+	}
+	sort.SliceStable(states, func(i, j int) bool {
+		return states[i].CreationTimestamp.Before(&states[j].CreationTimestamp)
+	})
+
+	w := printers.GetNewTabWriter(o.Out)
+	defer w.Flush()
+	if o.AllNamespaces {
+		fmt.Fprintf(w, "NAMESPACE\t")
+	}
+	fmt.Fprintf(w, "ID\tSOURCE\tDESTINATION\tSTATE\tSRC-MIGRATED\tDST-MIGRATED\tAGE\n")
+	for _, state := range states {
+		if o.AllNamespaces {
+			fmt.Fprintf(w, "%s\t", state.Dst.Namespace)
+		}
+		fmt.Fprintf(w, "%s\t%s/%s\t%s/%s\t%s\t%d\t%d\t%s\n", state.Result.ID,
+			workloadKind(state.Src), state.Src.Name, workloadKind(state.Dst), state.Dst.Name, state.Result.State,
+			state.Result.SrcMigratedReplicas, state.Result.DstMigratedReplicas,
+			duration.HumanDuration(time.Since(state.CreationTimestamp.Time)))
+	}
+	return nil
+}
+
+func (o *migrateListOptions) RunStatus() error {
+	if len(o.ID) == 0 {
+		return fmt.Errorf("must specify the ID of migration task")
+	}
+	state, err := migration.GetState(o.reader, o.Namespace, types.UID(o.ID))
+	if err != nil {
+		return err
+	}
+
+	w := printers.GetNewTabWriter(o.Out)
+	defer w.Flush()
+	fmt.Fprintf(w, "ID:\t%s\n", state.Result.ID)
+	fmt.Fprintf(w, "Namespace:\t%s\n", state.Dst.Namespace)
+	fmt.Fprintf(w, "Source:\t%s/%s\n", workloadKind(state.Src), state.Src.Name)
+	fmt.Fprintf(w, "Destination:\t%s/%s\n", workloadKind(state.Dst), state.Dst.Name)
+	fmt.Fprintf(w, "State:\t%s\n", state.Result.State)
+	if len(state.Result.Message) > 0 {
+		fmt.Fprintf(w, "Message:\t%s\n", state.Result.Message)
+	}
+	if state.Options.Replicas != nil {
+		fmt.Fprintf(w, "Replicas:\t%d\n", *state.Options.Replicas)
+	}
+	if state.Options.MaxSurge != nil {
+		fmt.Fprintf(w, "Max Surge:\t%s\n", state.Options.MaxSurge.String())
+	}
+	if state.Options.MaxUnavailable != nil {
+		fmt.Fprintf(w, "Max Unavailable:\t%s\n", state.Options.MaxUnavailable.String())
+	}
+	if state.Options.TimeoutSeconds != nil {
+		fmt.Fprintf(w, "Timeout Seconds:\t%d\n", *state.Options.TimeoutSeconds)
+	}
+	if state.Options.NodeSelector != nil {
+		fmt.Fprintf(w, "Node Selector:\t%s\n", metav1.FormatLabelSelector(state.Options.NodeSelector))
+	}
+	fmt.Fprintf(w, "Src Migrated Replicas:\t%d\n", state.Result.SrcMigratedReplicas)
+	fmt.Fprintf(w, "Dst Migrated Replicas:\t%d\n", state.Result.DstMigratedReplicas)
+	fmt.Fprintf(w, "Created:\t%s\n", state.CreationTimestamp.Format(time.RFC3339))
+	fmt.Fprintf(w, "Updated:\t%s\n", state.UpdateTimestamp.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+
+	"k8s.io/cli-runtime/pkg/printers"
+	"sigs.k8s.io/yaml"
+)
+
+// printPlan prints the steps of a migration plan as a table, or as JSON or YAML with --output.
+func (o *migrateOptions) printPlan(plan migration.Plan) error {
+	printWarnings(o.ErrOut, plan.Src, plan.Warnings)
+	switch format := *o.PrintFlags.OutputFormat; format {
+	case "json":
+		data, err := json.MarshalIndent(plan, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(o.Out, string(data))
+		return nil
+	case "yaml":
+		data, err := yaml.Marshal(plan)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(o.Out, string(data))
+		return nil
+	case "":
+	default:
+		return fmt.Errorf("unsupported output format %q for migration plan, must be one of json or yaml", format)
+	}
+
+	unit := "replicas"
+	if plan.Dst.GetGroupVersionKind() == api.AdvancedDaemonSetKind {
+		unit = "nodes"
+	}
+	var limits []string
+	if plan.Options.MaxSurge != nil {
+		limits = append(limits, "max surge "+plan.Options.MaxSurge.String())
+	}
+	if plan.Options.MaxUnavailable != nil {
+		limits = append(limits, "max unavailable "+plan.Options.MaxUnavailable.String())
+	}
+	fmt.Fprintf(o.Out, "Migrating %d %s from %s/%s to %s/%s with %s in %d steps:\n",
+		*plan.Options.Replicas, unit, o.From, o.SrcName, o.To, o.DstName, strings.Join(limits, " and "), len(plan.Steps))
+
+	w := printers.GetNewTabWriter(o.Out)
+	defer w.Flush()
+	fmt.Fprintf(w, "STEP\tACTION\tWORKLOAD\tCHANGE\tSRC-%s\tDST-%s\n", strings.ToUpper(unit), strings.ToUpper(unit))
+	for i, step := range plan.Steps {
+		workload, change := fmt.Sprintf("%s/%s", o.To, o.DstName), fmt.Sprintf("+%d", step.Replicas)
+		if step.Action == migration.StepScaleInSrc {
+			workload, change = fmt.Sprintf("%s/%s", o.From, o.SrcName), fmt.Sprintf("-%d", step.Replicas)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%d\n", i+1, step.Action, workload, change, step.SrcReplicas, step.DstReplicas)
+	}
+	return nil
+}
+
+// printWarnings prints the warnings of the pre-flight validation of a task from src.
+func printWarnings(out io.Writer, src api.ResourceRef, warnings []string) {
+	for _, warning := range warnings {
+		fmt.Fprintf(out, "Warning: %s/%s: %s\n", src.Namespace, src.Name, warning)
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"fmt"
+	"io"
+
+	appspub "github.com/openkruise/kruise-api/apps/pub"
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/conversion"
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// cloneSetProfile reads the conversion profile file if any, and overrides it with the flags.
+func (o *migrateOptions) cloneSetProfile(cmd *cobra.Command) (conversion.CloneSetProfile, error) {
+	profile := conversion.CloneSetProfile{}
+	if len(o.ConversionProfile) > 0 {
+		var err error
+		if profile, err = conversion.LoadCloneSetProfile(o.ConversionProfile); err != nil {
+			return profile, err
+		}
+	}
+
+	if cmd.Flags().Changed("update-strategy-type") {
+		profile.UpdateStrategyType = appsv1alpha1.CloneSetUpdateStrategyType(o.UpdateStrategyType)
+	}
+	if cmd.Flags().Changed("in-place-grace-period-seconds") {
+		profile.InPlaceUpdateStrategy = &appspub.InPlaceUpdateStrategy{GracePeriodSeconds: o.InPlaceGracePeriodSeconds}
+	}
+	if cmd.Flags().Changed("keep-finalizers") {
+		profile.KeepFinalizers = o.KeepFinalizers
+	}
+	return profile, profile.Validate()
+}
+
+// printConversionReport prints how the fields of the source workload were converted.
+func printConversionReport(out io.Writer, report conversion.Report) {
+	if len(report) == 0 {
+		return
+	}
+	w := printers.GetNewTabWriter(out)
+	defer w.Flush()
+	fmt.Fprintf(w, "FIELD\tACTION\tDETAIL\n")
+	for _, field := range report {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", field.Field, field.Action, field.Detail)
+	}
+}
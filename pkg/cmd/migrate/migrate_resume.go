@@ -0,0 +1,113 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	internalcmdutil "github.com/openkruise/kruise-tools/pkg/cmd/util"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+	clonesetmigration "github.com/openkruise/kruise-tools/pkg/migration/cloneset"
+	daemonsetmigration "github.com/openkruise/kruise-tools/pkg/migration/daemonset"
+	statefulsetmigration "github.com/openkruise/kruise-tools/pkg/migration/statefulset"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (o *migrateOptions) resumeMigration(f cmdutil.Factory) error {
+	cfg, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(cfg, client.Options{Scheme: api.GetScheme()})
+	if err != nil {
+		return err
+	}
+
+	id := o.Resume
+	if len(o.Rollback) > 0 {
+		id = o.Rollback
+	}
+	state, err := migration.GetState(c, o.Namespace, types.UID(id))
+	if err != nil {
+		return err
+	}
+	o.SrcRef, o.DstRef = state.Src, state.Dst
+	o.From, o.SrcName = workloadKind(state.Src), state.Src.Name
+	o.To, o.DstName = workloadKind(state.Dst), state.Dst.Name
+
+	switch {
+	case state.Result.State == migration.MigrateRolledBack:
+		internalcmdutil.Print(fmt.Sprintf("Migration task %v has already rolled back", state.Result.ID))
+		return nil
+	case len(o.Rollback) > 0:
+	case state.Result.State == migration.MigrateSucceeded:
+		internalcmdutil.Print(fmt.Sprintf("Migration task %v has already succeeded", state.Result.ID))
+		return nil
+	case state.Result.State == migration.MigrateFailed:
+		return fmt.Errorf("migration task %v has already failed: %v", state.Result.ID, state.Result.Message)
+	}
+
+	stopChan := make(chan struct{})
+	ctrl, err := newMigrationControl(cfg, state.Dst, stopChan, o.controlOptions())
+	if err != nil {
+		return err
+	}
+	result, err := ctrl.Resume(state.Dst)
+	if err != nil {
+		return err
+	}
+	if len(o.Rollback) > 0 {
+		if result, err = ctrl.Rollback(result.ID); err != nil {
+			return err
+		}
+		internalcmdutil.Print(fmt.Sprintf("Migration task %v rolling back from %s/%s scale in %d, %s/%s scale out %d",
+			result.ID, o.From, o.SrcName, result.SrcMigratedReplicas, o.To, o.DstName, result.DstMigratedReplicas))
+	} else {
+		internalcmdutil.Print(fmt.Sprintf("Migration task %v resumed from %s/%s scale in %d, %s/%s scale out %d",
+			result.ID, o.From, o.SrcName, result.SrcMigratedReplicas, o.To, o.DstName, result.DstMigratedReplicas))
+	}
+	return o.waitForMigration(ctrl, result)
+}
+
+// newMigrationControl returns the control that migrates replicas into the given destination.
+func newMigrationControl(cfg *rest.Config, dst api.ResourceRef, stopChan <-chan struct{}, opts migration.ControlOptions) (migration.Control, error) {
+	switch dst.GetGroupVersionKind() {
+	case api.CloneSetKind, api.DeploymentKind:
+		return clonesetmigration.NewControl(cfg, stopChan, opts)
+	case api.AdvancedStatefulSetKind:
+		return statefulsetmigration.NewControl(cfg, stopChan, opts)
+	case api.AdvancedDaemonSetKind:
+		return daemonsetmigration.NewControl(cfg, stopChan, opts)
+	}
+	return nil, fmt.Errorf("unsupported dst type %v", dst.GetGroupVersionKind())
+}
+
+// workloadKind returns the kind of the workload as it is named in the command line.
+func workloadKind(ref api.ResourceRef) string {
+	switch ref.GetGroupVersionKind() {
+	case api.AdvancedStatefulSetKind:
+		return "AdvancedStatefulSet"
+	case api.AdvancedDaemonSetKind:
+		return "AdvancedDaemonSet"
+	}
+	return ref.Kind
+}
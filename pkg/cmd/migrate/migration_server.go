@@ -0,0 +1,192 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+	"github.com/openkruise/kruise-tools/pkg/migration/server"
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/klog/v2"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type migrationServerOptions struct {
+	ListenAddress           string
+	TLSCertFile             string
+	TLSPrivateKeyFile       string
+	MaxConcurrentReconciles int
+	MaxPerNamespace         int
+
+	genericclioptions.IOStreams
+}
+
+// NewCmdMigrationServer runs the migration controls in a long-lived process that takes work from an HTTP API.
+func NewCmdMigrationServer(f cmdutil.Factory, ioStreams genericclioptions.IOStreams) *cobra.Command {
+	o := &migrationServerOptions{IOStreams: ioStreams}
+
+	cmd := &cobra.Command{
+		Use:                   "migration-server [flags]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Run migrations in a long-lived process that takes work from an HTTP API",
+		Long: `Run migrations in a long-lived process, e.g. a Deployment in the cluster, so that long migrations do not depend on a terminal.
+
+The tasks recorded as running in the cluster are resumed on start, and new ones are taken from an HTTP API:
+
+	GET  /healthz
+	GET  /migrations?namespace=NAMESPACE        list the tasks recorded in the cluster that the user may get
+	POST /migrations                            submit a task from {"src": REF, "dst": REF, "options": OPTIONS}
+	GET  /migrations/NAMESPACE/ID               get the result of a task
+	POST /migrations/NAMESPACE/ID/resume        resume a task recorded in the cluster
+	POST /migrations/NAMESPACE/ID/abort         abort a running task
+	POST /migrations/NAMESPACE/ID/rollback      roll a task back
+
+REF is {"apiVersion", "kind", "namespace", "name"} of a workload, and OPTIONS has the fields
+replicas, maxSurge, maxUnavailable, timeoutSeconds and nodeSelector. The destination workload must exist.
+
+Every request but /healthz must carry the bearer token of a user, which is checked with a TokenReview and
+SubjectAccessReviews, so the server must be allowed to create them. The GET requests only return the tasks whose
+destination workload the user may get. The POST requests need a user that may update both workloads of the task,
+and also patch nodes when migrating to an Advanced DaemonSet, or update the persistent volume claims of the
+namespace when migrating to an Advanced StatefulSet, since the server changes them on behalf of the user.
+
+The bearer tokens are only sent in clear text to a loopback address, so the server must be given a certificate
+with --tls-cert-file and --tls-private-key-file to listen on any other address.`,
+		Example: `
+	# Run the migration server on port 8080 of localhost.
+	kubectl-kruise migration-server --max-per-namespace=2
+
+	# Run the migration server on port 8443 of all interfaces, serving HTTPS.
+	kubectl-kruise migration-server --listen-address=:8443 --tls-cert-file=server.crt --tls-private-key-file=server.key
+
+	# Migrate replicas from an existing Deployment to an existing CloneSet with the server.
+	curl -X POST localhost:8080/migrations -H "Authorization: Bearer $TOKEN" -d '{"src": {"apiVersion": "apps/v1", "kind": "Deployment", "namespace": "default", "name": "demo"}, "dst": {"apiVersion": "apps.kruise.io/v1alpha1", "kind": "CloneSet", "namespace": "default", "name": "demo"}, "options": {"maxSurge": 2}}'
+`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 0 {
+				cmdutil.CheckErr(cmdutil.UsageErrorf(cmd, "unexpected args: %v", args))
+			}
+			cmdutil.CheckErr(o.Run(f))
+		},
+	}
+
+	cmd.Flags().StringVar(&o.ListenAddress, "listen-address", "127.0.0.1:8080", "Address that the HTTP API listens on, which must be a loopback address unless TLS is enabled.")
+	cmd.Flags().StringVar(&o.TLSCertFile, "tls-cert-file", "", "File containing the x509 certificate to serve the HTTP API with TLS.")
+	cmd.Flags().StringVar(&o.TLSPrivateKeyFile, "tls-private-key-file", "", "File containing the x509 private key matching --tls-cert-file.")
+	cmd.Flags().IntVar(&o.MaxConcurrentReconciles, "max-concurrent-reconciles", 5, "Number of workers reconciling the tasks of each kind of workload.")
+	cmd.Flags().IntVar(&o.MaxPerNamespace, "max-per-namespace", 0, "Max number of migrations running in a namespace at the same time, 0 indicates no limited.")
+	return cmd
+}
+
+func (o *migrationServerOptions) Run(f cmdutil.Factory) error {
+	if o.MaxConcurrentReconciles <= 0 {
+		return fmt.Errorf("invalid max concurrent reconciles %d", o.MaxConcurrentReconciles)
+	} else if o.MaxPerNamespace < 0 {
+		return fmt.Errorf("invalid max per namespace %d", o.MaxPerNamespace)
+	} else if err := o.validateTLS(); err != nil {
+		return err
+	}
+
+	cfg, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(cfg, client.Options{Scheme: api.GetScheme()})
+	if err != nil {
+		return err
+	}
+
+	stopChan := make(chan struct{})
+	defer close(stopChan)
+	controlOpts := migration.ControlOptions{
+		MaxConcurrentReconciles:     o.MaxConcurrentReconciles,
+		MaxRunningTasksPerNamespace: o.MaxPerNamespace,
+	}
+	s := server.New(c, server.NewReviewAuthorizer(c), func(dst api.ResourceRef) (migration.Control, error) {
+		return newMigrationControl(cfg, dst, stopChan, controlOpts)
+	})
+	if err := s.ResumeRunning(""); err != nil {
+		// a task that can not be resumed must not stop the others from being served
+		klog.Errorf("Failed to resume migration tasks: %v", err)
+	}
+
+	httpServer := &http.Server{Addr: o.ListenAddress, Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	errChan := make(chan error, 1)
+	go func() {
+		if o.servesTLS() {
+			errChan <- httpServer.ListenAndServeTLS(o.TLSCertFile, o.TLSPrivateKeyFile)
+		} else {
+			errChan <- httpServer.ListenAndServe()
+		}
+	}()
+	klog.Infof("Migration server listening on %s", o.ListenAddress)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	select {
+	case err := <-errChan:
+		return err
+	case <-signals:
+	}
+
+	// the running tasks are recorded in the cluster and resumed by the next server
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		return err
+	}
+	if err := <-errChan; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (o *migrationServerOptions) servesTLS() bool {
+	return len(o.TLSCertFile) > 0
+}
+
+// validateTLS checks that the certificate and key are given together, and that the bearer tokens of the requests
+// are not sent in clear text to any address but a loopback one.
+func (o *migrationServerOptions) validateTLS() error {
+	if (len(o.TLSCertFile) > 0) != (len(o.TLSPrivateKeyFile) > 0) {
+		return fmt.Errorf("--tls-cert-file and --tls-private-key-file must be set together")
+	} else if o.servesTLS() {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(o.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %s: %v", o.ListenAddress, err)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("listen address %s is not a loopback address, --tls-cert-file and --tls-private-key-file must be set to listen on it", o.ListenAddress)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTLS(t *testing.T) {
+	testCases := []struct {
+		name        string
+		opts        migrationServerOptions
+		expectedErr string
+	}{
+		{
+			name: "loopback address",
+			opts: migrationServerOptions{ListenAddress: "127.0.0.1:8080"},
+		},
+		{
+			name: "localhost",
+			opts: migrationServerOptions{ListenAddress: "localhost:8080"},
+		},
+		{
+			name: "ipv6 loopback address",
+			opts: migrationServerOptions{ListenAddress: "[::1]:8080"},
+		},
+		{
+			name:        "all interfaces without TLS",
+			opts:        migrationServerOptions{ListenAddress: ":8080"},
+			expectedErr: "listen address :8080 is not a loopback address, --tls-cert-file and --tls-private-key-file must be set to listen on it",
+		},
+		{
+			name: "all interfaces with TLS",
+			opts: migrationServerOptions{ListenAddress: ":8443", TLSCertFile: "server.crt", TLSPrivateKeyFile: "server.key"},
+		},
+		{
+			name:        "certificate without key",
+			opts:        migrationServerOptions{ListenAddress: ":8443", TLSCertFile: "server.crt"},
+			expectedErr: "--tls-cert-file and --tls-private-key-file must be set together",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opts.validateTLS()
+			if len(tc.expectedErr) > 0 {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -49,6 +49,9 @@ type ApproveOptions struct {
 	Namespace        string
 	EnforceNamespace bool
 
+	ToStep    int32
+	SkipToEnd bool
+
 	resource.FilenameOptions
 	genericclioptions.IOStreams
 }
@@ -59,12 +62,22 @@ var (
 
 		Paused resources will not be reconciled by a controller. By approving a
 		resource, we allow it to be continue to rollout.
-		Currently only kruise-rollouts support being approved.`)
+		Currently only kruise-rollouts support being approved.
+
+		With --to-step or --skip-to-end, the rollout jumps to a later step of its canary or
+		blue-green strategy once the current step is done, and the current step is approved
+		if it is paused.`)
 
 	ApproveExample = templates.Examples(`
 		# approve a kruise rollout resource named "rollout-demo" in "ns-demo" namespace
 		
-		kubectl-kruise rollout approve rollout/rollout-demo -n ns-demo`)
+		kubectl-kruise rollout approve rollout/rollout-demo -n ns-demo
+
+		# approve a kruise rollout resource and jump to step 3
+		kubectl-kruise rollout approve rollout/rollout-demo --to-step 3
+
+		# approve a kruise rollout resource and jump to the last step
+		kubectl-kruise rollout approve rollout/rollout-demo --skip-to-end`)
 )
 
 // NewRolloutApproveOptions returns an initialized ApproveOptions instance
@@ -95,6 +108,8 @@ func NewCmdRolloutApprove(f cmdutil.Factory, streams genericclioptions.IOStreams
 	usage := "identifying the resource to get from a server."
 	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
 	o.PrintFlags.AddFlags(cmd)
+	cmd.Flags().Int32Var(&o.ToStep, "to-step", o.ToStep, "The later step of the rollout to jump to once the current step is done.")
+	cmd.Flags().BoolVar(&o.SkipToEnd, "skip-to-end", o.SkipToEnd, "Jump to the last step of the rollout once the current step is done.")
 	return cmd
 }
 
@@ -102,7 +117,17 @@ func NewCmdRolloutApprove(f cmdutil.Factory, streams genericclioptions.IOStreams
 func (o *ApproveOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []string) error {
 	o.Resources = args
 
-	o.Approver = internalpolymorphichelpers.ObjectApproverFn
+	if cmd.Flags().Changed("to-step") && o.ToStep < 1 {
+		return fmt.Errorf("--to-step must be a positive integer: %v", o.ToStep)
+	}
+	switch {
+	case o.SkipToEnd:
+		o.Approver = internalpolymorphichelpers.RolloutStepApproverFn(internalpolymorphichelpers.SkipToEndStep)
+	case o.ToStep != 0:
+		o.Approver = internalpolymorphichelpers.RolloutStepApproverFn(o.ToStep)
+	default:
+		o.Approver = internalpolymorphichelpers.ObjectApproverFn
+	}
 
 	var err error
 	o.Namespace, o.EnforceNamespace, err = f.ToRawKubeConfigLoader().Namespace()
@@ -124,6 +149,9 @@ func (o *ApproveOptions) Validate() error {
 	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
 		return fmt.Errorf("required resource not specified")
 	}
+	if o.ToStep != 0 && o.SkipToEnd {
+		return fmt.Errorf("--to-step and --skip-to-end cannot be used together")
+	}
 	return nil
 }
 
@@ -19,11 +19,15 @@ package rollout
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	internalapi "github.com/openkruise/kruise-tools/pkg/api"
 	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
@@ -45,7 +49,19 @@ var (
 		kubectl-kruise rollout history asts/abc
 
 		# View the details of daemonset revision 3
-		kubectl-kruise rollout history daemonset/abc --revision=3`)
+		kubectl-kruise rollout history daemonset/abc --revision=3
+
+		# View the revisions of a cloneset with the number and names of the pods currently on each of them
+		kubectl-kruise rollout history cloneset/abc --show-pods
+
+		# View the changes of the pod template of a cloneset between revisions 3 and 5
+		kubectl-kruise rollout history cloneset/abc --diff=3..5
+
+		# View the revisions of a cloneset with their images and pods in json
+		kubectl-kruise rollout history cloneset/abc -o json
+
+		# View the number of pods on revision 3 of an advanced statefulset
+		kubectl-kruise rollout history asts/abc --revision=3 -o jsonpath='{.items[0].pods}'`)
 )
 
 // RolloutHistoryOptions holds the options for 'rollout history' sub command
@@ -54,6 +70,10 @@ type RolloutHistoryOptions struct {
 	ToPrinter  func(string) (printers.ResourcePrinter, error)
 
 	Revision int64
+	Diff     string
+	ShowPods bool
+
+	diffFrom, diffTo int64
 
 	Builder          func() *resource.Builder
 	Resources        []string
@@ -96,6 +116,8 @@ func NewCmdRolloutHistory(f cmdutil.Factory, streams genericclioptions.IOStreams
 	}
 
 	cmd.Flags().Int64Var(&o.Revision, "revision", o.Revision, "See the details, including podTemplate of the revision specified")
+	cmd.Flags().StringVar(&o.Diff, "diff", o.Diff, "See the changes of the podTemplate between two revisions, in the form FROM..TO, e.g. 3..5")
+	cmd.Flags().BoolVar(&o.ShowPods, "show-pods", o.ShowPods, "If true, show the number and the first names of the pods currently on each revision, all of them are listed by -o json")
 
 	usage := "identifying the resource to get from a server."
 	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
@@ -134,6 +156,21 @@ func (o *RolloutHistoryOptions) Validate() error {
 	if o.Revision < 0 {
 		return fmt.Errorf("revision must be a positive integer: %v", o.Revision)
 	}
+	if len(o.Diff) > 0 {
+		if o.Revision > 0 {
+			return fmt.Errorf("--revision and --diff cannot be used together")
+		}
+		if o.printsHistory() {
+			return fmt.Errorf("--output is not supported with --diff")
+		}
+		var err error
+		if o.diffFrom, o.diffTo, err = parseRevisionRange(o.Diff); err != nil {
+			return err
+		}
+	}
+	if o.ShowPods && (o.Revision > 0 || len(o.Diff) > 0 || o.printsHistory()) {
+		return fmt.Errorf("--show-pods cannot be used with --revision, --diff or --output")
+	}
 
 	return nil
 }
@@ -164,7 +201,18 @@ func (o *RolloutHistoryOptions) Run() error {
 		if err != nil {
 			return err
 		}
-		historyInfo, err := historyViewer.ViewHistory(info.Namespace, info.Name, o.Revision)
+		if o.printsHistory() {
+			return o.printHistory(historyViewer, info)
+		}
+
+		var historyInfo string
+		if len(o.Diff) > 0 {
+			historyInfo, err = historyViewer.DiffHistory(info.Namespace, info.Name, o.diffFrom, o.diffTo)
+		} else if o.ShowPods {
+			historyInfo, err = viewHistoryWithPods(historyViewer, info)
+		} else {
+			historyInfo, err = historyViewer.ViewHistory(info.Namespace, info.Name, o.Revision)
+		}
 		if err != nil {
 			return err
 		}
@@ -172,6 +220,8 @@ func (o *RolloutHistoryOptions) Run() error {
 		withRevision := ""
 		if o.Revision > 0 {
 			withRevision = fmt.Sprintf("with revision #%d", o.Revision)
+		} else if len(o.Diff) > 0 {
+			withRevision = fmt.Sprintf("from revision #%d to #%d", o.diffFrom, o.diffTo)
 		}
 
 		printer, err := o.ToPrinter(fmt.Sprintf("%s\n%s", withRevision, historyInfo))
@@ -182,3 +232,68 @@ func (o *RolloutHistoryOptions) Run() error {
 		return printer.PrintObj(info.Object, o.Out)
 	})
 }
+
+// printsHistory returns whether the revisions are printed in the --output format, rather than as a table
+func (o *RolloutHistoryOptions) printsHistory() bool {
+	return o.PrintFlags.OutputFormat != nil && len(*o.PrintFlags.OutputFormat) > 0 && *o.PrintFlags.OutputFormat != "name"
+}
+
+// viewHistoryWithPods returns a table of the revisions of the resource with the pods currently on each of them
+func viewHistoryWithPods(historyViewer internalpolymorphichelpers.HistoryViewer, info *resource.Info) (string, error) {
+	histories, err := historyViewer.GetHistory(info.Namespace, info.Name)
+	if err != nil {
+		return "", err
+	}
+	return internalpolymorphichelpers.PrintRevisionHistories(histories)
+}
+
+// printHistory prints the revisions of the resource, or only --revision if set, as a List in the --output format
+func (o *RolloutHistoryOptions) printHistory(historyViewer internalpolymorphichelpers.HistoryViewer, info *resource.Info) error {
+	histories, err := historyViewer.GetHistory(info.Namespace, info.Name)
+	if err != nil {
+		return err
+	}
+
+	items := make([]interface{}, 0, len(histories))
+	for i := range histories {
+		if o.Revision > 0 && histories[i].Revision != o.Revision {
+			continue
+		}
+		item, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&histories[i])
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+	if o.Revision > 0 && len(items) == 0 {
+		return fmt.Errorf("unable to find the specified revision")
+	}
+
+	printer, err := o.ToPrinter("")
+	if err != nil {
+		return err
+	}
+	list := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      items,
+	}}
+	return printer.PrintObj(list, o.Out)
+}
+
+// parseRevisionRange parses two positive revisions in the form FROM..TO
+func parseRevisionRange(value string) (int64, int64, error) {
+	from, to, ok := strings.Cut(value, "..")
+	if !ok {
+		return 0, 0, fmt.Errorf("--diff must be in the form FROM..TO: %v", value)
+	}
+	var revisions [2]int64
+	for i, revision := range []string{from, to} {
+		r, err := strconv.ParseInt(revision, 10, 64)
+		if err != nil || r <= 0 {
+			return 0, 0, fmt.Errorf("revisions of --diff must be positive integers: %v", value)
+		}
+		revisions[i] = r
+	}
+	return revisions[0], revisions[1], nil
+}
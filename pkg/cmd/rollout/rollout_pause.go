@@ -57,14 +57,18 @@ var (
 
 		Paused resources will not be reconciled by a controller.
 		Use "kubectl rollout resume" to resume a paused resource.
-		Currently  deployments, clonesets, rollouts support being paused.`)
+		Currently deployments, clonesets, advanced statefulsets, advanced daemonsets, uniteddeployments
+		and rollouts support being paused.`)
 
 	pauseExample = templates.Examples(`
 		# Mark the nginx deployment as paused. Any current state of
 		# the deployment will continue its function, new updates to the deployment will not
 		# have an effect as long as the deployment is paused.
 
-		kubectl-kruise rollout pause deployment/nginx`)
+		kubectl-kruise rollout pause deployment/nginx
+
+		# Pause the rolling update of an advanced statefulset
+		kubectl-kruise rollout pause asts/nginx`)
 )
 
 // NewCmdRolloutPause returns a Command instance for 'rollout pause' sub command
@@ -74,7 +78,7 @@ func NewCmdRolloutPause(f cmdutil.Factory, streams genericclioptions.IOStreams)
 		IOStreams:  streams,
 	}
 
-	validArgs := []string{"deployment", "cloneset", "rollout"}
+	validArgs := []string{"deployment", "cloneset", "advanced statefulset", "advanced daemonset", "uniteddeployment", "rollout"}
 
 	cmd := &cobra.Command{
 		Use:                   "pause RESOURCE",
@@ -18,17 +18,21 @@ package rollout
 
 import (
 	"fmt"
+	"time"
 
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	kruiseclientsets "github.com/openkruise/kruise-api/client/clientset/versioned"
 	internalapi "github.com/openkruise/kruise-tools/pkg/api"
 	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/kubectl/pkg/cmd/set"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/scheme"
@@ -49,6 +53,12 @@ type RestartOptions struct {
 	Namespace        string
 	EnforceNamespace bool
 
+	ByEnv            bool
+	InPlace          bool
+	Containers       []string
+	MaxUnavailable   string
+	InPlaceRestarter *internalpolymorphichelpers.InPlaceRestarter
+
 	resource.FilenameOptions
 	genericclioptions.IOStreams
 }
@@ -57,7 +67,24 @@ var (
 	restartLong = templates.LongDesc(`
 		Restart a resource.
 
-	        Resource will be rollout restarted.`)
+	        Resource will be rollout restarted.
+
+		A CloneSet, Advanced StatefulSet, Advanced DaemonSet or UnitedDeployment is restarted by the
+		kubectl.kruise.io/restartedAt annotation of its pod template. With an in-place update strategy,
+		the pods only get the new annotation, so their containers are not restarted. With --by-env, the
+		containers of a CloneSet, Advanced StatefulSet or Advanced DaemonSet get a new RESTARTED_AT env
+		instead, which restarts them in place.
+
+		A SidecarSet is restarted by a new RESTARTED_AT env of its sidecar containers. It only upgrades
+		the sidecar containers of running pods for new images, so the env takes effect on the pods created
+		or recreated afterwards.
+
+		With --in-place, the containers of the pods of a CloneSet, Advanced StatefulSet or Advanced DaemonSet
+		are restarted by ContainerRecreateRequests without making a new revision. The pods are restarted
+		in batches of --max-unavailable, and each batch waits for the previous one to be completed.
+		It stops on the first ContainerRecreateRequest that failed. The pods that are terminating, not scheduled
+		or not running are skipped, since their containers can not be recreated. The sidecar containers
+		of a SidecarSet are restarted in place in the pods it is injected into.`)
 
 	restartExample = templates.Examples(`
 		# Restart a deployment
@@ -68,14 +95,30 @@ var (
 		kubectl-kruise rollout restart daemonset/abc
 
 		# Restart a UnitedDeployment
-		kubectl-kruise rollout restart uniteddeployment/my-app`)
+		kubectl-kruise rollout restart uniteddeployment/my-app
+
+		# Restart an advanced statefulset and an advanced daemonset
+		kubectl-kruise rollout restart asts/abc daemonsets.apps.kruise.io/abc
+
+		# Restart the containers of a cloneset by a new env, in place if its update strategy allows it
+		kubectl-kruise rollout restart cloneset/abc --by-env
+
+		# Restart the sidecar containers of the running pods a sidecarset is injected into, one pod at a time
+		kubectl-kruise rollout restart sidecarset/abc --in-place
+
+		# Restart the containers of the pods of a cloneset in place, two pods at a time
+		kubectl-kruise rollout restart cloneset/abc --in-place --max-unavailable=2
+
+		# Restart the sidecar container of the pods of an advanced statefulset in place, a quarter of the pods at a time
+		kubectl-kruise rollout restart asts/abc --in-place --containers=sidecar --max-unavailable=25%`)
 )
 
 // NewRolloutRestartOptions returns an initialized RestartOptions instance
 func NewRolloutRestartOptions(streams genericclioptions.IOStreams) *RestartOptions {
 	return &RestartOptions{
-		PrintFlags: genericclioptions.NewPrintFlags("restarted").WithTypeSetter(internalapi.GetScheme()),
-		IOStreams:  streams,
+		PrintFlags:     genericclioptions.NewPrintFlags("restarted").WithTypeSetter(internalapi.GetScheme()),
+		MaxUnavailable: "1",
+		IOStreams:      streams,
 	}
 }
 
@@ -83,7 +126,8 @@ func NewRolloutRestartOptions(streams genericclioptions.IOStreams) *RestartOptio
 func NewCmdRolloutRestart(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
 	o := NewRolloutRestartOptions(streams)
 
-	validArgs := []string{"deployment", "daemonset", "statefulset", "cloneset", "uniteddeployment"}
+	validArgs := []string{"deployment", "daemonset", "statefulset", "cloneset", "advanced statefulset", "advanced daemonset",
+		"uniteddeployment", "sidecarset"}
 
 	cmd := &cobra.Command{
 		Use:                   "restart RESOURCE",
@@ -102,6 +146,10 @@ func NewCmdRolloutRestart(f cmdutil.Factory, streams genericclioptions.IOStreams
 	usage := "identifying the resource to get from a server."
 	cmdutil.AddFilenameOptionFlags(cmd, &o.FilenameOptions, usage)
 	o.PrintFlags.AddFlags(cmd)
+	cmd.Flags().BoolVar(&o.ByEnv, "by-env", o.ByEnv, "Restart a CloneSet, Advanced StatefulSet or Advanced DaemonSet by a new RESTARTED_AT env of its containers instead of an annotation of its pod template.")
+	cmd.Flags().BoolVar(&o.InPlace, "in-place", o.InPlace, "Restart the containers of the pods in place by ContainerRecreateRequests, without making a new revision.")
+	cmd.Flags().StringSliceVar(&o.Containers, "containers", o.Containers, "The containers to restart in place, defaults to all the containers of the pods.")
+	cmd.Flags().StringVar(&o.MaxUnavailable, "max-unavailable", o.MaxUnavailable, "The number or percentage of the pods restarted in place at a time.")
 	return cmd
 }
 
@@ -124,6 +172,29 @@ func (o *RestartOptions) Complete(f cmdutil.Factory, cmd *cobra.Command, args []
 
 	o.Builder = f.NewBuilder
 
+	if !o.InPlace && (cmd.Flags().Changed("containers") || cmd.Flags().Changed("max-unavailable")) {
+		return fmt.Errorf("--containers and --max-unavailable are only supported with --in-place")
+	}
+	if !o.InPlace {
+		return nil
+	}
+	clientConfig, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	o.InPlaceRestarter = &internalpolymorphichelpers.InPlaceRestarter{
+		Containers:     o.Containers,
+		MaxUnavailable: intstr.Parse(o.MaxUnavailable),
+		Interval:       time.Second,
+		ErrOut:         o.ErrOut,
+	}
+	if o.InPlaceRestarter.Client, err = kubernetes.NewForConfig(clientConfig); err != nil {
+		return err
+	}
+	if o.InPlaceRestarter.KruiseClient, err = kruiseclientsets.NewForConfig(clientConfig); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -131,6 +202,17 @@ func (o *RestartOptions) Validate() error {
 	if len(o.Resources) == 0 && cmdutil.IsFilenameSliceEmpty(o.Filenames, o.Kustomize) {
 		return fmt.Errorf("required resource not specified")
 	}
+	if o.ByEnv && o.InPlace {
+		return fmt.Errorf("--by-env and --in-place can not be used together")
+	}
+	if !o.InPlace {
+		return nil
+	}
+	if maxUnavailable := intstr.Parse(o.MaxUnavailable); maxUnavailable.Type == intstr.Int && maxUnavailable.IntVal < 1 {
+		return fmt.Errorf("--max-unavailable must be a positive integer or percentage: %s", o.MaxUnavailable)
+	} else if _, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, 100, false); err != nil {
+		return fmt.Errorf("invalid --max-unavailable %s: %v", o.MaxUnavailable, err)
+	}
 	return nil
 }
 
@@ -160,67 +242,104 @@ func (o RestartOptions) RunRestart() error {
 		allErrs = append(allErrs, err)
 	}
 
-	switch infos[0].Object.(type) {
-	case *kruiseappsv1alpha1.CloneSet, *kruiseappsv1beta1.StatefulSet, *kruiseappsv1alpha1.DaemonSet:
+	if o.InPlace {
+		for _, info := range infos {
+			if err := o.restartInPlace(info); err != nil {
+				// the rest are not restarted once a restart failed
+				return utilerrors.NewAggregate(append(allErrs, err))
+			}
+		}
+		return utilerrors.NewAggregate(allErrs)
+	}
 
-		obj, err := resource.
-			NewHelper(infos[0].Client, infos[0].Mapping).
-			Get(infos[0].Namespace, infos[0].Name)
-		if err != nil {
-			return err
+	if o.ByEnv {
+		for _, info := range infos {
+			if err := o.restartByEnv(info); err != nil {
+				allErrs = append(allErrs, err)
+			}
+		}
+		return utilerrors.NewAggregate(allErrs)
+	}
+
+	for _, patch := range set.CalculatePatches(infos, scheme.DefaultJSONEncoder(), set.PatchFn(o.Restarter)) {
+		info := patch.Info
+		if patch.Err != nil {
+			resourceString := info.Mapping.Resource.Resource
+			if len(info.Mapping.Resource.Group) > 0 {
+				resourceString = resourceString + "." + info.Mapping.Resource.Group
+			}
+			allErrs = append(allErrs, fmt.Errorf("error: %s %q %v", resourceString, info.Name, patch.Err))
+			continue
+		}
+
+		if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
+			allErrs = append(allErrs, fmt.Errorf("failed to create patch for %v: empty patch", info.Name))
 		}
-		internalpolymorphichelpers.UpdateResourceEnv(obj)
 
-		_, err = resource.
-			NewHelper(infos[0].Client, infos[0].Mapping).
-			Replace(infos[0].Namespace, infos[0].Name, true, obj)
+		obj, err := resource.NewHelper(info.Client, info.Mapping).Patch(info.Namespace, info.Name, types.MergePatchType, patch.Patch, nil)
 		if err != nil {
-			return err
+			allErrs = append(allErrs, fmt.Errorf("failed to patch: %v", err))
+			continue
 		}
+
+		info.Refresh(obj, true)
 		printer, err := o.ToPrinter("restarted")
 		if err != nil {
 			allErrs = append(allErrs, err)
+			continue
 		}
-		if err = printer.PrintObj(infos[0].Object, o.Out); err != nil {
+		if err = printer.PrintObj(info.Object, o.Out); err != nil {
 			allErrs = append(allErrs, err)
 		}
-		return utilerrors.NewAggregate(allErrs)
-
-	default:
-		for _, patch := range set.CalculatePatches(infos, scheme.DefaultJSONEncoder(), set.PatchFn(o.Restarter)) {
-			info := patch.Info
-			if patch.Err != nil {
-				resourceString := info.Mapping.Resource.Resource
-				if len(info.Mapping.Resource.Group) > 0 {
-					resourceString = resourceString + "." + info.Mapping.Resource.Group
-				}
-				allErrs = append(allErrs, fmt.Errorf("error: %s %q %v", resourceString, info.Name, patch.Err))
-				continue
-			}
-
-			if string(patch.Patch) == "{}" || len(patch.Patch) == 0 {
-				allErrs = append(allErrs, fmt.Errorf("failed to create patch for %v: empty patch", info.Name))
-			}
-
-			obj, err := resource.NewHelper(info.Client, info.Mapping).Patch(info.Namespace, info.Name, types.MergePatchType, patch.Patch, nil)
-			if err != nil {
-				allErrs = append(allErrs, fmt.Errorf("failed to patch: %v", err))
-				continue
-			}
+	}
 
-			info.Refresh(obj, true)
-			printer, err := o.ToPrinter("restarted")
-			if err != nil {
-				allErrs = append(allErrs, err)
-				continue
-			}
-			if err = printer.PrintObj(info.Object, o.Out); err != nil {
-				allErrs = append(allErrs, err)
-			}
-		}
+	return utilerrors.NewAggregate(allErrs)
+}
 
-		return utilerrors.NewAggregate(allErrs)
+// restartInPlace restarts the containers of the pods of the resource in place.
+func (o RestartOptions) restartInPlace(info *resource.Info) error {
+	switch info.Object.(type) {
+	case *kruiseappsv1alpha1.CloneSet, *kruiseappsv1beta1.StatefulSet, *kruiseappsv1alpha1.DaemonSet, *kruiseappsv1alpha1.SidecarSet:
+	default:
+		return fmt.Errorf("%s %q does not support in-place restart", info.Mapping.Resource.Resource, info.Name)
+	}
+	if err := o.InPlaceRestarter.Restart(info.Object); err != nil {
+		return fmt.Errorf("failed to restart %s %q in place: %v", info.Mapping.Resource.Resource, info.Name, err)
+	}
+	printer, err := o.ToPrinter("restarted in place")
+	if err != nil {
+		return err
+	}
+	return printer.PrintObj(info.Object, o.Out)
+}
 
+// restartByEnv restarts the resource by updating the RESTARTED_AT env of its containers.
+func (o RestartOptions) restartByEnv(info *resource.Info) error {
+	switch info.Object.(type) {
+	case *kruiseappsv1alpha1.CloneSet, *kruiseappsv1beta1.StatefulSet, *kruiseappsv1alpha1.DaemonSet:
+	default:
+		return fmt.Errorf("%s %q does not support restart by env", info.Mapping.Resource.Resource, info.Name)
+	}
+	obj, err := resource.
+		NewHelper(info.Client, info.Mapping).
+		Get(info.Namespace, info.Name)
+	if err != nil {
+		return err
+	}
+	if err := internalpolymorphichelpers.UpdateResourceEnv(obj); err != nil {
+		return fmt.Errorf("%s %q %v", info.Mapping.Resource.Resource, info.Name, err)
 	}
 
+	obj, err = resource.
+		NewHelper(info.Client, info.Mapping).
+		Replace(info.Namespace, info.Name, true, obj)
+	if err != nil {
+		return err
+	}
+	info.Refresh(obj, true)
+	printer, err := o.ToPrinter("restarted")
+	if err != nil {
+		return err
+	}
+	return printer.PrintObj(info.Object, o.Out)
 }
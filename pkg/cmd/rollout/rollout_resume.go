@@ -58,14 +58,18 @@ var (
 
 		Paused resources will not be reconciled by a controller. By resuming a
 		resource, we allow it to be reconciled again.
-		Currently deployments, cloneset support being resumed.`)
+		Currently deployments, clonesets, advanced statefulsets, advanced daemonsets, uniteddeployments
+		and rollouts support being resumed.`)
 
 	resumeExample = templates.Examples(`
 		# Resume an already paused rollout/cloneset/deployment resource
 		
 		kubectl-kruise rollout resume rollout/nginx
 		kubectl-kruise rollout resume cloneset/nginx
-		kubectl-kruise rollout resume deployment/nginx`)
+		kubectl-kruise rollout resume deployment/nginx
+
+		# Resume the rolling update of an advanced daemonset
+		kubectl-kruise rollout resume daemonsets.apps.kruise.io/nginx`)
 )
 
 // NewRolloutResumeOptions returns an initialized ResumeOptions instance
@@ -80,7 +84,7 @@ func NewRolloutResumeOptions(streams genericclioptions.IOStreams) *ResumeOptions
 func NewCmdRolloutResume(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
 	o := NewRolloutResumeOptions(streams)
 
-	validArgs := []string{"deployment", "cloneset", "rollout"}
+	validArgs := []string{"deployment", "cloneset", "advanced statefulset", "advanced daemonset", "uniteddeployment", "rollout"}
 
 	cmd := &cobra.Command{
 		Use:                   "resume RESOURCE",
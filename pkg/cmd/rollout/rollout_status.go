@@ -18,7 +18,10 @@ package rollout
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	internalapi "github.com/openkruise/kruise-tools/pkg/api"
@@ -31,6 +34,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/resource"
@@ -43,6 +47,7 @@ import (
 	"k8s.io/kubectl/pkg/util/i18n"
 	"k8s.io/kubectl/pkg/util/interrupt"
 	"k8s.io/kubectl/pkg/util/templates"
+	uexec "k8s.io/utils/exec"
 )
 
 var (
@@ -54,7 +59,14 @@ var (
 		you can use --watch=false. Note that if a new rollout starts in-between, then
 		'rollout status' will continue watching the latest revision. If you want to
 		pin to a specific revision and abort if it is rolled over by another revision,
-		use --revision=N where N is the revision you need to watch for.`)
+		use --revision=N where N is the revision you need to watch for.
+
+		The exit code is 0 once the rollout is done, 2 if it failed or was aborted,
+		3 if it is still progressing when --timeout is reached, and 1 on any other error,
+		including an interrupt. A paused rollout is watched until it is resumed or approved,
+		unless --exit-on-pause is set, which exits with 4 once it is paused and waits to be
+		resumed or approved. With -o json, a JSON snapshot of the status is printed on a line
+		for every update, and --exit-on-pause is implied.`)
 
 	statusExample = templates.Examples(`
 		# Watch the rollout status of a deployment
@@ -64,9 +76,54 @@ var (
 		kubectl-kruise rollout status cloneset/nginx
 
 		# Watch the rollout status of a advanced statefulset
-		kubectl-kruise rollout status asts/nginx`)
+		kubectl-kruise rollout status asts/nginx
+
+		# Watch the rollout status of an advanced daemonset
+		kubectl-kruise rollout status daemonsets.apps.kruise.io/nginx
+
+		# Watch the rollout status of each subset of a uniteddeployment
+		kubectl-kruise rollout status uniteddeployment/nginx
+
+		# Watch the steps of a kruise rollout until it is healthy
+		kubectl-kruise rollout status rollout/rollout-demo
+
+		# Print a JSON snapshot of every status update of a cloneset, and give up after 10 minutes
+		kubectl-kruise rollout status cloneset/nginx -o json --timeout=10m
+
+		# Watch the steps of a kruise rollout, and exit with 4 once a step waits to be approved
+		kubectl-kruise rollout status rollout/rollout-demo --exit-on-pause`)
 )
 
+// Exit codes of 'rollout status' besides 0 once the rollout is done, and 1 on any other error.
+const (
+	StatusExitCodeFailed   = 2
+	StatusExitCodeTimedOut = 3
+	// StatusExitCodePaused is only used with --exit-on-pause or -o json.
+	StatusExitCodePaused = 4
+)
+
+// Phases of a RolloutStatusSnapshot.
+const (
+	StatusPhaseDone        = "Done"
+	StatusPhaseProgressing = "Progressing"
+	StatusPhasePaused      = "Paused"
+	StatusPhaseFailed      = "Failed"
+	StatusPhaseTimedOut    = "TimedOut"
+	StatusPhaseError       = "Error"
+)
+
+// RolloutStatusSnapshot is printed as a line of JSON for every update of the status with -o json.
+type RolloutStatusSnapshot struct {
+	Kind      string      `json:"kind"`
+	Namespace string      `json:"namespace"`
+	Name      string      `json:"name"`
+	Phase     string      `json:"phase"`
+	Done      bool        `json:"done"`
+	Message   string      `json:"message,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Time      metav1.Time `json:"time"`
+}
+
 // RolloutStatusOptions holds the command-line options for 'rollout status' sub command
 type RolloutStatusOptions struct {
 	PrintFlags *genericclioptions.PrintFlags
@@ -79,6 +136,9 @@ type RolloutStatusOptions struct {
 	Revision int64
 	Timeout  time.Duration
 	Detail   bool
+	Output   string
+	// ExitOnPause stops watching a paused rollout, rather than waiting for it to be resumed or approved.
+	ExitOnPause bool
 
 	StatusViewerFn func(*meta.RESTMapping) (internalpolymorphichelpers.StatusViewer, error)
 	Builder        func() *resource.Builder
@@ -105,7 +165,8 @@ func NewRolloutStatusOptions(streams genericclioptions.IOStreams) *RolloutStatus
 func NewCmdRolloutStatus(f cmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
 	o := NewRolloutStatusOptions(streams)
 
-	validArgs := []string{"deployment", "daemonset", "statefulset", "cloneset", "advanced statefulset"}
+	validArgs := []string{"deployment", "daemonset", "statefulset", "cloneset", "advanced statefulset", "advanced daemonset",
+		"uniteddeployment", "rollout"}
 
 	cmd := &cobra.Command{
 		Use:                   "status (TYPE NAME | TYPE/NAME) [flags]",
@@ -127,6 +188,8 @@ func NewCmdRolloutStatus(f cmdutil.Factory, streams genericclioptions.IOStreams)
 	cmd.Flags().Int64Var(&o.Revision, "revision", o.Revision, "Pin to a specific revision for showing its status. Defaults to 0 (last revision).")
 	cmd.Flags().DurationVar(&o.Timeout, "timeout", o.Timeout, "The length of time to wait before ending watch, zero means never. Any other values should contain a corresponding time unit (e.g. 1s, 2m, 3h).")
 	cmd.Flags().BoolVarP(&o.Detail, "detail", "d", o.Detail, "Show the detail status of the rollout.")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", o.Output, "Output format. Only json is supported, which prints a JSON snapshot of the status on a line for every update, and implies --exit-on-pause.")
+	cmd.Flags().BoolVar(&o.ExitOnPause, "exit-on-pause", o.ExitOnPause, "If true, exit with code 4 once the rollout is paused and waits to be resumed or approved, rather than watching it.")
 
 	return cmd
 }
@@ -172,6 +235,10 @@ func (o *RolloutStatusOptions) Validate() error {
 		return fmt.Errorf("revision must be a positive integer: %v", o.Revision)
 	}
 
+	if len(o.Output) > 0 && o.Output != "json" {
+		return fmt.Errorf("unsupported output format %q, only json is supported", o.Output)
+	}
+
 	return nil
 }
 
@@ -236,7 +303,7 @@ func (o *RolloutStatusOptions) Run() error {
 	intr := interrupt.New(nil, cancel)
 	var status string
 	var consideredDone bool
-	return intr.Run(func() error {
+	err = intr.Run(func() error {
 		_, err = watchtools.UntilWithSync(ctx, lw, &unstructured.Unstructured{}, preconditionFunc, func(e watch.Event) (bool, error) {
 			switch t := e.Type; t {
 			case watch.Added, watch.Modified:
@@ -245,10 +312,17 @@ func (o *RolloutStatusOptions) Run() error {
 				} else {
 					status, consideredDone, err = statusViewer.Status(o.ClientSet, e.Object.(runtime.Unstructured), o.Revision)
 				}
+				var paused *internalpolymorphichelpers.RolloutPausedError
+				if errors.As(err, &paused) && !o.exitsOnPause() {
+					// like kubectl, a paused rollout is watched until it is resumed or approved
+					err = nil
+				}
+				if printErr := o.printStatus(info, status, consideredDone, err); printErr != nil {
+					return false, printErr
+				}
 				if err != nil {
 					return false, err
 				}
-				fmt.Fprintf(o.Out, "%s", status)
 				// Quit waiting if the rollout is done
 				if consideredDone {
 					return true, nil
@@ -263,7 +337,11 @@ func (o *RolloutStatusOptions) Run() error {
 
 			case watch.Deleted:
 				// We need to abort to avoid cases of recreation and not to silently watch the wrong (new) object
-				return true, fmt.Errorf("object has been deleted")
+				err := &internalpolymorphichelpers.RolloutFailedError{Message: "object has been deleted"}
+				if printErr := o.printStatus(info, "", false, err); printErr != nil {
+					return false, printErr
+				}
+				return true, err
 
 			default:
 				return true, fmt.Errorf("internal error: unexpected event %#v", e)
@@ -271,4 +349,94 @@ func (o *RolloutStatusOptions) Run() error {
 		})
 		return err
 	})
+	return o.exitError(info, watchError(ctx, info, err))
+}
+
+// exitsOnPause returns whether watching stops once the rollout is paused.
+func (o *RolloutStatusOptions) exitsOnPause() bool {
+	return o.ExitOnPause || o.Output == "json"
+}
+
+// rolloutTimedOutError is returned once --timeout is reached before the rollout is done.
+type rolloutTimedOutError struct {
+	message string
+}
+
+func (e *rolloutTimedOutError) Error() string {
+	return e.message
+}
+
+// watchError returns the error of watching the status until ctx is done, which either timed out once the deadline
+// of --timeout passed, or was interrupted.
+func watchError(ctx context.Context, info *resource.Info, err error) error {
+	if !wait.Interrupted(err) {
+		return err
+	} else if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &rolloutTimedOutError{
+			message: fmt.Sprintf("timed out waiting for the rollout of %s %q to finish", info.Mapping.Resource.Resource, info.Name),
+		}
+	}
+	return fmt.Errorf("interrupted while waiting for the rollout of %s %q to finish", info.Mapping.Resource.Resource, info.Name)
+}
+
+// printStatus prints the status, or its snapshot with -o json. err is the error of the StatusViewer, if any.
+func (o *RolloutStatusOptions) printStatus(info *resource.Info, status string, done bool, err error) error {
+	if o.Output != "json" {
+		fmt.Fprintf(o.Out, "%s", status)
+		return nil
+	}
+
+	snapshot := RolloutStatusSnapshot{
+		Kind:      info.Mapping.GroupVersionKind.Kind,
+		Namespace: info.Namespace,
+		Name:      info.Name,
+		Phase:     statusPhase(done, err),
+		Done:      done,
+		Message:   strings.TrimSpace(status),
+		Time:      metav1.Now(),
+	}
+	if err != nil {
+		snapshot.Error = err.Error()
+	}
+	return json.NewEncoder(o.Out).Encode(snapshot)
+}
+
+// exitError returns the error of watching the status with the exit code of how the rollout ended.
+func (o *RolloutStatusOptions) exitError(info *resource.Info, err error) error {
+	var code int
+	switch statusPhase(false, err) {
+	case StatusPhaseFailed:
+		code = StatusExitCodeFailed
+	case StatusPhasePaused:
+		code = StatusExitCodePaused
+	case StatusPhaseTimedOut:
+		if printErr := o.printStatus(info, "", false, err); printErr != nil {
+			return printErr
+		}
+		code = StatusExitCodeTimedOut
+	default:
+		return err
+	}
+	return uexec.CodeExitError{Err: err, Code: code}
+}
+
+// statusPhase returns the phase of the status returned by a StatusViewer, or of the error of watching it.
+func statusPhase(done bool, err error) string {
+	var paused *internalpolymorphichelpers.RolloutPausedError
+	var failed *internalpolymorphichelpers.RolloutFailedError
+	var timedOut *rolloutTimedOutError
+	switch {
+	case err == nil && done:
+		return StatusPhaseDone
+	case err == nil:
+		return StatusPhaseProgressing
+	case errors.As(err, &paused):
+		return StatusPhasePaused
+	case errors.As(err, &failed):
+		return StatusPhaseFailed
+	case errors.As(err, &timedOut):
+		return StatusPhaseTimedOut
+	default:
+		return StatusPhaseError
+	}
 }
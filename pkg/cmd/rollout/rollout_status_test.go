@@ -0,0 +1,191 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	internalpolymorphichelpers "github.com/openkruise/kruise-tools/pkg/internal/polymorphichelpers"
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/resource"
+	uexec "k8s.io/utils/exec"
+)
+
+func TestRolloutStatusExitCodes(t *testing.T) {
+	info := &resource.Info{Namespace: "default", Name: "demo", Mapping: &meta.RESTMapping{
+		GroupVersionKind: schema.GroupVersionKind{Group: "apps.kruise.io", Version: "v1alpha1", Kind: "CloneSet"},
+		Resource:         schema.GroupVersionResource{Group: "apps.kruise.io", Version: "v1alpha1", Resource: "clonesets"},
+	}}
+
+	testCases := []struct {
+		name             string
+		done             bool
+		err              error
+		expectedSnapshot RolloutStatusSnapshot
+		expectedCode     int
+	}{
+		{
+			name:             "done",
+			done:             true,
+			expectedSnapshot: RolloutStatusSnapshot{Phase: StatusPhaseDone, Done: true, Message: "status"},
+		},
+		{
+			name:             "progressing",
+			expectedSnapshot: RolloutStatusSnapshot{Phase: StatusPhaseProgressing, Message: "status"},
+		},
+		{
+			name:             "paused",
+			err:              &internalpolymorphichelpers.RolloutPausedError{Message: "paused"},
+			expectedSnapshot: RolloutStatusSnapshot{Phase: StatusPhasePaused, Message: "status", Error: "paused"},
+			expectedCode:     StatusExitCodePaused,
+		},
+		{
+			name:             "failed",
+			err:              fmt.Errorf("wrapped: %w", &internalpolymorphichelpers.RolloutFailedError{Message: "failed"}),
+			expectedSnapshot: RolloutStatusSnapshot{Phase: StatusPhaseFailed, Message: "status", Error: "wrapped: failed"},
+			expectedCode:     StatusExitCodeFailed,
+		},
+		{
+			name:             "timed out",
+			err:              &rolloutTimedOutError{message: "timed out"},
+			expectedSnapshot: RolloutStatusSnapshot{Phase: StatusPhaseTimedOut, Message: "status", Error: "timed out"},
+			expectedCode:     StatusExitCodeTimedOut,
+		},
+		{
+			name:             "other errors",
+			err:              errors.New("other"),
+			expectedSnapshot: RolloutStatusSnapshot{Phase: StatusPhaseError, Message: "status", Error: "other"},
+			expectedCode:     1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			streams, _, out, _ := genericclioptions.NewTestIOStreams()
+			o := NewRolloutStatusOptions(streams)
+			o.Output = "json"
+
+			assert.NoError(t, o.printStatus(info, "status\n", tc.done, tc.err))
+			snapshot := RolloutStatusSnapshot{}
+			assert.NoError(t, json.Unmarshal(out.Bytes(), &snapshot))
+			assert.False(t, snapshot.Time.IsZero())
+			snapshot.Time = metav1.Time{}
+			tc.expectedSnapshot.Kind, tc.expectedSnapshot.Namespace, tc.expectedSnapshot.Name = "CloneSet", "default", "demo"
+			assert.Equal(t, tc.expectedSnapshot, snapshot)
+
+			out.Reset()
+			err := o.exitError(info, tc.err)
+			var exitErr uexec.CodeExitError
+			switch {
+			case tc.err == nil:
+				assert.NoError(t, err)
+			case errors.As(err, &exitErr):
+				assert.Equal(t, tc.expectedCode, exitErr.Code)
+			default:
+				assert.Equal(t, 1, tc.expectedCode)
+				assert.Equal(t, tc.err, err)
+			}
+		})
+	}
+}
+
+func TestRolloutStatusWatchError(t *testing.T) {
+	info := &resource.Info{Namespace: "default", Name: "demo", Mapping: &meta.RESTMapping{
+		Resource: schema.GroupVersionResource{Group: "apps.kruise.io", Version: "v1alpha1", Resource: "clonesets"},
+	}}
+	timedOut, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	interrupted, interrupt := context.WithCancel(context.Background())
+	interrupt()
+
+	testCases := []struct {
+		name          string
+		ctx           context.Context
+		err           error
+		expectedPhase string
+		expectedError string
+	}{
+		{
+			name:          "timeout reached",
+			ctx:           timedOut,
+			err:           wait.ErrorInterrupted(errors.New("timed out")),
+			expectedPhase: StatusPhaseTimedOut,
+			expectedError: "timed out waiting for the rollout of clonesets \"demo\" to finish",
+		},
+		{
+			name:          "interrupted",
+			ctx:           interrupted,
+			err:           wait.ErrorInterrupted(errors.New("timed out")),
+			expectedPhase: StatusPhaseError,
+			expectedError: "interrupted while waiting for the rollout of clonesets \"demo\" to finish",
+		},
+		{
+			name:          "failed",
+			ctx:           interrupted,
+			err:           &internalpolymorphichelpers.RolloutFailedError{Message: "failed"},
+			expectedPhase: StatusPhaseFailed,
+			expectedError: "failed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			<-tc.ctx.Done()
+			err := watchError(tc.ctx, info, tc.err)
+			assert.EqualError(t, err, tc.expectedError)
+			assert.Equal(t, tc.expectedPhase, statusPhase(false, err))
+		})
+	}
+}
+
+func TestRolloutStatusExitsOnPause(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     RolloutStatusOptions
+		expected bool
+	}{
+		{
+			name: "watch by default",
+		},
+		{
+			name:     "exit on pause",
+			opts:     RolloutStatusOptions{ExitOnPause: true},
+			expected: true,
+		},
+		{
+			name:     "json output",
+			opts:     RolloutStatusOptions{Output: "json"},
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.opts.exitsOnPause())
+		})
+	}
+}
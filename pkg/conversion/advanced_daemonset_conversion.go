@@ -0,0 +1,57 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Convert DaemonSet to Advanced DaemonSet
+func DaemonSetToAdvancedDaemonSet(ds *apps.DaemonSet, dstDaemonSetName string) *appsv1alpha1.DaemonSet {
+	// Deep copy first
+	from := ds.DeepCopy()
+
+	ads := &appsv1alpha1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   from.Namespace,
+			Name:        dstDaemonSetName,
+			Labels:      from.Labels,
+			Annotations: from.Annotations,
+			Finalizers:  from.Finalizers,
+		},
+		Spec: appsv1alpha1.DaemonSetSpec{
+			Selector:             from.Spec.Selector,
+			Template:             from.Spec.Template,
+			MinReadySeconds:      from.Spec.MinReadySeconds,
+			RevisionHistoryLimit: from.Spec.RevisionHistoryLimit,
+			UpdateStrategy: appsv1alpha1.DaemonSetUpdateStrategy{
+				Type: appsv1alpha1.DaemonSetUpdateStrategyType(from.Spec.UpdateStrategy.Type),
+			},
+		},
+	}
+
+	if from.Spec.UpdateStrategy.RollingUpdate != nil {
+		ads.Spec.UpdateStrategy.RollingUpdate = &appsv1alpha1.RollingUpdateDaemonSet{
+			Type:           appsv1alpha1.StandardRollingUpdateType,
+			MaxUnavailable: from.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable,
+			MaxSurge:       from.Spec.UpdateStrategy.RollingUpdate.MaxSurge,
+		}
+	}
+	return ads
+}
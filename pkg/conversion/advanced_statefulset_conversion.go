@@ -0,0 +1,73 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	appsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Convert StatefulSet to Advanced StatefulSet
+func StatefulSetToAdvancedStatefulSet(sts *apps.StatefulSet, dstStatefulSetName string) *appsv1beta1.StatefulSet {
+	// Deep copy first
+	from := sts.DeepCopy()
+
+	asts := &appsv1beta1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   from.Namespace,
+			Name:        dstStatefulSetName,
+			Labels:      from.Labels,
+			Annotations: from.Annotations,
+			Finalizers:  from.Finalizers,
+		},
+		Spec: appsv1beta1.StatefulSetSpec{
+			Replicas:             from.Spec.Replicas,
+			Selector:             from.Spec.Selector,
+			Template:             from.Spec.Template,
+			VolumeClaimTemplates: from.Spec.VolumeClaimTemplates,
+			ServiceName:          from.Spec.ServiceName,
+			PodManagementPolicy:  from.Spec.PodManagementPolicy,
+			RevisionHistoryLimit: from.Spec.RevisionHistoryLimit,
+			UpdateStrategy: appsv1beta1.StatefulSetUpdateStrategy{
+				Type: from.Spec.UpdateStrategy.Type,
+			},
+		},
+	}
+
+	if from.Spec.UpdateStrategy.RollingUpdate != nil || from.Spec.MinReadySeconds > 0 {
+		asts.Spec.UpdateStrategy.RollingUpdate = &appsv1beta1.RollingUpdateStatefulSetStrategy{}
+		if from.Spec.UpdateStrategy.RollingUpdate != nil {
+			asts.Spec.UpdateStrategy.RollingUpdate.Partition = from.Spec.UpdateStrategy.RollingUpdate.Partition
+			asts.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable = from.Spec.UpdateStrategy.RollingUpdate.MaxUnavailable
+		}
+		if from.Spec.MinReadySeconds > 0 {
+			asts.Spec.UpdateStrategy.RollingUpdate.MinReadySeconds = &from.Spec.MinReadySeconds
+		}
+	}
+
+	if from.Spec.PersistentVolumeClaimRetentionPolicy != nil {
+		asts.Spec.PersistentVolumeClaimRetentionPolicy = &appsv1beta1.StatefulSetPersistentVolumeClaimRetentionPolicy{
+			WhenDeleted: appsv1beta1.PersistentVolumeClaimRetentionPolicyType(from.Spec.PersistentVolumeClaimRetentionPolicy.WhenDeleted),
+			WhenScaled:  appsv1beta1.PersistentVolumeClaimRetentionPolicyType(from.Spec.PersistentVolumeClaimRetentionPolicy.WhenScaled),
+		}
+	}
+	if from.Spec.Ordinals != nil {
+		asts.Spec.Ordinals = &appsv1beta1.StatefulSetOrdinals{Start: from.Spec.Ordinals.Start}
+	}
+	return asts
+}
@@ -17,15 +17,81 @@ limitations under the License.
 package conversion
 
 import (
+	"fmt"
+	"os"
+
+	appspub "github.com/openkruise/kruise-api/apps/pub"
 	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	apps "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openkruise/kruise-tools/pkg/migration"
 )
 
+// deploymentRevisionAnnotation is set on a Deployment by its controller, and means nothing to a CloneSet.
+const deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// CloneSetProfile configures the fields of a CloneSet that a Deployment has no counterpart for.
+// It can be read from a profile file in YAML or JSON.
+type CloneSetProfile struct {
+	// UpdateStrategyType of the CloneSet, defaults to ReCreate which updates pods like a Deployment.
+	UpdateStrategyType appsv1alpha1.CloneSetUpdateStrategyType `json:"updateStrategyType,omitempty"`
+	// InPlaceUpdateStrategy is used when pods are updated in place.
+	InPlaceUpdateStrategy *appspub.InPlaceUpdateStrategy `json:"inPlaceUpdateStrategy,omitempty"`
+	// ScaleStrategy of the CloneSet.
+	ScaleStrategy *appsv1alpha1.CloneSetScaleStrategy `json:"scaleStrategy,omitempty"`
+	// Lifecycle hooks of the CloneSet.
+	Lifecycle *appspub.Lifecycle `json:"lifecycle,omitempty"`
+	// KeepFinalizers copies the finalizers of the Deployment, which are dropped by default
+	// since they are usually handled by controllers watching Deployments only.
+	KeepFinalizers bool `json:"keepFinalizers,omitempty"`
+}
+
+// Validate returns an error if the profile can not be used to convert.
+func (p *CloneSetProfile) Validate() error {
+	switch p.UpdateStrategyType {
+	case "", appsv1alpha1.RecreateCloneSetUpdateStrategyType,
+		appsv1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType,
+		appsv1alpha1.InPlaceOnlyCloneSetUpdateStrategyType:
+	default:
+		return fmt.Errorf("invalid update strategy type %q, must be one of %s, %s and %s", p.UpdateStrategyType,
+			appsv1alpha1.RecreateCloneSetUpdateStrategyType,
+			appsv1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType,
+			appsv1alpha1.InPlaceOnlyCloneSetUpdateStrategyType)
+	}
+	if p.InPlaceUpdateStrategy != nil && p.InPlaceUpdateStrategy.GracePeriodSeconds < 0 {
+		return fmt.Errorf("invalid in-place update grace period seconds %d", p.InPlaceUpdateStrategy.GracePeriodSeconds)
+	}
+	return nil
+}
+
+// LoadCloneSetProfile reads and validates the profile from a YAML or JSON file.
+func LoadCloneSetProfile(path string) (CloneSetProfile, error) {
+	profile := CloneSetProfile{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return profile, fmt.Errorf("failed to read conversion profile: %v", err)
+	}
+	if err := yaml.UnmarshalStrict(data, &profile); err != nil {
+		return profile, fmt.Errorf("failed to parse conversion profile %s: %v", path, err)
+	}
+	return profile, profile.Validate()
+}
+
 // Convert Deployment to CloneSet
 func DeploymentToCloneSet(deploy *apps.Deployment, dstCloneSetName string) *appsv1alpha1.CloneSet {
+	cs, _ := ConvertDeploymentToCloneSet(deploy, dstCloneSetName, &CloneSetProfile{})
+	return cs
+}
+
+// ConvertDeploymentToCloneSet converts the Deployment to a CloneSet configured by the profile,
+// and reports which fields were mapped, defaulted or dropped.
+func ConvertDeploymentToCloneSet(deploy *apps.Deployment, dstCloneSetName string, profile *CloneSetProfile) (*appsv1alpha1.CloneSet, Report) {
 	// Deep copy first
 	from := deploy.DeepCopy()
+	report := Report{}
 
 	cs := &appsv1alpha1.CloneSet{
 		ObjectMeta: metav1.ObjectMeta{
@@ -33,7 +99,6 @@ func DeploymentToCloneSet(deploy *apps.Deployment, dstCloneSetName string) *apps
 			Name:        dstCloneSetName,
 			Labels:      from.Labels,
 			Annotations: from.Annotations,
-			Finalizers:  from.Finalizers,
 		},
 		Spec: appsv1alpha1.CloneSetSpec{
 			Replicas:             from.Spec.Replicas,
@@ -43,18 +108,71 @@ func DeploymentToCloneSet(deploy *apps.Deployment, dstCloneSetName string) *apps
 			MinReadySeconds:      from.Spec.MinReadySeconds,
 			UpdateStrategy: appsv1alpha1.CloneSetUpdateStrategy{
 				Type:   appsv1alpha1.RecreateCloneSetUpdateStrategyType,
-				Paused: deploy.Spec.Paused,
+				Paused: from.Spec.Paused,
 			},
 		},
 	}
+	report.Mapped("metadata.labels", "metadata.labels")
+	if _, ok := cs.Annotations[deploymentRevisionAnnotation]; ok {
+		delete(cs.Annotations, deploymentRevisionAnnotation)
+		report.Dropped("metadata.annotations."+deploymentRevisionAnnotation, "set by the Deployment controller")
+	}
+	if _, ok := cs.Annotations[migration.StateAnnotation]; ok {
+		delete(cs.Annotations, migration.StateAnnotation)
+		report.Dropped("metadata.annotations."+migration.StateAnnotation, "recorded by the migration task of the Deployment")
+	}
+	report.Mapped("metadata.annotations", "metadata.annotations")
+	if len(from.Finalizers) > 0 {
+		if profile.KeepFinalizers {
+			cs.Finalizers = from.Finalizers
+			report.Mapped("metadata.finalizers", "metadata.finalizers")
+		} else {
+			report.Dropped("metadata.finalizers", "handled by controllers of the Deployment, keep them with the profile")
+		}
+	}
+	report.Mapped("spec.replicas", "spec.replicas")
+	report.Mapped("spec.selector", "spec.selector")
+	report.Mapped("spec.template", "spec.template")
+	report.Mapped("spec.revisionHistoryLimit", "spec.revisionHistoryLimit")
+	report.Mapped("spec.minReadySeconds", "spec.minReadySeconds")
+	report.Mapped("spec.paused", "spec.updateStrategy.paused")
 
-	if from.Spec.Strategy.RollingUpdate != nil {
+	if from.Spec.Strategy.Type == apps.RecreateDeploymentStrategyType {
+		// CloneSet has no strategy to delete all old pods before creating new ones
+		maxUnavailable := intstr.FromString("100%")
+		cs.Spec.UpdateStrategy.MaxUnavailable = &maxUnavailable
+		cs.Spec.UpdateStrategy.MaxSurge = nil
+		report.Mapped("spec.strategy.type", "spec.updateStrategy.maxUnavailable=100%")
+	} else if from.Spec.Strategy.RollingUpdate != nil {
 		if from.Spec.Strategy.RollingUpdate.MaxUnavailable != nil {
 			cs.Spec.UpdateStrategy.MaxUnavailable = from.Spec.Strategy.RollingUpdate.MaxUnavailable
+			report.Mapped("spec.strategy.rollingUpdate.maxUnavailable", "spec.updateStrategy.maxUnavailable")
 		}
 		if from.Spec.Strategy.RollingUpdate.MaxSurge != nil {
 			cs.Spec.UpdateStrategy.MaxSurge = from.Spec.Strategy.RollingUpdate.MaxSurge
+			report.Mapped("spec.strategy.rollingUpdate.maxSurge", "spec.updateStrategy.maxSurge")
 		}
 	}
-	return cs
+	if from.Spec.ProgressDeadlineSeconds != nil {
+		report.Dropped("spec.progressDeadlineSeconds", "not supported by CloneSet")
+	}
+
+	if len(profile.UpdateStrategyType) > 0 {
+		cs.Spec.UpdateStrategy.Type = profile.UpdateStrategyType
+	} else {
+		report.Defaulted("spec.updateStrategy.type", cs.Spec.UpdateStrategy.Type)
+	}
+	if profile.InPlaceUpdateStrategy != nil {
+		cs.Spec.UpdateStrategy.InPlaceUpdateStrategy = profile.InPlaceUpdateStrategy.DeepCopy()
+		report.Defaulted("spec.updateStrategy.inPlaceUpdateStrategy.gracePeriodSeconds", profile.InPlaceUpdateStrategy.GracePeriodSeconds)
+	}
+	if profile.ScaleStrategy != nil {
+		cs.Spec.ScaleStrategy = *profile.ScaleStrategy.DeepCopy()
+		report.Defaulted("spec.scaleStrategy", "from profile")
+	}
+	if profile.Lifecycle != nil {
+		cs.Spec.Lifecycle = profile.Lifecycle.DeepCopy()
+		report.Defaulted("spec.lifecycle", "from profile")
+	}
+	return cs, report
 }
@@ -0,0 +1,62 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openkruise/kruise-tools/pkg/migration"
+)
+
+// Convert CloneSet to Deployment
+func CloneSetToDeployment(cs *appsv1alpha1.CloneSet, dstDeploymentName string) *apps.Deployment {
+	// Deep copy first
+	from := cs.DeepCopy()
+	// the migration task recorded in the CloneSet does not belong to the Deployment,
+	// and finalizers are handled by controllers watching CloneSets only
+	delete(from.Annotations, migration.StateAnnotation)
+
+	deploy := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   from.Namespace,
+			Name:        dstDeploymentName,
+			Labels:      from.Labels,
+			Annotations: from.Annotations,
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas:             from.Spec.Replicas,
+			Selector:             from.Spec.Selector,
+			Template:             from.Spec.Template,
+			RevisionHistoryLimit: from.Spec.RevisionHistoryLimit,
+			MinReadySeconds:      from.Spec.MinReadySeconds,
+			Paused:               from.Spec.UpdateStrategy.Paused,
+			Strategy: apps.DeploymentStrategy{
+				Type: apps.RollingUpdateDeploymentStrategyType,
+			},
+		},
+	}
+
+	if from.Spec.UpdateStrategy.MaxUnavailable != nil || from.Spec.UpdateStrategy.MaxSurge != nil {
+		deploy.Spec.Strategy.RollingUpdate = &apps.RollingUpdateDeployment{
+			MaxUnavailable: from.Spec.UpdateStrategy.MaxUnavailable,
+			MaxSurge:       from.Spec.UpdateStrategy.MaxSurge,
+		}
+	}
+	return deploy
+}
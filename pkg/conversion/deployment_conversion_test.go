@@ -0,0 +1,148 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"testing"
+
+	appspub "github.com/openkruise/kruise-api/apps/pub"
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+func TestCloneSetToDeploymentRoundTrip(t *testing.T) {
+	maxSurge := intstr.FromString("25%")
+	maxUnavailable := intstr.FromInt32(1)
+	deploy := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "demo",
+			Labels:    map[string]string{"app": "demo"},
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas:             ptr.To[int32](3),
+			Selector:             &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}},
+			RevisionHistoryLimit: ptr.To[int32](5),
+			MinReadySeconds:      10,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "demo"}},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "main", Image: "nginx"}}},
+			},
+			Strategy: apps.DeploymentStrategy{
+				Type: apps.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &apps.RollingUpdateDeployment{
+					MaxSurge:       &maxSurge,
+					MaxUnavailable: &maxUnavailable,
+				},
+			},
+		},
+	}
+
+	got := CloneSetToDeployment(DeploymentToCloneSet(deploy, "demo-cs"), "demo")
+	assert.Equal(t, deploy.ObjectMeta, got.ObjectMeta)
+	assert.Equal(t, deploy.Spec, got.Spec)
+}
+
+func TestCloneSetToDeployment(t *testing.T) {
+	cs := &appsv1alpha1.CloneSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "demo",
+			Annotations: map[string]string{"migration.kruise.io/state": "{}", "owner": "demo"},
+			Finalizers:  []string{"example.com/protect"},
+		},
+	}
+
+	deploy := CloneSetToDeployment(cs, "demo")
+	assert.Equal(t, map[string]string{"owner": "demo"}, deploy.Annotations)
+	assert.Empty(t, deploy.Finalizers)
+	assert.Contains(t, cs.Annotations, "migration.kruise.io/state")
+}
+
+func TestConvertDeploymentToCloneSet(t *testing.T) {
+	deploy := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "demo",
+			Annotations: map[string]string{"deployment.kubernetes.io/revision": "3", "migration.kruise.io/state": "{}", "owner": "demo"},
+			Finalizers:  []string{"example.com/protect"},
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas:                ptr.To[int32](3),
+			ProgressDeadlineSeconds: ptr.To[int32](600),
+			Strategy:                apps.DeploymentStrategy{Type: apps.RecreateDeploymentStrategyType},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		profile  CloneSetProfile
+		validate func(t *testing.T, cs *appsv1alpha1.CloneSet, report Report)
+	}{
+		{
+			name: "default profile",
+			validate: func(t *testing.T, cs *appsv1alpha1.CloneSet, report Report) {
+				assert.Equal(t, map[string]string{"owner": "demo"}, cs.Annotations)
+				assert.Empty(t, cs.Finalizers)
+				assert.Equal(t, appsv1alpha1.RecreateCloneSetUpdateStrategyType, cs.Spec.UpdateStrategy.Type)
+				assert.Equal(t, ptr.To(intstr.FromString("100%")), cs.Spec.UpdateStrategy.MaxUnavailable)
+				assert.Contains(t, report, FieldReport{Field: "metadata.finalizers", Action: FieldDropped,
+					Detail: "handled by controllers of the Deployment, keep them with the profile"})
+				assert.Contains(t, report, FieldReport{Field: "metadata.annotations.migration.kruise.io/state", Action: FieldDropped,
+					Detail: "recorded by the migration task of the Deployment"})
+				assert.Contains(t, report, FieldReport{Field: "spec.progressDeadlineSeconds", Action: FieldDropped,
+					Detail: "not supported by CloneSet"})
+				assert.Contains(t, report, FieldReport{Field: "spec.updateStrategy.type", Action: FieldDefaulted, Detail: "ReCreate"})
+			},
+		},
+		{
+			name: "in-place profile",
+			profile: CloneSetProfile{
+				UpdateStrategyType:    appsv1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType,
+				InPlaceUpdateStrategy: &appspub.InPlaceUpdateStrategy{GracePeriodSeconds: 10},
+				ScaleStrategy:         &appsv1alpha1.CloneSetScaleStrategy{DisablePVCReuse: true},
+				Lifecycle:             &appspub.Lifecycle{PreDelete: &appspub.LifecycleHook{MarkPodNotReady: true}},
+				KeepFinalizers:        true,
+			},
+			validate: func(t *testing.T, cs *appsv1alpha1.CloneSet, report Report) {
+				assert.Equal(t, []string{"example.com/protect"}, cs.Finalizers)
+				assert.Equal(t, appsv1alpha1.InPlaceIfPossibleCloneSetUpdateStrategyType, cs.Spec.UpdateStrategy.Type)
+				assert.Equal(t, int32(10), cs.Spec.UpdateStrategy.InPlaceUpdateStrategy.GracePeriodSeconds)
+				assert.True(t, cs.Spec.ScaleStrategy.DisablePVCReuse)
+				assert.True(t, cs.Spec.Lifecycle.PreDelete.MarkPodNotReady)
+				assert.Contains(t, report, FieldReport{Field: "metadata.finalizers", Action: FieldMapped, Detail: "metadata.finalizers"})
+				for _, field := range report {
+					assert.NotEqual(t, "spec.updateStrategy.type", field.Field, "set by the profile, not defaulted")
+				}
+				assert.Contains(t, report, FieldReport{Field: "spec.lifecycle", Action: FieldDefaulted, Detail: "from profile"})
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.NoError(t, tc.profile.Validate())
+			cs, report := ConvertDeploymentToCloneSet(deploy, "demo", &tc.profile)
+			tc.validate(t, cs, report)
+		})
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// ManifestKinds maps the kinds that manifests can be converted to, to the kinds they are converted from.
+var ManifestKinds = map[schema.GroupVersionKind]schema.GroupVersionKind{
+	api.CloneSetKind:            api.DeploymentKind,
+	api.AdvancedStatefulSetKind: api.StatefulSetKind,
+	api.AdvancedDaemonSetKind:   api.DaemonSetKind,
+}
+
+// ConvertManifest converts the workload in node to the kind to in place, keeping the comments and the order
+// of the fields that are kept by the conversion. It returns false if node is not of the kind converted to to.
+func ConvertManifest(node *yaml.RNode, to schema.GroupVersionKind, profile *CloneSetProfile) (bool, Report, error) {
+	from, ok := ManifestKinds[to]
+	if !ok {
+		return false, nil, fmt.Errorf("unsupported kind %v to convert to", to)
+	} else if schema.FromAPIVersionAndKind(node.GetApiVersion(), node.GetKind()) != from {
+		return false, nil, nil
+	}
+
+	data, err := node.MarshalJSON()
+	if err != nil {
+		return false, nil, err
+	}
+	var converted runtime.Object
+	var report Report
+	switch from {
+	case api.DeploymentKind:
+		deploy := &apps.Deployment{}
+		if err := json.Unmarshal(data, deploy); err != nil {
+			return false, nil, fmt.Errorf("failed to parse Deployment %s: %v", node.GetName(), err)
+		}
+		converted, report = ConvertDeploymentToCloneSet(deploy, deploy.Name, profile)
+	case api.StatefulSetKind:
+		sts := &apps.StatefulSet{}
+		if err := json.Unmarshal(data, sts); err != nil {
+			return false, nil, fmt.Errorf("failed to parse StatefulSet %s: %v", node.GetName(), err)
+		}
+		converted = StatefulSetToAdvancedStatefulSet(sts, sts.Name)
+	case api.DaemonSetKind:
+		ds := &apps.DaemonSet{}
+		if err := json.Unmarshal(data, ds); err != nil {
+			return false, nil, fmt.Errorf("failed to parse DaemonSet %s: %v", node.GetName(), err)
+		}
+		converted = DaemonSetToAdvancedDaemonSet(ds, ds.Name)
+	}
+	converted.GetObjectKind().SetGroupVersionKind(to)
+
+	value, err := runtime.DefaultUnstructuredConverter.ToUnstructured(converted)
+	if err != nil {
+		return false, nil, err
+	}
+	// status is owned by the controller of the converted workload
+	delete(value, "status")
+	if err := syncNode(node.YNode(), pruneNil(value)); err != nil {
+		return false, nil, err
+	}
+	return true, report, nil
+}
+
+// syncNode changes node to represent value, keeping the nodes that already represent a part of it
+// so that their comments and order are not lost.
+func syncNode(node *yaml.Node, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if node.Kind != yaml.MappingNode {
+			return replaceNode(node, value)
+		}
+		var content []*yaml.Node
+		seen := make(map[string]bool, len(v))
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			fieldValue, ok := v[key]
+			if !ok {
+				continue
+			}
+			seen[key] = true
+			if err := syncNode(node.Content[i+1], fieldValue); err != nil {
+				return err
+			}
+			content = append(content, node.Content[i], node.Content[i+1])
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if seen[key] {
+				continue
+			} else if m, ok := v[key].(map[string]interface{}); ok && len(m) == 0 {
+				// zero structs of the converted object, which were not written in the manifest
+				continue
+			}
+			fieldNode := &yaml.Node{}
+			if err := fieldNode.Encode(v[key]); err != nil {
+				return err
+			}
+			content = append(content, &yaml.Node{Kind: yaml.ScalarNode, Tag: yaml.NodeTagString, Value: key}, fieldNode)
+		}
+		node.Content = content
+		return nil
+
+	case []interface{}:
+		if node.Kind != yaml.SequenceNode || len(node.Content) != len(v) {
+			return replaceNode(node, value)
+		}
+		for i := range v {
+			if err := syncNode(node.Content[i], v[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var current interface{}
+	if node.Kind == yaml.ScalarNode && node.Decode(&current) == nil {
+		currentData, err1 := json.Marshal(current)
+		valueData, err2 := json.Marshal(value)
+		if err1 == nil && err2 == nil && bytes.Equal(currentData, valueData) {
+			return nil
+		}
+	}
+	return replaceNode(node, value)
+}
+
+// replaceNode encodes value into node, keeping the comments of node.
+func replaceNode(node *yaml.Node, value interface{}) error {
+	newNode := yaml.Node{}
+	if err := newNode.Encode(value); err != nil {
+		return err
+	}
+	newNode.HeadComment, newNode.LineComment, newNode.FootComment = node.HeadComment, node.LineComment, node.FootComment
+	*node = newNode
+	return nil
+}
+
+// pruneNil removes the nil fields, such as the zero creationTimestamp, from the unstructured value.
+func pruneNil(value map[string]interface{}) map[string]interface{} {
+	for key, fieldValue := range value {
+		switch v := fieldValue.(type) {
+		case nil:
+			delete(value, key)
+		case map[string]interface{}:
+			pruneNil(v)
+		case []interface{}:
+			for _, item := range v {
+				if m, ok := item.(map[string]interface{}); ok {
+					pruneNil(m)
+				}
+			}
+		}
+	}
+	return value
+}
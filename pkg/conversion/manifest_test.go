@@ -0,0 +1,160 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func TestConvertManifest(t *testing.T) {
+	testCases := []struct {
+		name              string
+		to                schema.GroupVersionKind
+		manifest          string
+		expectedConverted bool
+		expectedManifest  string
+	}{
+		{
+			name: "deployment",
+			to:   api.CloneSetKind,
+			manifest: `# web workload
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web # the name
+spec:
+  replicas: 3
+  progressDeadlineSeconds: 600
+  selector:
+    matchLabels:
+      app: web
+  strategy:
+    rollingUpdate:
+      maxSurge: 25%
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        # pinned image
+        image: nginx:1.25
+`,
+			expectedConverted: true,
+			expectedManifest: `# web workload
+apiVersion: apps.kruise.io/v1alpha1
+kind: CloneSet
+metadata:
+  name: web # the name
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        # pinned image
+        image: nginx:1.25
+  updateStrategy:
+    maxSurge: 25%
+    type: ReCreate
+`,
+		},
+		{
+			name: "statefulset",
+			to:   api.AdvancedStatefulSetKind,
+			manifest: `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  serviceName: db # headless
+  minReadySeconds: 10
+  selector:
+    matchLabels:
+      app: db
+  template:
+    metadata:
+      labels:
+        app: db
+    spec:
+      containers:
+      - name: db
+        image: mysql:8
+`,
+			expectedConverted: true,
+			expectedManifest: `apiVersion: apps.kruise.io/v1beta1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  serviceName: db # headless
+  selector:
+    matchLabels:
+      app: db
+  template:
+    metadata:
+      labels:
+        app: db
+    spec:
+      containers:
+      - name: db
+        image: mysql:8
+  updateStrategy:
+    rollingUpdate:
+      minReadySeconds: 10
+`,
+		},
+		{
+			name: "not converted",
+			to:   api.AdvancedDaemonSetKind,
+			manifest: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`,
+			expectedManifest: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			node, err := yaml.Parse(tc.manifest)
+			assert.NoError(t, err)
+			converted, _, err := ConvertManifest(node, tc.to, &CloneSetProfile{})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedConverted, converted)
+			assert.Equal(t, tc.expectedManifest, node.MustString())
+		})
+	}
+}
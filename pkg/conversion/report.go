@@ -0,0 +1,59 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import "fmt"
+
+// FieldAction is what a conversion did with a field.
+type FieldAction string
+
+const (
+	// FieldMapped means that the field of the source was copied into a field of the destination.
+	FieldMapped FieldAction = "Mapped"
+	// FieldDefaulted means that the field of the destination was set without a counterpart in the source.
+	FieldDefaulted FieldAction = "Defaulted"
+	// FieldDropped means that the field of the source has no counterpart in the destination.
+	FieldDropped FieldAction = "Dropped"
+)
+
+// FieldReport describes how a field was converted.
+type FieldReport struct {
+	// Field is the path of the field in the source, or in the destination if it was defaulted.
+	Field  string      `json:"field"`
+	Action FieldAction `json:"action"`
+	// Detail is the field of the destination that was mapped into, the value that was defaulted,
+	// or the reason why the field was dropped.
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report lists the fields of a conversion in the order they were converted.
+type Report []FieldReport
+
+// Mapped records that field was copied into the field to.
+func (r *Report) Mapped(field, to string) {
+	*r = append(*r, FieldReport{Field: field, Action: FieldMapped, Detail: to})
+}
+
+// Defaulted records that field was set to value.
+func (r *Report) Defaulted(field string, value interface{}) {
+	*r = append(*r, FieldReport{Field: field, Action: FieldDefaulted, Detail: fmt.Sprintf("%v", value)})
+}
+
+// Dropped records that field was not converted for the reason.
+func (r *Report) Dropped(field, reason string) {
+	*r = append(*r, FieldReport{Field: field, Action: FieldDropped, Detail: reason})
+}
@@ -16,12 +16,47 @@ limitations under the License.
 
 package creation
 
-import "github.com/openkruise/kruise-tools/pkg/api"
+import (
+	"context"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/conversion"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
 
 type Control interface {
-	Create(src api.ResourceRef, dst api.ResourceRef, opts Options) error
+	// Create creates dst from src, and returns the created object with a report of how
+	// the fields of src were converted, if the control reports them.
+	Create(src api.ResourceRef, dst api.ResourceRef, opts Options) (client.Object, conversion.Report, error)
 }
 
 type Options struct {
 	CopyReplicas bool
+	// DryRun returns the object that would be created without persisting it.
+	DryRun DryRun
+	// CloneSetProfile configures the CloneSet converted from a Deployment.
+	CloneSetProfile conversion.CloneSetProfile
+}
+
+type DryRun string
+
+const (
+	// DryRunNone creates the object.
+	DryRunNone DryRun = ""
+	// DryRunClient only generates the object, without sending it to the API server.
+	DryRunClient DryRun = "client"
+	// DryRunServer sends the object to the API server to be validated but not persisted.
+	DryRunServer DryRun = "server"
+)
+
+// CreateObject creates the object generated by a control according to the dry run option.
+func CreateObject(c client.Client, obj client.Object, opts Options) (client.Object, error) {
+	switch opts.DryRun {
+	case DryRunClient:
+		return obj, nil
+	case DryRunServer:
+		return obj, c.Create(context.TODO(), obj, client.DryRunAll)
+	}
+	return obj, c.Create(context.TODO(), obj)
 }
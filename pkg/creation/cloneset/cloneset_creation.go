@@ -55,23 +55,30 @@ func NewControl(cfg *rest.Config) (creation.Control, error) {
 	return ctrl, nil
 }
 
-func (c *control) Create(src api.ResourceRef, dst api.ResourceRef, opts creation.Options) error {
+func (c *control) Create(src api.ResourceRef, dst api.ResourceRef, opts creation.Options) (client.Object, conversion.Report, error) {
 	if src.GetGroupVersionKind() != api.DeploymentKind {
-		return fmt.Errorf("invalid src type, currently only support %v", api.DeploymentKind.String())
+		return nil, nil, fmt.Errorf("invalid src type, currently only support %v", api.DeploymentKind.String())
 	} else if dst.GetGroupVersionKind() != api.CloneSetKind {
-		return fmt.Errorf("invalid dst type, must be %v", api.CloneSetKind.String())
+		return nil, nil, fmt.Errorf("invalid dst type, must be %v", api.CloneSetKind.String())
+	} else if err := opts.CloneSetProfile.Validate(); err != nil {
+		return nil, nil, err
 	}
 
 	if err := c.ensureCloneSetNotExists(dst); err != nil {
-		return err
+		return nil, nil, err
 	}
 	srcDeployment, err := c.getDeployment(src)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	dstCloneSet := conversion.DeploymentToCloneSet(srcDeployment, dst.Name)
-	return c.client.Create(context.TODO(), dstCloneSet)
+	dstCloneSet, report := conversion.ConvertDeploymentToCloneSet(srcDeployment, dst.Name, &opts.CloneSetProfile)
+	if !opts.CopyReplicas {
+		dstCloneSet.Spec.Replicas = func() *int32 { var i int32 = 0; return &i }()
+		report.Defaulted("spec.replicas", 0)
+	}
+	obj, err := creation.CreateObject(c.client, dstCloneSet, opts)
+	return obj, report, err
 }
 
 func (c *control) getDeployment(ref api.ResourceRef) (*apps.Deployment, error) {
@@ -0,0 +1,105 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemonset
+
+import (
+	"context"
+	"fmt"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/conversion"
+	"github.com/openkruise/kruise-tools/pkg/creation"
+	daemonsetmigration "github.com/openkruise/kruise-tools/pkg/migration/daemonset"
+
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+type control struct {
+	client client.Client
+}
+
+func NewControl(cfg *rest.Config) (creation.Control, error) {
+	scheme := api.GetScheme()
+	c, err := rest.HTTPClientFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := apiutil.NewDynamicRESTMapper(cfg, c)
+	if err != nil {
+		return nil, err
+	}
+
+	ctrl := &control{}
+	if ctrl.client, err = client.New(cfg, client.Options{Scheme: scheme, Mapper: mapper}); err != nil {
+		return nil, err
+	}
+
+	return ctrl, nil
+}
+
+func (c *control) Create(src api.ResourceRef, dst api.ResourceRef, opts creation.Options) (client.Object, conversion.Report, error) {
+	if src.GetGroupVersionKind() != api.DaemonSetKind {
+		return nil, nil, fmt.Errorf("invalid src type, currently only support %v", api.DaemonSetKind.String())
+	} else if dst.GetGroupVersionKind() != api.AdvancedDaemonSetKind {
+		return nil, nil, fmt.Errorf("invalid dst type, must be %v", api.AdvancedDaemonSetKind.String())
+	} else if opts.CopyReplicas {
+		return nil, nil, fmt.Errorf("can not copy replicas for %v, nodes are handed over by migration", dst)
+	}
+
+	if err := c.ensureAdvancedDaemonSetNotExists(dst); err != nil {
+		return nil, nil, err
+	}
+	srcDaemonSet, err := c.getDaemonSet(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Advanced DaemonSet runs on no node until the migration hands nodes over to it, since the nodes
+	// are labeled as pending before it is created.
+	dstDaemonSet := conversion.DaemonSetToAdvancedDaemonSet(srcDaemonSet, dst.Name)
+	daemonsetmigration.AddNodeSelectorRequirement(&dstDaemonSet.Spec.Template, daemonsetmigration.DstNodeSelectorRequirement(src))
+	if opts.DryRun == creation.DryRunNone {
+		if err := daemonsetmigration.LabelPendingNodes(c.client, src); err != nil {
+			return nil, nil, err
+		}
+	}
+	obj, err := creation.CreateObject(c.client, dstDaemonSet, opts)
+	return obj, nil, err
+}
+
+func (c *control) getDaemonSet(ref api.ResourceRef) (*apps.DaemonSet, error) {
+	ds := &apps.DaemonSet{}
+	if err := c.client.Get(context.TODO(), ref.GetNamespacedName(), ds); err != nil {
+		return nil, fmt.Errorf("failed to get %v: %v", ref, err)
+	}
+	return ds, nil
+}
+
+func (c *control) ensureAdvancedDaemonSetNotExists(ref api.ResourceRef) error {
+	ads := &appsv1alpha1.DaemonSet{}
+	if err := c.client.Get(context.TODO(), ref.GetNamespacedName(), ads); err == nil {
+		return fmt.Errorf("advanced daemonset %v already exists", ref.GetNamespacedName())
+	} else if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get %v: %v", ref, err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deployment
+
+import (
+	"context"
+	"fmt"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/conversion"
+	"github.com/openkruise/kruise-tools/pkg/creation"
+
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+type control struct {
+	client client.Client
+}
+
+func NewControl(cfg *rest.Config) (creation.Control, error) {
+	scheme := api.GetScheme()
+	c, err := rest.HTTPClientFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := apiutil.NewDynamicRESTMapper(cfg, c)
+	if err != nil {
+		return nil, err
+	}
+
+	ctrl := &control{}
+	if ctrl.client, err = client.New(cfg, client.Options{Scheme: scheme, Mapper: mapper}); err != nil {
+		return nil, err
+	}
+
+	return ctrl, nil
+}
+
+func (c *control) Create(src api.ResourceRef, dst api.ResourceRef, opts creation.Options) (client.Object, conversion.Report, error) {
+	if src.GetGroupVersionKind() != api.CloneSetKind {
+		return nil, nil, fmt.Errorf("invalid src type, currently only support %v", api.CloneSetKind.String())
+	} else if dst.GetGroupVersionKind() != api.DeploymentKind {
+		return nil, nil, fmt.Errorf("invalid dst type, must be %v", api.DeploymentKind.String())
+	}
+
+	if err := c.ensureDeploymentNotExists(dst); err != nil {
+		return nil, nil, err
+	}
+	srcCloneSet, err := c.getCloneSet(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(srcCloneSet.Spec.VolumeClaimTemplates) > 0 {
+		return nil, nil, fmt.Errorf("can not create deployment from %v, volumeClaimTemplates are not supported by Deployment", src)
+	}
+
+	dstDeployment := conversion.CloneSetToDeployment(srcCloneSet, dst.Name)
+	if !opts.CopyReplicas {
+		dstDeployment.Spec.Replicas = func() *int32 { var i int32 = 0; return &i }()
+	}
+	obj, err := creation.CreateObject(c.client, dstDeployment, opts)
+	return obj, nil, err
+}
+
+func (c *control) getCloneSet(ref api.ResourceRef) (*appsv1alpha1.CloneSet, error) {
+	cs := &appsv1alpha1.CloneSet{}
+	if err := c.client.Get(context.TODO(), ref.GetNamespacedName(), cs); err != nil {
+		return nil, fmt.Errorf("failed to get %v: %v", ref, err)
+	}
+	return cs, nil
+}
+
+func (c *control) ensureDeploymentNotExists(ref api.ResourceRef) error {
+	d := &apps.Deployment{}
+	if err := c.client.Get(context.TODO(), ref.GetNamespacedName(), d); err == nil {
+		return fmt.Errorf("deployment %v already exists", ref.GetNamespacedName())
+	} else if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get %v: %v", ref, err)
+	}
+	return nil
+}
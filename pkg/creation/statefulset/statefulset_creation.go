@@ -0,0 +1,103 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statefulset
+
+import (
+	"context"
+	"fmt"
+
+	appsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/conversion"
+	"github.com/openkruise/kruise-tools/pkg/creation"
+
+	apps "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+type control struct {
+	client client.Client
+}
+
+func NewControl(cfg *rest.Config) (creation.Control, error) {
+	scheme := api.GetScheme()
+	c, err := rest.HTTPClientFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := apiutil.NewDynamicRESTMapper(cfg, c)
+	if err != nil {
+		return nil, err
+	}
+
+	ctrl := &control{}
+	if ctrl.client, err = client.New(cfg, client.Options{Scheme: scheme, Mapper: mapper}); err != nil {
+		return nil, err
+	}
+
+	return ctrl, nil
+}
+
+func (c *control) Create(src api.ResourceRef, dst api.ResourceRef, opts creation.Options) (client.Object, conversion.Report, error) {
+	if src.GetGroupVersionKind() != api.StatefulSetKind {
+		return nil, nil, fmt.Errorf("invalid src type, currently only support %v", api.StatefulSetKind.String())
+	} else if dst.GetGroupVersionKind() != api.AdvancedStatefulSetKind {
+		return nil, nil, fmt.Errorf("invalid dst type, must be %v", api.AdvancedStatefulSetKind.String())
+	}
+
+	// Pods and PVCs of a StatefulSet are named after it, so an Advanced StatefulSet with the
+	// same name can not run the same ordinals until they have been migrated from the source.
+	if opts.CopyReplicas && src.Name == dst.Name {
+		return nil, nil, fmt.Errorf("can not copy replicas into %v, it shares pod names with %v", dst, src)
+	}
+
+	if err := c.ensureAdvancedStatefulSetNotExists(dst); err != nil {
+		return nil, nil, err
+	}
+	srcStatefulSet, err := c.getStatefulSet(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dstStatefulSet := conversion.StatefulSetToAdvancedStatefulSet(srcStatefulSet, dst.Name)
+	if !opts.CopyReplicas {
+		dstStatefulSet.Spec.Replicas = func() *int32 { var i int32 = 0; return &i }()
+	}
+	obj, err := creation.CreateObject(c.client, dstStatefulSet, opts)
+	return obj, nil, err
+}
+
+func (c *control) getStatefulSet(ref api.ResourceRef) (*apps.StatefulSet, error) {
+	sts := &apps.StatefulSet{}
+	if err := c.client.Get(context.TODO(), ref.GetNamespacedName(), sts); err != nil {
+		return nil, fmt.Errorf("failed to get %v: %v", ref, err)
+	}
+	return sts, nil
+}
+
+func (c *control) ensureAdvancedStatefulSetNotExists(ref api.ResourceRef) error {
+	asts := &appsv1beta1.StatefulSet{}
+	if err := c.client.Get(context.TODO(), ref.GetNamespacedName(), asts); err == nil {
+		return fmt.Errorf("advanced statefulset %v already exists", ref.GetNamespacedName())
+	} else if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get %v: %v", ref, err)
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ package polymorphichelpers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"time"
@@ -37,6 +38,7 @@ import (
 	"k8s.io/apimachinery/pkg/watch"
 	coreclient "k8s.io/client-go/kubernetes/typed/core/v1"
 	watchtools "k8s.io/client-go/tools/watch"
+	"k8s.io/utils/ptr"
 )
 
 const (
@@ -249,7 +251,9 @@ func updateEnv(existing []corev1.EnvVar, env []corev1.EnvVar, remove []string) [
 	return out
 }
 
-func UpdateResourceEnv(object runtime.Object) {
+// UpdateResourceEnv restarts the pods of a CloneSet, Advanced StatefulSet or Advanced DaemonSet by setting the
+// RestartedEnv of their containers, which restarts them even when their in-place update would not for annotations.
+func UpdateResourceEnv(object runtime.Object) error {
 	var addingEnvs []corev1.EnvVar
 	var restartEnv = corev1.EnvVar{
 		Name:  RestartedEnv,
@@ -265,15 +269,23 @@ func UpdateResourceEnv(object runtime.Object) {
 		}
 
 	case *kruiseappsv1beta1.StatefulSet:
+		if obj.Spec.UpdateStrategy.RollingUpdate != nil && obj.Spec.UpdateStrategy.RollingUpdate.Paused {
+			return errors.New("can't restart paused advanced statefulset (run rollout resume first)")
+		}
 		for i := range obj.Spec.Template.Spec.Containers {
 			tmp := &obj.Spec.Template.Spec.Containers[i]
 			tmp.Env = updateEnv(tmp.Env, addingEnvs, []string{})
 		}
 	case *kruiseappsv1alpha1.DaemonSet:
+		if obj.Spec.UpdateStrategy.RollingUpdate != nil && ptr.Deref(obj.Spec.UpdateStrategy.RollingUpdate.Paused, false) {
+			return errors.New("can't restart paused advanced daemonset (run rollout resume first)")
+		}
 		for i := range obj.Spec.Template.Spec.Containers {
 			tmp := &obj.Spec.Template.Spec.Containers[i]
 			tmp.Env = updateEnv(tmp.Env, addingEnvs, []string{})
 		}
+	default:
+		return fmt.Errorf("restarting by env is not supported for %T", object)
 	}
-
+	return nil
 }
@@ -22,6 +22,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 	"text/tabwriter"
 
 	internalapps "github.com/openkruise/kruise-tools/pkg/internal/apps"
@@ -31,6 +33,7 @@ import (
 	kruiseclientsets "github.com/openkruise/kruise-api/client/clientset/versioned"
 	kruiseclientappsv1alpha1 "github.com/openkruise/kruise-api/client/clientset/versioned/typed/apps/v1alpha1"
 	kruiseclientappsv1beta1 "github.com/openkruise/kruise-api/client/clientset/versioned/typed/apps/v1beta1"
+	"github.com/pmezard/go-difflib/difflib"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -42,9 +45,11 @@ import (
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/client-go/kubernetes"
 	clientappsv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/kubectl/pkg/describe"
 	deploymentutil "k8s.io/kubectl/pkg/util/deployment"
 	sliceutil "k8s.io/kubectl/pkg/util/slice"
+	"sigs.k8s.io/yaml"
 )
 
 const (
@@ -54,6 +59,31 @@ const (
 // HistoryViewer provides an interface for resources have historical information.
 type HistoryViewer interface {
 	ViewHistory(namespace, name string, revision int64) (string, error)
+	// DiffHistory returns a unified diff of the pod templates of the revisions from and to.
+	DiffHistory(namespace, name string, from, to int64) (string, error)
+	// GetHistory returns the revisions sorted by revision number, with the pods currently on each of them.
+	GetHistory(namespace, name string) ([]RevisionHistory, error)
+}
+
+// RevisionHistory is a revision of a workload with the pods currently on it, as printed by 'rollout history'.
+type RevisionHistory struct {
+	Revision    int64  `json:"revision"`
+	ChangeCause string `json:"changeCause,omitempty"`
+	// ControllerRevision is the name of the ControllerRevision of the revision, for the workloads other than Deployment.
+	ControllerRevision string `json:"controllerRevision,omitempty"`
+	// ReplicaSet is the name of the ReplicaSet of the revision of a Deployment.
+	ReplicaSet        string      `json:"replicaSet,omitempty"`
+	CreationTimestamp metav1.Time `json:"creationTimestamp"`
+	Images            []string    `json:"images"`
+	// Pods is the number of pods of the workload currently on the revision.
+	Pods int32 `json:"pods"`
+	// PodNames are the names of the pods of the workload currently on the revision, sorted by name.
+	PodNames []string `json:"podNames,omitempty"`
+}
+
+func (h *RevisionHistory) addPod(pod *corev1.Pod) {
+	h.Pods++
+	h.PodNames = append(h.PodNames, pod.Name)
 }
 
 type HistoryVisitor struct {
@@ -134,82 +164,141 @@ func (v *HistoryVisitor) VisitAdvancedDaemonSet(kind internalapps.GroupKindEleme
 	v.result = &AdvancedDaemonSetHistoryViewer{v.clientset, v.kruiseclientset}
 }
 
-// TODO impl ViewHistory func for CloneSet
 func (h *CloneSetHistoryViewer) ViewHistory(namespace, name string, revision int64) (string, error) {
+	history, err := h.history(namespace, name)
+	if err != nil {
+		return "", err
+	}
+	return printHistory(history.revisions, revision, history.podTemplate)
+}
 
-	cs, history, err := clonesetHistory(h.k.AppsV1(), h.kc.AppsV1alpha1(), namespace, name)
+func (h *CloneSetHistoryViewer) DiffHistory(namespace, name string, from, to int64) (string, error) {
+	history, err := h.history(namespace, name)
 	if err != nil {
 		return "", err
 	}
+	return diffHistory(history.revisions, from, to, history.podTemplate)
+}
 
-	return printHistory(history, revision, func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
-		cloneSetOfHistory, err := applyCloneSetHistory(cs, history)
-		if err != nil {
-			return nil, err
-		}
-		return &cloneSetOfHistory.Spec.Template, err
-	})
+func (h *CloneSetHistoryViewer) GetHistory(namespace, name string) ([]RevisionHistory, error) {
+	history, err := h.history(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return history.revisionHistories(h.k.CoreV1())
+}
+
+func (h *CloneSetHistoryViewer) history(namespace, name string) (*controllerRevisionHistory, error) {
+	cs, history, err := clonesetHistory(h.k.AppsV1(), h.kc.AppsV1alpha1(), namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return &controllerRevisionHistory{
+		workload:  cs,
+		selector:  cs.Spec.Selector,
+		revisions: history,
+		podTemplate: func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+			cloneSetOfHistory, err := applyCloneSetHistory(cs, history)
+			if err != nil {
+				return nil, err
+			}
+			return &cloneSetOfHistory.Spec.Template, err
+		},
+	}, nil
 }
 
 func (h *AdvancedStatefulSetHistoryViewer) ViewHistory(namespace, name string, revision int64) (string, error) {
-	asts, history, err := advancedstsHistory(h.k.AppsV1(), h.kc.AppsV1beta1(), namespace, name)
+	history, err := h.history(namespace, name)
 	if err != nil {
 		return "", err
 	}
-	return printHistory(history, revision, func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
-		astsOfHistory, err := applyAdvancedStatefulSetHistory(asts, history)
-		if err != nil {
-			return nil, err
-		}
-		return &astsOfHistory.Spec.Template, err
-	})
+	return printHistory(history.revisions, revision, history.podTemplate)
+}
+
+func (h *AdvancedStatefulSetHistoryViewer) DiffHistory(namespace, name string, from, to int64) (string, error) {
+	history, err := h.history(namespace, name)
+	if err != nil {
+		return "", err
+	}
+	return diffHistory(history.revisions, from, to, history.podTemplate)
+}
+
+func (h *AdvancedStatefulSetHistoryViewer) GetHistory(namespace, name string) ([]RevisionHistory, error) {
+	history, err := h.history(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return history.revisionHistories(h.k.CoreV1())
+}
+
+func (h *AdvancedStatefulSetHistoryViewer) history(namespace, name string) (*controllerRevisionHistory, error) {
+	asts, history, err := advancedstsHistory(h.k.AppsV1(), h.kc.AppsV1beta1(), namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return &controllerRevisionHistory{
+		workload:  asts,
+		selector:  asts.Spec.Selector,
+		revisions: history,
+		podTemplate: func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+			astsOfHistory, err := applyAdvancedStatefulSetHistory(asts, history)
+			if err != nil {
+				return nil, err
+			}
+			return &astsOfHistory.Spec.Template, err
+		},
+	}, nil
 }
 
 func (h *AdvancedDaemonSetHistoryViewer) ViewHistory(namespace, name string, revision int64) (string, error) {
-	ads, history, err := advancedDaemonSetHistory(h.k.AppsV1(), h.kc.AppsV1alpha1(), namespace, name)
+	history, err := h.history(namespace, name)
 	if err != nil {
 		return "", err
 	}
-	return printHistory(history, revision, func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
-		adsOfHistory, err := applyAdvancedDaemonSetHistory(ads, history)
-		if err != nil {
-			return nil, err
-		}
-		return &adsOfHistory.Spec.Template, err
-	})
+	return printHistory(history.revisions, revision, history.podTemplate)
 }
 
-// ViewHistory returns a revision-to-replicaset map as the revision history of a deployment
-// TODO: this should be a describer
-func (h *DeploymentHistoryViewer) ViewHistory(namespace, name string, revision int64) (string, error) {
-	versionedAppsClient := h.c.AppsV1()
-	deployment, err := versionedAppsClient.Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+func (h *AdvancedDaemonSetHistoryViewer) DiffHistory(namespace, name string, from, to int64) (string, error) {
+	history, err := h.history(namespace, name)
 	if err != nil {
-		return "", fmt.Errorf("failed to retrieve deployment %s: %v", name, err)
+		return "", err
 	}
-	_, allOldRSs, newRS, err := deploymentutil.GetAllReplicaSets(deployment, versionedAppsClient)
+	return diffHistory(history.revisions, from, to, history.podTemplate)
+}
+
+func (h *AdvancedDaemonSetHistoryViewer) GetHistory(namespace, name string) ([]RevisionHistory, error) {
+	history, err := h.history(namespace, name)
 	if err != nil {
-		return "", fmt.Errorf("failed to retrieve replica sets from deployment %s: %v", name, err)
+		return nil, err
 	}
-	allRSs := allOldRSs
-	if newRS != nil {
-		allRSs = append(allRSs, newRS)
+	return history.revisionHistories(h.k.CoreV1())
+}
+
+func (h *AdvancedDaemonSetHistoryViewer) history(namespace, name string) (*controllerRevisionHistory, error) {
+	ads, history, err := advancedDaemonSetHistory(h.k.AppsV1(), h.kc.AppsV1alpha1(), namespace, name)
+	if err != nil {
+		return nil, err
 	}
+	return &controllerRevisionHistory{
+		workload:  ads,
+		selector:  ads.Spec.Selector,
+		revisions: history,
+		podTemplate: func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+			adsOfHistory, err := applyAdvancedDaemonSetHistory(ads, history)
+			if err != nil {
+				return nil, err
+			}
+			return &adsOfHistory.Spec.Template, err
+		},
+	}, nil
+}
 
-	historyInfo := make(map[int64]*corev1.PodTemplateSpec)
-	for _, rs := range allRSs {
-		v, err := deploymentutil.Revision(rs)
-		if err != nil {
-			continue
-		}
-		historyInfo[v] = &rs.Spec.Template
-		changeCause := getChangeCause(rs)
-		if historyInfo[v].Annotations == nil {
-			historyInfo[v].Annotations = make(map[string]string)
-		}
-		if len(changeCause) > 0 {
-			historyInfo[v].Annotations[ChangeCauseAnnotation] = changeCause
-		}
+// ViewHistory returns a revision-to-replicaset map as the revision history of a deployment
+// TODO: this should be a describer
+func (h *DeploymentHistoryViewer) ViewHistory(namespace, name string, revision int64) (string, error) {
+	_, historyInfo, err := h.history(namespace, name)
+	if err != nil {
+		return "", err
 	}
 
 	if len(historyInfo) == 0 {
@@ -218,11 +307,11 @@ func (h *DeploymentHistoryViewer) ViewHistory(namespace, name string, revision i
 
 	if revision > 0 {
 		// Print details of a specific revision
-		template, ok := historyInfo[revision]
+		rs, ok := historyInfo[revision]
 		if !ok {
 			return "", fmt.Errorf("unable to find the specified revision")
 		}
-		return printTemplate(template)
+		return printTemplate(&rs.Spec.Template)
 	}
 
 	// Sort the revisionToChangeCause map by revision
@@ -236,7 +325,7 @@ func (h *DeploymentHistoryViewer) ViewHistory(namespace, name string, revision i
 		fmt.Fprintf(out, "REVISION\tCHANGE-CAUSE\n")
 		for _, r := range revisions {
 			// Find the change-cause of revision r
-			changeCause := historyInfo[r].Annotations[ChangeCauseAnnotation]
+			changeCause := historyInfo[r].Spec.Template.Annotations[ChangeCauseAnnotation]
 			if len(changeCause) == 0 {
 				changeCause = "<none>"
 			}
@@ -246,6 +335,94 @@ func (h *DeploymentHistoryViewer) ViewHistory(namespace, name string, revision i
 	})
 }
 
+// DiffHistory returns a unified diff of the pod templates of the ReplicaSets of the revisions from and to,
+// without the pod-template-hash label that differs between all of them.
+func (h *DeploymentHistoryViewer) DiffHistory(namespace, name string, from, to int64) (string, error) {
+	_, historyInfo, err := h.history(namespace, name)
+	if err != nil {
+		return "", err
+	}
+	return diffTemplates(from, to, func(revision int64) (*corev1.PodTemplateSpec, error) {
+		rs, ok := historyInfo[revision]
+		if !ok {
+			return nil, fmt.Errorf("unable to find revision %d", revision)
+		}
+		// the hash label, the change-cause and the revision differ between all the revisions
+		template := rs.Spec.Template.DeepCopy()
+		delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+		delete(template.Annotations, ChangeCauseAnnotation)
+		delete(template.Annotations, deploymentutil.RevisionAnnotation)
+		return template, nil
+	})
+}
+
+// GetHistory returns the ReplicaSets of a deployment by revision, with the pods they control.
+func (h *DeploymentHistoryViewer) GetHistory(namespace, name string) ([]RevisionHistory, error) {
+	deployment, historyInfo, err := h.history(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := listPods(h.c.CoreV1(), namespace, deployment.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]int64, 0, len(historyInfo))
+	for r := range historyInfo {
+		revisions = append(revisions, r)
+	}
+	sliceutil.SortInts64(revisions)
+
+	histories := make([]RevisionHistory, 0, len(revisions))
+	for _, r := range revisions {
+		rs := historyInfo[r]
+		history := newRevisionHistory(r, rs, &rs.Spec.Template)
+		history.ReplicaSet = rs.Name
+		for i := range pods {
+			if metav1.IsControlledBy(&pods[i], rs) {
+				history.addPod(&pods[i])
+			}
+		}
+		histories = append(histories, history)
+	}
+	return histories, nil
+}
+
+// history returns a deployment and its ReplicaSets by revision, with the change-cause of the ReplicaSets
+// set on their pod templates.
+func (h *DeploymentHistoryViewer) history(namespace, name string) (*appsv1.Deployment, map[int64]*appsv1.ReplicaSet, error) {
+	versionedAppsClient := h.c.AppsV1()
+	deployment, err := versionedAppsClient.Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve deployment %s: %v", name, err)
+	}
+	_, allOldRSs, newRS, err := deploymentutil.GetAllReplicaSets(deployment, versionedAppsClient)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve replica sets from deployment %s: %v", name, err)
+	}
+	allRSs := allOldRSs
+	if newRS != nil {
+		allRSs = append(allRSs, newRS)
+	}
+
+	historyInfo := make(map[int64]*appsv1.ReplicaSet)
+	for _, rs := range allRSs {
+		v, err := deploymentutil.Revision(rs)
+		if err != nil {
+			continue
+		}
+		historyInfo[v] = rs
+		changeCause := getChangeCause(rs)
+		if rs.Spec.Template.Annotations == nil {
+			rs.Spec.Template.Annotations = make(map[string]string)
+		}
+		if len(changeCause) > 0 {
+			rs.Spec.Template.Annotations[ChangeCauseAnnotation] = changeCause
+		}
+	}
+	return deployment, historyInfo, nil
+}
+
 func printTemplate(template *corev1.PodTemplateSpec) (string, error) {
 	buf := bytes.NewBuffer([]byte{})
 	w := describe.NewPrefixWriter(buf)
@@ -260,22 +437,148 @@ type DaemonSetHistoryViewer struct {
 // ViewHistory returns a revision-to-history map as the revision history of a deployment
 // TODO: this should be a describer
 func (h *DaemonSetHistoryViewer) ViewHistory(namespace, name string, revision int64) (string, error) {
-	ds, history, err := daemonSetHistory(h.c.AppsV1(), namespace, name)
+	history, err := h.history(namespace, name)
 	if err != nil {
 		return "", err
 	}
-	return printHistory(history, revision, func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
-		dsOfHistory, err := applyDaemonSetHistory(ds, history)
+	return printHistory(history.revisions, revision, history.podTemplate)
+}
+
+func (h *DaemonSetHistoryViewer) DiffHistory(namespace, name string, from, to int64) (string, error) {
+	history, err := h.history(namespace, name)
+	if err != nil {
+		return "", err
+	}
+	return diffHistory(history.revisions, from, to, history.podTemplate)
+}
+
+func (h *DaemonSetHistoryViewer) GetHistory(namespace, name string) ([]RevisionHistory, error) {
+	history, err := h.history(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return history.revisionHistories(h.c.CoreV1())
+}
+
+func (h *DaemonSetHistoryViewer) history(namespace, name string) (*controllerRevisionHistory, error) {
+	ds, history, err := daemonSetHistory(h.c.AppsV1(), namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return &controllerRevisionHistory{
+		workload:  ds,
+		selector:  ds.Spec.Selector,
+		revisions: history,
+		podTemplate: func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+			dsOfHistory, err := applyDaemonSetHistory(ds, history)
+			if err != nil {
+				return nil, err
+			}
+			return &dsOfHistory.Spec.Template, err
+		},
+	}, nil
+}
+
+// podTemplateOfHistory returns the podTemplate of a workload at the given ControllerRevision
+type podTemplateOfHistory func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error)
+
+// controllerRevisionHistory is a workload with the ControllerRevisions in its history
+type controllerRevisionHistory struct {
+	workload    metav1.Object
+	selector    *metav1.LabelSelector
+	revisions   []*appsv1.ControllerRevision
+	podTemplate podTemplateOfHistory
+}
+
+// revisionHistories returns the revisions sorted by revision number, with the pods of the workload
+// whose controller-revision-hash label is the revision.
+func (h *controllerRevisionHistory) revisionHistories(c corev1client.CoreV1Interface) ([]RevisionHistory, error) {
+	pods, err := listPods(c, h.workload.GetNamespace(), h.selector)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]*appsv1.ControllerRevision, len(h.revisions))
+	copy(revisions, h.revisions)
+	sort.SliceStable(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+
+	histories := make([]RevisionHistory, 0, len(revisions))
+	for _, revision := range revisions {
+		podTemplate, err := h.podTemplate(revision)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("unable to parse history %s", revision.Name)
+		}
+		history := newRevisionHistory(revision.Revision, revision, podTemplate)
+		history.ControllerRevision = revision.Name
+		for i := range pods {
+			if metav1.IsControlledBy(&pods[i], h.workload) && isPodOfRevision(&pods[i], revision) {
+				history.addPod(&pods[i])
+			}
+		}
+		histories = append(histories, history)
+	}
+	return histories, nil
+}
+
+// isPodOfRevision returns whether the controller-revision-hash label of the pod is the revision, which is
+// the name of the revision for CloneSets and StatefulSets, and its hash for DaemonSets.
+func isPodOfRevision(pod *corev1.Pod, revision *appsv1.ControllerRevision) bool {
+	hash := pod.Labels[appsv1.ControllerRevisionHashLabelKey]
+	return len(hash) > 0 && (hash == revision.Name || hash == revision.Labels[appsv1.ControllerRevisionHashLabelKey])
+}
+
+// newRevisionHistory returns the RevisionHistory of the revision, with the change-cause and creation time of obj
+// and the images of podTemplate.
+func newRevisionHistory(revision int64, obj metav1.Object, podTemplate *corev1.PodTemplateSpec) RevisionHistory {
+	history := RevisionHistory{
+		Revision:          revision,
+		ChangeCause:       obj.GetAnnotations()[ChangeCauseAnnotation],
+		CreationTimestamp: obj.GetCreationTimestamp(),
+		Images:            []string{},
+	}
+	for _, container := range podTemplate.Spec.Containers {
+		history.Images = append(history.Images, container.Image)
+	}
+	return history
+}
+
+// listPods returns the pods in namespace selected by selector that are not being deleted, sorted by name
+func listPods(c corev1client.CoreV1Interface, namespace string, selector *metav1.LabelSelector) ([]corev1.Pod, error) {
+	allPods, err := listSelectedPods(c, namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]corev1.Pod, 0, len(allPods))
+	for _, pod := range allPods {
+		if pod.DeletionTimestamp == nil {
+			pods = append(pods, pod)
 		}
-		return &dsOfHistory.Spec.Template, err
+	}
+	return pods, nil
+}
+
+// listSelectedPods returns all the pods in namespace selected by selector, sorted by name
+func listSelectedPods(c corev1client.CoreV1Interface, namespace string, selector *metav1.LabelSelector) ([]corev1.Pod, error) {
+	podSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create selector: %v", err)
+	}
+	podList, err := c.Pods(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: podSelector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+	pods := podList.Items
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
 	})
+	return pods, nil
 }
 
 // printHistory returns the podTemplate of the given revision if it is non-zero
 // else returns the overall revisions
-func printHistory(history []*appsv1.ControllerRevision, revision int64, getPodTemplate func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error)) (string, error) {
+func printHistory(history []*appsv1.ControllerRevision, revision int64, getPodTemplate podTemplateOfHistory) (string, error) {
 	historyInfo := make(map[int64]*appsv1.ControllerRevision)
 	for _, history := range history {
 		// TODO: for now we assume revisions don't overlap, we may need to handle it
@@ -320,6 +623,85 @@ func printHistory(history []*appsv1.ControllerRevision, revision int64, getPodTe
 	})
 }
 
+// maxPrintedPodNames is the number of pod names printed for each revision by PrintRevisionHistories.
+const maxPrintedPodNames = 5
+
+// PrintRevisionHistories returns a table of the revisions with their change-cause and the pods on each of them.
+// Only the first pod names of a revision are printed, all of them are in the json output of 'rollout history'.
+func PrintRevisionHistories(histories []RevisionHistory) (string, error) {
+	if len(histories) == 0 {
+		return "No rollout history found.", nil
+	}
+	return tabbedString(func(out io.Writer) error {
+		fmt.Fprintf(out, "REVISION\tCHANGE-CAUSE\tPODS\tPOD-NAMES\n")
+		for _, history := range histories {
+			changeCause := history.ChangeCause
+			if len(changeCause) == 0 {
+				changeCause = "<none>"
+			}
+			podNames := "<none>"
+			if len(history.PodNames) > maxPrintedPodNames {
+				podNames = fmt.Sprintf("%s and %d more", strings.Join(history.PodNames[:maxPrintedPodNames], ","),
+					len(history.PodNames)-maxPrintedPodNames)
+			} else if len(history.PodNames) > 0 {
+				podNames = strings.Join(history.PodNames, ",")
+			}
+			fmt.Fprintf(out, "%d\t%s\t%d\t%s\n", history.Revision, changeCause, history.Pods, podNames)
+		}
+		return nil
+	})
+}
+
+// diffHistory returns a unified diff of the podTemplates of the revisions from and to
+func diffHistory(history []*appsv1.ControllerRevision, from, to int64, getPodTemplate podTemplateOfHistory) (string, error) {
+	historyInfo := make(map[int64]*appsv1.ControllerRevision)
+	for _, history := range history {
+		historyInfo[history.Revision] = history
+	}
+	return diffTemplates(from, to, func(revision int64) (*corev1.PodTemplateSpec, error) {
+		history, ok := historyInfo[revision]
+		if !ok {
+			return nil, fmt.Errorf("unable to find revision %d", revision)
+		}
+		podTemplate, err := getPodTemplate(history)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse history %s", history.Name)
+		}
+		return podTemplate, nil
+	})
+}
+
+// diffTemplates returns a unified diff of the podTemplates of the revisions from and to in yaml
+func diffTemplates(from, to int64, getPodTemplate func(revision int64) (*corev1.PodTemplateSpec, error)) (string, error) {
+	var lines [2][]string
+	for i, revision := range []int64{from, to} {
+		podTemplate, err := getPodTemplate(revision)
+		if err != nil {
+			return "", err
+		}
+		data, err := yaml.Marshal(podTemplate)
+		if err != nil {
+			return "", err
+		}
+		lines[i] = difflib.SplitLines(strings.TrimSuffix(string(data), "\n"))
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        lines[0],
+		B:        lines[1],
+		FromFile: fmt.Sprintf("revision %d", from),
+		ToFile:   fmt.Sprintf("revision %d", to),
+		Context:  3,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(diff) == 0 {
+		return "No differences found.", nil
+	}
+	return diff, nil
+}
+
 type StatefulSetHistoryViewer struct {
 	c kubernetes.Interface
 }
@@ -327,17 +709,46 @@ type StatefulSetHistoryViewer struct {
 // ViewHistory returns a list of the revision history of a statefulset
 // TODO: this should be a describer
 func (h *StatefulSetHistoryViewer) ViewHistory(namespace, name string, revision int64) (string, error) {
-	sts, history, err := statefulSetHistory(h.c.AppsV1(), namespace, name)
+	history, err := h.history(namespace, name)
 	if err != nil {
 		return "", err
 	}
-	return printHistory(history, revision, func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
-		stsOfHistory, err := applyStatefulSetHistory(sts, history)
-		if err != nil {
-			return nil, err
-		}
-		return &stsOfHistory.Spec.Template, err
-	})
+	return printHistory(history.revisions, revision, history.podTemplate)
+}
+
+func (h *StatefulSetHistoryViewer) DiffHistory(namespace, name string, from, to int64) (string, error) {
+	history, err := h.history(namespace, name)
+	if err != nil {
+		return "", err
+	}
+	return diffHistory(history.revisions, from, to, history.podTemplate)
+}
+
+func (h *StatefulSetHistoryViewer) GetHistory(namespace, name string) ([]RevisionHistory, error) {
+	history, err := h.history(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return history.revisionHistories(h.c.CoreV1())
+}
+
+func (h *StatefulSetHistoryViewer) history(namespace, name string) (*controllerRevisionHistory, error) {
+	sts, history, err := statefulSetHistory(h.c.AppsV1(), namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return &controllerRevisionHistory{
+		workload:  sts,
+		selector:  sts.Spec.Selector,
+		revisions: history,
+		podTemplate: func(history *appsv1.ControllerRevision) (*corev1.PodTemplateSpec, error) {
+			stsOfHistory, err := applyStatefulSetHistory(sts, history)
+			if err != nil {
+				return nil, err
+			}
+			return &stsOfHistory.Spec.Template, err
+		},
+	}, nil
 }
 
 // controlledHistories returns all ControllerRevisions in namespace that selected by selector and owned by accessor
@@ -0,0 +1,272 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"fmt"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+var historyLabels = map[string]string{"app": "demo"}
+
+func newHistoryTemplate(image string) corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: historyLabels},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "main", Image: image}}},
+	}
+}
+
+func newHistoryCloneSet() *kruiseappsv1alpha1.CloneSet {
+	return &kruiseappsv1alpha1.CloneSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo", UID: "cloneset-uid"},
+		Spec: kruiseappsv1alpha1.CloneSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: historyLabels},
+			Template: newHistoryTemplate("nginx:1.3"),
+		},
+	}
+}
+
+func newHistoryControllerRevision(revision int64, image string) *appsv1.ControllerRevision {
+	return &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: fmt.Sprintf("demo-%d", revision), Labels: historyLabels,
+			CreationTimestamp: metav1.Unix(revision, 0),
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps.kruise.io/v1alpha1", Kind: "CloneSet",
+				Name: "demo", UID: "cloneset-uid", Controller: ptr.To(true)}}},
+		Data: runtime.RawExtension{Raw: []byte(fmt.Sprintf(
+			`{"spec":{"template":{"$patch":"replace","metadata":{"labels":{"app":"demo"}},"spec":{"containers":[{"name":"main","image":"%s"}]}}}}`,
+			image))},
+		Revision: revision,
+	}
+}
+
+func newHistoryDeployment() *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo", UID: "deployment-uid"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: historyLabels},
+			Template: newHistoryTemplate("nginx:1.3"),
+		},
+	}
+}
+
+func newHistoryReplicaSet(revision int64, image string) *appsv1.ReplicaSet {
+	rsTemplate := newHistoryTemplate(image)
+	rsTemplate.Labels = map[string]string{"app": "demo", appsv1.DefaultDeploymentUniqueLabelKey: fmt.Sprintf("hash-%d", revision)}
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: fmt.Sprintf("demo-%d", revision), Labels: historyLabels,
+			UID:               types.UID(fmt.Sprintf("rs-uid-%d", revision)),
+			CreationTimestamp: metav1.Unix(revision, 0),
+			Annotations:       map[string]string{"deployment.kubernetes.io/revision": fmt.Sprint(revision)},
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment",
+				Name: "demo", UID: "deployment-uid", Controller: ptr.To(true)}}},
+		Spec: appsv1.ReplicaSetSpec{Replicas: ptr.To[int32](1), Selector: &metav1.LabelSelector{MatchLabels: historyLabels}, Template: rsTemplate},
+	}
+}
+
+func newHistoryPod(name string, labels map[string]string, kind, owner string, uid types.UID) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: labels,
+		OwnerReferences: []metav1.OwnerReference{{Kind: kind, Name: owner, UID: uid, Controller: ptr.To(true)}}}}
+}
+
+func TestDiffHistory(t *testing.T) {
+	cloneSetViewer := &CloneSetHistoryViewer{
+		k: fake.NewSimpleClientset(newHistoryControllerRevision(1, "nginx:1.1"), newHistoryControllerRevision(3, "nginx:1.2"),
+			newHistoryControllerRevision(5, "nginx:1.3")),
+		kc: kruisefake.NewSimpleClientset(newHistoryCloneSet()),
+	}
+	deploymentViewer := &DeploymentHistoryViewer{
+		c: fake.NewSimpleClientset(newHistoryDeployment(), newHistoryReplicaSet(3, "nginx:1.2"), newHistoryReplicaSet(5, "nginx:1.3")),
+	}
+	changeCauseReplicaSet := newHistoryReplicaSet(6, "nginx:1.3")
+	changeCauseReplicaSet.Annotations[ChangeCauseAnnotation] = "kubectl annotate deployment/demo"
+	changeCauseReplicaSet.Spec.Template.Annotations = map[string]string{"deployment.kubernetes.io/revision": "6"}
+	changeCauseViewer := &DeploymentHistoryViewer{
+		c: fake.NewSimpleClientset(newHistoryDeployment(), newHistoryReplicaSet(5, "nginx:1.3"), changeCauseReplicaSet),
+	}
+	changed := `--- revision 3
++++ revision 5
+@@ -4,6 +4,6 @@
+     app: demo
+ spec:
+   containers:
+-  - image: nginx:1.2
++  - image: nginx:1.3
+     name: main
+     resources: {}
+`
+
+	testCases := []struct {
+		name          string
+		viewer        HistoryViewer
+		from, to      int64
+		expectedDiff  string
+		expectedError string
+	}{
+		{
+			name:         "CloneSet revisions",
+			viewer:       cloneSetViewer,
+			from:         3,
+			to:           5,
+			expectedDiff: changed,
+		},
+		{
+			name:         "same CloneSet revision",
+			viewer:       cloneSetViewer,
+			from:         3,
+			to:           3,
+			expectedDiff: "No differences found.",
+		},
+		{
+			name:          "unknown CloneSet revision",
+			viewer:        cloneSetViewer,
+			from:          2,
+			to:            5,
+			expectedError: "unable to find revision 2",
+		},
+		{
+			name:         "Deployment revisions without pod-template-hash",
+			viewer:       deploymentViewer,
+			from:         3,
+			to:           5,
+			expectedDiff: changed,
+		},
+		{
+			name:         "Deployment revisions only differing in change-cause",
+			viewer:       changeCauseViewer,
+			from:         5,
+			to:           6,
+			expectedDiff: "No differences found.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			diff, err := tc.viewer.DiffHistory("default", "demo", tc.from, tc.to)
+			if len(tc.expectedError) > 0 {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedDiff, diff)
+		})
+	}
+}
+
+func TestGetHistory(t *testing.T) {
+	podLabels := func(hash string) map[string]string {
+		return map[string]string{"app": "demo", appsv1.ControllerRevisionHashLabelKey: hash}
+	}
+
+	testCases := []struct {
+		name              string
+		viewer            HistoryViewer
+		expectedHistories []RevisionHistory
+	}{
+		{
+			name: "CloneSet pods by controller-revision-hash",
+			viewer: &CloneSetHistoryViewer{
+				k: fake.NewSimpleClientset(newHistoryControllerRevision(5, "nginx:1.3"), newHistoryControllerRevision(3, "nginx:1.2"),
+					newHistoryPod("demo-a", podLabels("demo-3"), "CloneSet", "demo", "cloneset-uid"),
+					newHistoryPod("demo-b", podLabels("demo-5"), "CloneSet", "demo", "cloneset-uid"),
+					newHistoryPod("demo-c", podLabels("demo-5"), "CloneSet", "demo", "cloneset-uid"),
+					newHistoryPod("other", podLabels("demo-5"), "CloneSet", "other", "other-uid"),
+				),
+				kc: kruisefake.NewSimpleClientset(newHistoryCloneSet()),
+			},
+			expectedHistories: []RevisionHistory{
+				{Revision: 3, ControllerRevision: "demo-3", CreationTimestamp: metav1.Unix(3, 0), Images: []string{"nginx:1.2"}, Pods: 1,
+					PodNames: []string{"demo-a"}},
+				{Revision: 5, ControllerRevision: "demo-5", CreationTimestamp: metav1.Unix(5, 0), Images: []string{"nginx:1.3"}, Pods: 2,
+					PodNames: []string{"demo-b", "demo-c"}},
+			},
+		},
+		{
+			name: "Deployment pods by ReplicaSet",
+			viewer: &DeploymentHistoryViewer{
+				c: fake.NewSimpleClientset(newHistoryDeployment(), newHistoryReplicaSet(3, "nginx:1.2"), newHistoryReplicaSet(5, "nginx:1.3"),
+					newHistoryPod("demo-5-a", historyLabels, "ReplicaSet", "demo-5", "rs-uid-5"),
+				),
+			},
+			expectedHistories: []RevisionHistory{
+				{Revision: 3, ReplicaSet: "demo-3", CreationTimestamp: metav1.Unix(3, 0), Images: []string{"nginx:1.2"}},
+				{Revision: 5, ReplicaSet: "demo-5", CreationTimestamp: metav1.Unix(5, 0), Images: []string{"nginx:1.3"}, Pods: 1,
+					PodNames: []string{"demo-5-a"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			histories, err := tc.viewer.GetHistory("default", "demo")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedHistories, histories)
+		})
+	}
+}
+
+func TestViewHistory(t *testing.T) {
+	viewer := &CloneSetHistoryViewer{
+		k: fake.NewSimpleClientset(newHistoryControllerRevision(3, "nginx:1.2"), newHistoryControllerRevision(5, "nginx:1.3"),
+			newHistoryPod("demo-b", map[string]string{"app": "demo", appsv1.ControllerRevisionHashLabelKey: "demo-5"}, "CloneSet", "demo", "cloneset-uid"),
+			newHistoryPod("demo-a", map[string]string{"app": "demo", appsv1.ControllerRevisionHashLabelKey: "demo-5"}, "CloneSet", "demo", "cloneset-uid"),
+		),
+		kc: kruisefake.NewSimpleClientset(newHistoryCloneSet()),
+	}
+
+	// the default table keeps the columns of kubectl
+	history, err := viewer.ViewHistory("default", "demo", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, `REVISION  CHANGE-CAUSE
+3         <none>
+5         <none>
+`, history)
+
+	histories, err := viewer.GetHistory("default", "demo")
+	assert.NoError(t, err)
+	history, err = PrintRevisionHistories(histories)
+	assert.NoError(t, err)
+	assert.Equal(t, `REVISION  CHANGE-CAUSE  PODS  POD-NAMES
+3         <none>        0     <none>
+5         <none>        2     demo-a,demo-b
+`, history)
+}
+
+func TestPrintRevisionHistories(t *testing.T) {
+	history, err := PrintRevisionHistories([]RevisionHistory{
+		{Revision: 1, ChangeCause: "scale", Pods: 7, PodNames: []string{"demo-a", "demo-b", "demo-c", "demo-d", "demo-e", "demo-f", "demo-g"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `REVISION  CHANGE-CAUSE  PODS  POD-NAMES
+1         scale         7     demo-a,demo-b,demo-c,demo-d,demo-e and 2 more
+`, history)
+
+	history, err = PrintRevisionHistories(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "No rollout history found.", history)
+}
@@ -0,0 +1,297 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	kruiseclientsets "github.com/openkruise/kruise-api/client/clientset/versioned"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+)
+
+// the ContainerRecreateRequests of an in-place restart have the same defaults as 'create ContainerRecreateRequest'
+const (
+	inPlaceRestartActiveDeadlineSeconds   int64 = 300
+	inPlaceRestartTTLSecondsAfterFinished int32 = 1800
+)
+
+// SidecarSetHashAnnotation is set on the pods by Kruise with the SidecarSets injected into them.
+const SidecarSetHashAnnotation = "kruise.io/sidecarset-hash"
+
+// InPlaceRestarter restarts the containers of the pods of a CloneSet, Advanced StatefulSet or Advanced DaemonSet,
+// or the sidecar containers of the pods a SidecarSet is injected into, in place by ContainerRecreateRequests,
+// batch by batch, without making a new revision of the workload.
+type InPlaceRestarter struct {
+	Client       kubernetes.Interface
+	KruiseClient kruiseclientsets.Interface
+
+	// Containers to restart, all the containers of the pods or all the sidecar containers of a SidecarSet if empty.
+	Containers []string
+	// MaxUnavailable is the number or percentage of the pods restarted in a batch, at least one.
+	MaxUnavailable intstr.IntOrString
+	// Interval of checking whether the ContainerRecreateRequests of a batch are completed.
+	Interval time.Duration
+
+	// ErrOut receives the progress of the restart, so that the output only carries the printed objects.
+	ErrOut io.Writer
+}
+
+// Restart creates the ContainerRecreateRequests of the pods of the workload batch by batch, and waits for those of
+// each batch to be completed before the next one. It stops on the first ContainerRecreateRequest that failed.
+func (r *InPlaceRestarter) Restart(obj runtime.Object) error {
+	var workload metav1.Object
+	var selector *metav1.LabelSelector
+	var template *corev1.PodTemplateSpec
+	switch obj := obj.(type) {
+	case *kruiseappsv1alpha1.CloneSet:
+		workload, selector, template = obj, obj.Spec.Selector, &obj.Spec.Template
+	case *kruiseappsv1beta1.StatefulSet:
+		workload, selector, template = obj, obj.Spec.Selector, &obj.Spec.Template
+	case *kruiseappsv1alpha1.DaemonSet:
+		workload, selector, template = obj, obj.Spec.Selector, &obj.Spec.Template
+	case *kruiseappsv1alpha1.SidecarSet:
+		return r.restartSidecarSet(obj)
+	default:
+		return fmt.Errorf("in-place restart is not supported for %T", obj)
+	}
+	for _, name := range r.Containers {
+		if !slices.ContainsFunc(template.Spec.Containers, func(c corev1.Container) bool { return c.Name == name }) {
+			return fmt.Errorf("container %s not found in the pod template of %s", name, workload.GetName())
+		}
+	}
+
+	allPods, err := listSelectedPods(r.Client.CoreV1(), workload.GetNamespace(), selector)
+	if err != nil {
+		return err
+	}
+	crrs := make([]*kruiseappsv1alpha1.ContainerRecreateRequest, 0, len(allPods))
+	restartedAt := time.Now().Unix()
+	for i := range allPods {
+		if !metav1.IsControlledBy(&allPods[i], workload) || r.skipInactive(&allPods[i]) {
+			continue
+		}
+		crr, err := newContainerRecreateRequest(&allPods[i], r.Containers, restartedAt)
+		if err != nil {
+			return err
+		}
+		crrs = append(crrs, crr)
+	}
+	return r.restartBatches(crrs)
+}
+
+// restartSidecarSet restarts the sidecar containers of the pods the SidecarSet is injected into. A pod injected
+// with an earlier revision of the SidecarSet only has its sidecar containers of that revision restarted.
+func (r *InPlaceRestarter) restartSidecarSet(sidecarSet *kruiseappsv1alpha1.SidecarSet) error {
+	sidecars := make([]string, 0, len(sidecarSet.Spec.Containers))
+	for _, container := range sidecarSet.Spec.Containers {
+		sidecars = append(sidecars, container.Name)
+	}
+	for _, name := range r.Containers {
+		if !slices.Contains(sidecars, name) {
+			return fmt.Errorf("container %s is not a sidecar container of sidecarset %s", name, sidecarSet.Name)
+		}
+	}
+	if len(r.Containers) > 0 {
+		sidecars = r.Containers
+	} else if len(sidecars) == 0 {
+		return fmt.Errorf("sidecarset %s has no sidecar container to restart", sidecarSet.Name)
+	}
+
+	// the pods of all namespaces if the SidecarSet is not limited to one
+	allPods, err := listSelectedPods(r.Client.CoreV1(), sidecarSet.Spec.Namespace, sidecarSet.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	var crrs []*kruiseappsv1alpha1.ContainerRecreateRequest
+	restartedAt := time.Now().Unix()
+	for i := range allPods {
+		pod := &allPods[i]
+		if !isInjectedBySidecarSet(pod, sidecarSet.Name) || r.skipInactive(pod) {
+			continue
+		}
+		var containers []string
+		for _, container := range pod.Spec.Containers {
+			if slices.Contains(sidecars, container.Name) {
+				containers = append(containers, container.Name)
+			}
+		}
+		if len(containers) == 0 {
+			continue
+		}
+		crr, err := newContainerRecreateRequest(pod, containers, restartedAt)
+		if err != nil {
+			return err
+		}
+		crrs = append(crrs, crr)
+	}
+	return r.restartBatches(crrs)
+}
+
+// skipInactive returns whether the containers of the pod can not be recreated, since the ContainerRecreateRequests
+// of pods that are terminating, not scheduled or not running are refused, and reports the skipped pod.
+func (r *InPlaceRestarter) skipInactive(pod *corev1.Pod) bool {
+	var reason string
+	switch {
+	case pod.DeletionTimestamp != nil:
+		reason = "terminating"
+	case len(pod.Spec.NodeName) == 0:
+		reason = "not scheduled"
+	case pod.Status.Phase != corev1.PodRunning:
+		reason = fmt.Sprintf("%s, not Running", pod.Status.Phase)
+	default:
+		return false
+	}
+	fmt.Fprintf(r.ErrOut, "skipped pod %s: %s\n", pod.Name, reason)
+	return true
+}
+
+// isInjectedBySidecarSet returns whether the SidecarSetHashAnnotation of the pod records the SidecarSet.
+func isInjectedBySidecarSet(pod *corev1.Pod, name string) bool {
+	value, ok := pod.Annotations[SidecarSetHashAnnotation]
+	if !ok {
+		return false
+	}
+	hashes := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(value), &hashes); err != nil {
+		return false
+	}
+	_, ok = hashes[name]
+	return ok
+}
+
+// restartBatches restarts the ContainerRecreateRequests in batches of MaxUnavailable.
+func (r *InPlaceRestarter) restartBatches(crrs []*kruiseappsv1alpha1.ContainerRecreateRequest) error {
+	batchSize, err := intstr.GetScaledValueFromIntOrPercent(&r.MaxUnavailable, len(crrs), false)
+	if err != nil {
+		return fmt.Errorf("failed to get max unavailable: %v", err)
+	}
+	batchSize = max(batchSize, 1)
+	for batch := range slices.Chunk(crrs, batchSize) {
+		if err := r.restartBatch(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restartBatch creates the ContainerRecreateRequests and waits for them to be completed.
+func (r *InPlaceRestarter) restartBatch(batch []*kruiseappsv1alpha1.ContainerRecreateRequest) error {
+	for _, crr := range batch {
+		crrClient := r.KruiseClient.AppsV1alpha1().ContainerRecreateRequests(crr.Namespace)
+		if _, err := crrClient.Create(context.TODO(), crr, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create ContainerRecreateRequest %s: %v", crr.Name, err)
+		}
+	}
+
+	// a ContainerRecreateRequest is completed once its active deadline exceeded, so waiting a little
+	// longer than that only times out if it is not handled at all
+	timeout := time.Duration(inPlaceRestartActiveDeadlineSeconds)*time.Second + time.Minute
+	for _, crr := range batch {
+		crrClient := r.KruiseClient.AppsV1alpha1().ContainerRecreateRequests(crr.Namespace)
+		err := wait.PollUntilContextTimeout(context.TODO(), r.Interval, timeout, true, func(ctx context.Context) (bool, error) {
+			latest, err := crrClient.Get(ctx, crr.Name, metav1.GetOptions{})
+			if err != nil {
+				return false, fmt.Errorf("failed to get ContainerRecreateRequest %s: %v", crr.Name, err)
+			}
+			if latest.Status.Phase != kruiseappsv1alpha1.ContainerRecreateRequestCompleted {
+				return false, nil
+			}
+			if failure := containerRecreateFailure(latest); len(failure) > 0 {
+				return false, fmt.Errorf("failed to restart containers of pod %s: %s", crr.Spec.PodName, failure)
+			}
+			return true, nil
+		})
+		if wait.Interrupted(err) {
+			return fmt.Errorf("timed out waiting for ContainerRecreateRequest %s to be completed", crr.Name)
+		} else if err != nil {
+			return err
+		}
+		fmt.Fprintf(r.ErrOut, "restarted containers of pod %s\n", crr.Spec.PodName)
+	}
+	return nil
+}
+
+// newContainerRecreateRequest returns the ContainerRecreateRequest restarting the named containers of the pod,
+// all of them if names is empty.
+func newContainerRecreateRequest(pod *corev1.Pod, names []string, restartedAt int64) (*kruiseappsv1alpha1.ContainerRecreateRequest, error) {
+	var containers []kruiseappsv1alpha1.ContainerRecreateRequestContainer
+	for _, container := range pod.Spec.Containers {
+		if len(names) > 0 && !slices.Contains(names, container.Name) {
+			continue
+		}
+		crrContainer := kruiseappsv1alpha1.ContainerRecreateRequestContainer{Name: container.Name, Ports: container.Ports}
+		if container.Lifecycle != nil && container.Lifecycle.PreStop != nil {
+			crrContainer.PreStop = &kruiseappsv1alpha1.ProbeHandler{
+				Exec:      container.Lifecycle.PreStop.Exec,
+				HTTPGet:   container.Lifecycle.PreStop.HTTPGet,
+				TCPSocket: container.Lifecycle.PreStop.TCPSocket,
+			}
+		}
+		containers = append(containers, crrContainer)
+	}
+	for _, name := range names {
+		if !slices.ContainsFunc(containers, func(c kruiseappsv1alpha1.ContainerRecreateRequestContainer) bool { return c.Name == name }) {
+			return nil, fmt.Errorf("container %s not found in pod %s", name, pod.Name)
+		}
+	}
+
+	return &kruiseappsv1alpha1.ContainerRecreateRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: pod.Namespace,
+			Name:      fmt.Sprintf("%s-restart-%d", pod.Name, restartedAt),
+		},
+		Spec: kruiseappsv1alpha1.ContainerRecreateRequestSpec{
+			PodName:    pod.Name,
+			Containers: containers,
+			Strategy: &kruiseappsv1alpha1.ContainerRecreateRequestStrategy{
+				FailurePolicy:             kruiseappsv1alpha1.ContainerRecreateRequestFailurePolicyFail,
+				UnreadyGracePeriodSeconds: ptr.To[int64](3),
+				MinStartedSeconds:         3,
+			},
+			ActiveDeadlineSeconds:   ptr.To(inPlaceRestartActiveDeadlineSeconds),
+			TTLSecondsAfterFinished: ptr.To(inPlaceRestartTTLSecondsAfterFinished),
+		},
+	}, nil
+}
+
+// containerRecreateFailure returns why the completed ContainerRecreateRequest failed, empty if it succeeded.
+func containerRecreateFailure(crr *kruiseappsv1alpha1.ContainerRecreateRequest) string {
+	for _, state := range crr.Status.ContainerRecreateStates {
+		if state.Phase != kruiseappsv1alpha1.ContainerRecreateRequestSucceeded {
+			failure := fmt.Sprintf("container %s is %s", state.Name, state.Phase)
+			if len(state.Message) > 0 {
+				failure += ": " + state.Message
+			}
+			return failure
+		}
+	}
+	return crr.Status.Message
+}
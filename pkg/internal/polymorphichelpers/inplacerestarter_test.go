@@ -0,0 +1,240 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruisefake "github.com/openkruise/kruise-api/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestRestartInPlace(t *testing.T) {
+	newPod := func(name, owner string, uid types.UID) *corev1.Pod {
+		pod := newHistoryPod(name, historyLabels, "CloneSet", owner, uid)
+		pod.Spec.Containers = []corev1.Container{{Name: "main"}, {Name: "sidecar"}}
+		pod.Spec.NodeName = "node"
+		pod.Status.Phase = corev1.PodRunning
+		return pod
+	}
+	terminating := newPod("demo-terminating", "demo", "cloneset-uid")
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	terminating.Finalizers = []string{"test"}
+	unscheduled := newPod("demo-unscheduled", "demo", "cloneset-uid")
+	unscheduled.Spec.NodeName = ""
+	unscheduled.Status.Phase = corev1.PodPending
+	pending := newPod("demo-pending", "demo", "cloneset-uid")
+	pending.Status.Phase = corev1.PodPending
+	cloneSet := newHistoryCloneSet()
+	cloneSet.Spec.Template.Spec.Containers = []corev1.Container{{Name: "main"}, {Name: "sidecar"}}
+
+	testCases := []struct {
+		name           string
+		containers     []string
+		maxUnavailable intstr.IntOrString
+		// failedPod has a failed ContainerRecreateRequest
+		failedPod          string
+		expectedEvents     []string
+		expectedContainers []string
+		expectedError      string
+	}{
+		{
+			name:           "all containers in batches",
+			maxUnavailable: intstr.FromInt32(2),
+			expectedEvents: []string{"create demo-a", "create demo-b", "completed demo-a", "completed demo-b",
+				"create demo-c", "completed demo-c"},
+			expectedContainers: []string{"main", "sidecar"},
+		},
+		{
+			name:           "selected containers one by one",
+			containers:     []string{"sidecar"},
+			maxUnavailable: intstr.FromString("10%"),
+			expectedEvents: []string{"create demo-a", "completed demo-a", "create demo-b", "completed demo-b",
+				"create demo-c", "completed demo-c"},
+			expectedContainers: []string{"sidecar"},
+		},
+		{
+			name:               "stop on the first failure",
+			maxUnavailable:     intstr.FromInt32(2),
+			failedPod:          "demo-a",
+			expectedEvents:     []string{"create demo-a", "create demo-b", "failed demo-a"},
+			expectedContainers: []string{"main", "sidecar"},
+			expectedError:      "failed to restart containers of pod demo-a: container main is Failed: boom",
+		},
+		{
+			name:          "unknown container",
+			containers:    []string{"unknown"},
+			expectedError: "container unknown not found in the pod template of demo",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var events []string
+			kc := kruisefake.NewSimpleClientset()
+			kc.PrependReactor("create", "containerrecreaterequests", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				crr := action.(clienttesting.CreateAction).GetObject().(*kruiseappsv1alpha1.ContainerRecreateRequest)
+				events = append(events, "create "+crr.Spec.PodName)
+				var containers []string
+				for _, container := range crr.Spec.Containers {
+					containers = append(containers, container.Name)
+				}
+				assert.Equal(t, tc.expectedContainers, containers)
+				return false, nil, nil
+			})
+			kc.PrependReactor("get", "containerrecreaterequests", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				name := action.(clienttesting.GetAction).GetName()
+				podName := name[:strings.Index(name, "-restart-")]
+				crr := &kruiseappsv1alpha1.ContainerRecreateRequest{ObjectMeta: metav1.ObjectMeta{Name: name}}
+				crr.Status.Phase = kruiseappsv1alpha1.ContainerRecreateRequestCompleted
+				if podName == tc.failedPod {
+					crr.Status.ContainerRecreateStates = []kruiseappsv1alpha1.ContainerRecreateRequestContainerRecreateState{
+						{Name: "main", Phase: kruiseappsv1alpha1.ContainerRecreateRequestFailed, Message: "boom"},
+					}
+					events = append(events, "failed "+podName)
+				} else {
+					events = append(events, "completed "+podName)
+				}
+				return true, crr, nil
+			})
+
+			errOut := &bytes.Buffer{}
+			restarter := &InPlaceRestarter{
+				Client: fake.NewSimpleClientset(newPod("demo-c", "demo", "cloneset-uid"), newPod("demo-a", "demo", "cloneset-uid"),
+					newPod("demo-b", "demo", "cloneset-uid"), newPod("other", "other", "other-uid"), terminating, unscheduled, pending),
+				KruiseClient:   kc,
+				Containers:     tc.containers,
+				MaxUnavailable: tc.maxUnavailable,
+				Interval:       time.Millisecond,
+				ErrOut:         errOut,
+			}
+			err := restarter.Restart(cloneSet)
+			if len(tc.expectedError) > 0 {
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "skipped pod demo-pending: Pending, not Running\nskipped pod demo-terminating: terminating\n"+
+					"skipped pod demo-unscheduled: not scheduled\n"+
+					"restarted containers of pod demo-a\nrestarted containers of pod demo-b\nrestarted containers of pod demo-c\n",
+					errOut.String())
+			}
+			assert.Equal(t, tc.expectedEvents, events)
+		})
+	}
+}
+
+func TestRestartSidecarSetInPlace(t *testing.T) {
+	newPod := func(namespace, name, injected string, containers ...string) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: historyLabels},
+			Spec: corev1.PodSpec{NodeName: "node"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}}
+		if len(injected) > 0 {
+			pod.Annotations = map[string]string{SidecarSetHashAnnotation: `{"` + injected + `":{"hash":"abc"}}`}
+		}
+		for _, container := range containers {
+			pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: container})
+		}
+		return pod
+	}
+	sidecarSet := &kruiseappsv1alpha1.SidecarSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo"},
+		Spec: kruiseappsv1alpha1.SidecarSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: historyLabels},
+			Containers: []kruiseappsv1alpha1.SidecarContainer{
+				{Container: corev1.Container{Name: "proxy"}},
+				{Container: corev1.Container{Name: "log"}},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		containers    []string
+		expectedCRRs  []string
+		expectedError string
+	}{
+		{
+			name: "all sidecar containers",
+			// demo-b was injected before the log container was added to the SidecarSet
+			expectedCRRs: []string{"default/demo-a:proxy,log", "default/demo-b:proxy", "other/demo-c:proxy,log"},
+		},
+		{
+			name:         "selected sidecar container",
+			containers:   []string{"log"},
+			expectedCRRs: []string{"default/demo-a:log", "other/demo-c:log"},
+		},
+		{
+			name:          "not a sidecar container",
+			containers:    []string{"main"},
+			expectedError: "container main is not a sidecar container of sidecarset demo",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var crrs []string
+			kc := kruisefake.NewSimpleClientset()
+			kc.PrependReactor("create", "containerrecreaterequests", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				crr := action.(clienttesting.CreateAction).GetObject().(*kruiseappsv1alpha1.ContainerRecreateRequest)
+				var containers []string
+				for _, container := range crr.Spec.Containers {
+					containers = append(containers, container.Name)
+				}
+				crrs = append(crrs, crr.Namespace+"/"+crr.Spec.PodName+":"+strings.Join(containers, ","))
+				return false, nil, nil
+			})
+			kc.PrependReactor("get", "containerrecreaterequests", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				crr := &kruiseappsv1alpha1.ContainerRecreateRequest{ObjectMeta: metav1.ObjectMeta{Name: action.(clienttesting.GetAction).GetName()}}
+				crr.Status.Phase = kruiseappsv1alpha1.ContainerRecreateRequestCompleted
+				return true, crr, nil
+			})
+
+			restarter := &InPlaceRestarter{
+				Client: fake.NewSimpleClientset(
+					newPod("default", "demo-a", "demo", "main", "proxy", "log"),
+					newPod("default", "demo-b", "demo", "main", "proxy"),
+					newPod("other", "demo-c", "demo", "main", "proxy", "log"),
+					newPod("default", "not-injected", "", "main"),
+					newPod("default", "injected-by-other", "other", "main", "proxy", "log"),
+				),
+				KruiseClient:   kc,
+				Containers:     tc.containers,
+				MaxUnavailable: intstr.FromInt32(1),
+				Interval:       time.Millisecond,
+				ErrOut:         &bytes.Buffer{},
+			}
+			err := restarter.Restart(sidecarSet)
+			if len(tc.expectedError) > 0 {
+				assert.EqualError(t, err, tc.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedCRRs, crrs)
+		})
+	}
+}
@@ -117,6 +117,13 @@ var ObjectResumerFn ObjectResumerFunc = defaultObjectResumer
 // in case the object is already approved.
 var ObjectApproverFn ObjectApproverFunc = defaultObjectApprover
 
+// RolloutStepApproverFunc is a function type that returns the approver of a rollout to jump to the target step.
+type RolloutStepApproverFunc func(targetStep int32) ObjectApproverFunc
+
+// RolloutStepApproverFn gives a way to easily override the function for unit testing if needed.
+// The approver returns an error if the target step is not after the current step of the rollout.
+var RolloutStepApproverFn RolloutStepApproverFunc = defaultRolloutStepApprover
+
 // RollbackerFunc gives a way to change the rollback version of the specified RESTMapping type
 type RollbackerFunc func(restClientGetter genericclioptions.RESTClientGetter, mapping *meta.RESTMapping) (Rollbacker, error)
 
@@ -22,6 +22,7 @@ import (
 	"fmt"
 
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 	rolloutsapi "github.com/openkruise/kruise-rollout-api/rollouts/v1beta1"
 	appsv1 "k8s.io/api/apps/v1"
 	appsv1beta1 "k8s.io/api/apps/v1beta1"
@@ -29,9 +30,10 @@ import (
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/utils/ptr"
 )
 
-// Currently supports Deployments, CloneSet and Kruise Rollout.
+// Currently supports Deployments, CloneSet, Advanced StatefulSet, Advanced DaemonSet, UnitedDeployment and Kruise Rollout.
 func defaultObjectPauser(obj runtime.Object) ([]byte, error) {
 	switch obj := obj.(type) {
 	case *extensionsv1beta1.Deployment:
@@ -69,6 +71,37 @@ func defaultObjectPauser(obj runtime.Object) ([]byte, error) {
 		obj.Spec.UpdateStrategy.Paused = true
 		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
 
+	case *kruiseappsv1beta1.StatefulSet:
+		if obj.Spec.UpdateStrategy.RollingUpdate != nil && obj.Spec.UpdateStrategy.RollingUpdate.Paused {
+			return nil, errors.New("is already paused")
+		}
+		if obj.Spec.UpdateStrategy.RollingUpdate == nil {
+			obj.Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1beta1.RollingUpdateStatefulSetStrategy{}
+		}
+		obj.Spec.UpdateStrategy.RollingUpdate.Paused = true
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1beta1.SchemeGroupVersion), obj)
+
+	case *kruiseappsv1alpha1.DaemonSet:
+		if obj.Spec.UpdateStrategy.RollingUpdate != nil && ptr.Deref(obj.Spec.UpdateStrategy.RollingUpdate.Paused, false) {
+			return nil, errors.New("is already paused")
+		}
+		if obj.Spec.UpdateStrategy.RollingUpdate == nil {
+			obj.Spec.UpdateStrategy.RollingUpdate = &kruiseappsv1alpha1.RollingUpdateDaemonSet{}
+		}
+		obj.Spec.UpdateStrategy.RollingUpdate.Paused = ptr.To(true)
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+
+	case *kruiseappsv1alpha1.UnitedDeployment:
+		paused, err := unitedDeploymentPaused(obj)
+		if err != nil {
+			return nil, err
+		}
+		if paused {
+			return nil, errors.New("is already paused")
+		}
+		setUnitedDeploymentPaused(obj, true)
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+
 	case *rolloutsapi.Rollout:
 		if obj.Spec.Strategy.Paused {
 			return nil, errors.New("is already paused")
@@ -80,3 +113,38 @@ func defaultObjectPauser(obj runtime.Object) ([]byte, error) {
 		return nil, fmt.Errorf("pausing is not supported")
 	}
 }
+
+// unitedDeploymentPaused returns whether the subset template of the UnitedDeployment is paused, which pauses all its
+// subsets. Subsets of StatefulSets cannot be paused.
+func unitedDeploymentPaused(obj *kruiseappsv1alpha1.UnitedDeployment) (bool, error) {
+	template := obj.Spec.Template
+	switch {
+	case template.AdvancedStatefulSetTemplate != nil:
+		rollingUpdate := template.AdvancedStatefulSetTemplate.Spec.UpdateStrategy.RollingUpdate
+		return rollingUpdate != nil && rollingUpdate.Paused, nil
+	case template.CloneSetTemplate != nil:
+		return template.CloneSetTemplate.Spec.UpdateStrategy.Paused, nil
+	case template.DeploymentTemplate != nil:
+		return template.DeploymentTemplate.Spec.Paused, nil
+	default:
+		return false, fmt.Errorf("pausing is not supported for UnitedDeployment of StatefulSets")
+	}
+}
+
+// setUnitedDeploymentPaused pauses or resumes the subset template of the UnitedDeployment,
+// which must be supported by unitedDeploymentPaused.
+func setUnitedDeploymentPaused(obj *kruiseappsv1alpha1.UnitedDeployment, paused bool) {
+	template := obj.Spec.Template
+	switch {
+	case template.AdvancedStatefulSetTemplate != nil:
+		strategy := &template.AdvancedStatefulSetTemplate.Spec.UpdateStrategy
+		if strategy.RollingUpdate == nil {
+			strategy.RollingUpdate = &kruiseappsv1beta1.RollingUpdateStatefulSetStrategy{}
+		}
+		strategy.RollingUpdate.Paused = paused
+	case template.CloneSetTemplate != nil:
+		template.CloneSetTemplate.Spec.UpdateStrategy.Paused = paused
+	case template.DeploymentTemplate != nil:
+		template.DeploymentTemplate.Spec.Paused = paused
+	}
+}
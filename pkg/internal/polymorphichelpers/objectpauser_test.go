@@ -0,0 +1,116 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	"github.com/stretchr/testify/assert"
+
+	// registers the kruise types in the scheme of kubectl
+	_ "github.com/openkruise/kruise-tools/pkg/api"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+)
+
+func TestPauseAndResumeKruiseWorkloads(t *testing.T) {
+	testCases := []struct {
+		name     string
+		obj      runtime.Object
+		isPaused func(obj runtime.Object) bool
+		// expectedError of pausing and resuming, if they are not supported
+		expectedError string
+	}{
+		{
+			name: "Advanced StatefulSet",
+			obj:  &kruiseappsv1beta1.StatefulSet{},
+			isPaused: func(obj runtime.Object) bool {
+				rollingUpdate := obj.(*kruiseappsv1beta1.StatefulSet).Spec.UpdateStrategy.RollingUpdate
+				return rollingUpdate != nil && rollingUpdate.Paused
+			},
+		},
+		{
+			name: "Advanced DaemonSet",
+			obj:  &kruiseappsv1alpha1.DaemonSet{},
+			isPaused: func(obj runtime.Object) bool {
+				rollingUpdate := obj.(*kruiseappsv1alpha1.DaemonSet).Spec.UpdateStrategy.RollingUpdate
+				return rollingUpdate != nil && ptr.Deref(rollingUpdate.Paused, false)
+			},
+		},
+		{
+			name: "UnitedDeployment of Advanced StatefulSets",
+			obj: &kruiseappsv1alpha1.UnitedDeployment{Spec: kruiseappsv1alpha1.UnitedDeploymentSpec{Template: kruiseappsv1alpha1.SubsetTemplate{
+				AdvancedStatefulSetTemplate: &kruiseappsv1alpha1.AdvancedStatefulSetTemplateSpec{},
+			}}},
+			isPaused: func(obj runtime.Object) bool {
+				rollingUpdate := obj.(*kruiseappsv1alpha1.UnitedDeployment).Spec.Template.AdvancedStatefulSetTemplate.Spec.UpdateStrategy.RollingUpdate
+				return rollingUpdate != nil && rollingUpdate.Paused
+			},
+		},
+		{
+			name: "UnitedDeployment of CloneSets",
+			obj: &kruiseappsv1alpha1.UnitedDeployment{Spec: kruiseappsv1alpha1.UnitedDeploymentSpec{Template: kruiseappsv1alpha1.SubsetTemplate{
+				CloneSetTemplate: &kruiseappsv1alpha1.CloneSetTemplateSpec{},
+			}}},
+			isPaused: func(obj runtime.Object) bool {
+				return obj.(*kruiseappsv1alpha1.UnitedDeployment).Spec.Template.CloneSetTemplate.Spec.UpdateStrategy.Paused
+			},
+		},
+		{
+			name: "UnitedDeployment of StatefulSets",
+			obj: &kruiseappsv1alpha1.UnitedDeployment{Spec: kruiseappsv1alpha1.UnitedDeploymentSpec{Template: kruiseappsv1alpha1.SubsetTemplate{
+				StatefulSetTemplate: &kruiseappsv1alpha1.StatefulSetTemplateSpec{},
+			}}},
+			expectedError: "is not supported for UnitedDeployment of StatefulSets",
+		},
+	}
+
+	decode := func(t *testing.T, data []byte, into runtime.Object) runtime.Object {
+		obj := reflect.New(reflect.TypeOf(into).Elem()).Interface().(runtime.Object)
+		assert.NoError(t, json.Unmarshal(data, obj))
+		return obj
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := defaultObjectResumer(tc.obj.DeepCopyObject())
+			if len(tc.expectedError) > 0 {
+				assert.ErrorContains(t, err, tc.expectedError)
+				_, err = defaultObjectPauser(tc.obj.DeepCopyObject())
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			assert.EqualError(t, err, "is not paused")
+
+			data, err := defaultObjectPauser(tc.obj.DeepCopyObject())
+			assert.NoError(t, err)
+			paused := decode(t, data, tc.obj)
+			assert.True(t, tc.isPaused(paused))
+			_, err = defaultObjectPauser(paused.DeepCopyObject())
+			assert.EqualError(t, err, "is already paused")
+
+			data, err = defaultObjectResumer(paused)
+			assert.NoError(t, err)
+			assert.False(t, tc.isPaused(decode(t, data, tc.obj)))
+		})
+	}
+}
@@ -23,14 +23,20 @@ import (
 	"time"
 
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 	appsv1 "k8s.io/api/apps/v1"
 	appsv1beta1 "k8s.io/api/apps/v1beta1"
 	appsv1beta2 "k8s.io/api/apps/v1beta2"
+	corev1 "k8s.io/api/core/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/utils/ptr"
 )
 
+// RestartedAtAnnotation is set on the pod template of a Kruise workload to restart its pods.
+const RestartedAtAnnotation = "kubectl.kruise.io/restartedAt"
+
 func defaultObjectRestarter(obj runtime.Object) ([]byte, error) {
 	switch obj := obj.(type) {
 	case *extensionsv1beta1.Deployment:
@@ -120,10 +126,57 @@ func defaultObjectRestarter(obj runtime.Object) ([]byte, error) {
 			obj.Spec.Template.ObjectMeta.Annotations = make(map[string]string)
 		}
 
-		obj.Spec.Template.ObjectMeta.Annotations["kubectl.kruise.io/restartedAt"] = time.Now().Format(time.RFC3339)
+		obj.Spec.Template.ObjectMeta.Annotations[RestartedAtAnnotation] = time.Now().Format(time.RFC3339)
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+
+	case *kruiseappsv1beta1.StatefulSet:
+		if obj.Spec.UpdateStrategy.RollingUpdate != nil && obj.Spec.UpdateStrategy.RollingUpdate.Paused {
+			return nil, errors.New("can't restart paused advanced statefulset (run rollout resume first)")
+		}
+		setRestartedAt(&obj.Spec.Template)
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1beta1.SchemeGroupVersion), obj)
+
+	case *kruiseappsv1alpha1.DaemonSet:
+		if obj.Spec.UpdateStrategy.RollingUpdate != nil && ptr.Deref(obj.Spec.UpdateStrategy.RollingUpdate.Paused, false) {
+			return nil, errors.New("can't restart paused advanced daemonset (run rollout resume first)")
+		}
+		setRestartedAt(&obj.Spec.Template)
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+
+	case *kruiseappsv1alpha1.SidecarSet:
+		// the annotations of a SidecarSet are not injected into the pods, so its sidecar containers get a new env instead
+		restartEnv := []corev1.EnvVar{{Name: RestartedEnv, Value: time.Now().Format(time.RFC3339)}}
+		for i := range obj.Spec.Containers {
+			container := &obj.Spec.Containers[i]
+			container.Env = updateEnv(container.Env, restartEnv, []string{})
+		}
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+
+	case *kruiseappsv1alpha1.UnitedDeployment:
+		template := obj.Spec.Template
+		switch {
+		case template.AdvancedStatefulSetTemplate != nil:
+			setRestartedAt(&template.AdvancedStatefulSetTemplate.Spec.Template)
+		case template.StatefulSetTemplate != nil:
+			setRestartedAt(&template.StatefulSetTemplate.Spec.Template)
+		case template.CloneSetTemplate != nil:
+			setRestartedAt(&template.CloneSetTemplate.Spec.Template)
+		case template.DeploymentTemplate != nil:
+			setRestartedAt(&template.DeploymentTemplate.Spec.Template)
+		default:
+			return nil, errors.New("has no subset template to restart")
+		}
 		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
 
 	default:
 		return nil, fmt.Errorf("restarting is not supported")
 	}
 }
+
+// setRestartedAt sets the RestartedAtAnnotation of the pod template to now.
+func setRestartedAt(template *corev1.PodTemplateSpec) {
+	if template.ObjectMeta.Annotations == nil {
+		template.ObjectMeta.Annotations = make(map[string]string)
+	}
+	template.ObjectMeta.Annotations[RestartedAtAnnotation] = time.Now().Format(time.RFC3339)
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	"github.com/stretchr/testify/assert"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+)
+
+func TestRestartKruiseWorkloads(t *testing.T) {
+	restartedAt := func(template corev1.PodTemplateSpec) string {
+		return template.Annotations[RestartedAtAnnotation]
+	}
+
+	testCases := []struct {
+		name          string
+		obj           runtime.Object
+		getRestarted  func(obj runtime.Object) []string
+		expectedError string
+	}{
+		{
+			name: "CloneSet",
+			obj:  &kruiseappsv1alpha1.CloneSet{},
+			getRestarted: func(obj runtime.Object) []string {
+				return []string{restartedAt(obj.(*kruiseappsv1alpha1.CloneSet).Spec.Template)}
+			},
+		},
+		{
+			name: "Advanced StatefulSet",
+			obj:  &kruiseappsv1beta1.StatefulSet{},
+			getRestarted: func(obj runtime.Object) []string {
+				return []string{restartedAt(obj.(*kruiseappsv1beta1.StatefulSet).Spec.Template)}
+			},
+		},
+		{
+			name: "paused Advanced StatefulSet",
+			obj: &kruiseappsv1beta1.StatefulSet{Spec: kruiseappsv1beta1.StatefulSetSpec{UpdateStrategy: kruiseappsv1beta1.StatefulSetUpdateStrategy{
+				RollingUpdate: &kruiseappsv1beta1.RollingUpdateStatefulSetStrategy{Paused: true},
+			}}},
+			expectedError: "can't restart paused advanced statefulset (run rollout resume first)",
+		},
+		{
+			name: "Advanced DaemonSet",
+			obj:  &kruiseappsv1alpha1.DaemonSet{},
+			getRestarted: func(obj runtime.Object) []string {
+				return []string{restartedAt(obj.(*kruiseappsv1alpha1.DaemonSet).Spec.Template)}
+			},
+		},
+		{
+			name: "paused Advanced DaemonSet",
+			obj: &kruiseappsv1alpha1.DaemonSet{Spec: kruiseappsv1alpha1.DaemonSetSpec{UpdateStrategy: kruiseappsv1alpha1.DaemonSetUpdateStrategy{
+				RollingUpdate: &kruiseappsv1alpha1.RollingUpdateDaemonSet{Paused: ptr.To(true)},
+			}}},
+			expectedError: "can't restart paused advanced daemonset (run rollout resume first)",
+		},
+		{
+			name: "SidecarSet",
+			obj: &kruiseappsv1alpha1.SidecarSet{Spec: kruiseappsv1alpha1.SidecarSetSpec{Containers: []kruiseappsv1alpha1.SidecarContainer{
+				{Container: corev1.Container{Name: "sidecar"}},
+				{Container: corev1.Container{Name: "proxy", Env: []corev1.EnvVar{{Name: RestartedEnv, Value: "before"}}}},
+			}}},
+			getRestarted: func(obj runtime.Object) []string {
+				var values []string
+				for _, container := range obj.(*kruiseappsv1alpha1.SidecarSet).Spec.Containers {
+					assert.Len(t, container.Env, 1)
+					for _, env := range container.Env {
+						if env.Name == RestartedEnv && env.Value != "before" {
+							values = append(values, env.Value)
+						}
+					}
+				}
+				assert.Len(t, values, 2)
+				return values
+			},
+		},
+		{
+			name: "UnitedDeployment of CloneSets",
+			obj: &kruiseappsv1alpha1.UnitedDeployment{Spec: kruiseappsv1alpha1.UnitedDeploymentSpec{Template: kruiseappsv1alpha1.SubsetTemplate{
+				CloneSetTemplate: &kruiseappsv1alpha1.CloneSetTemplateSpec{},
+			}}},
+			getRestarted: func(obj runtime.Object) []string {
+				return []string{restartedAt(obj.(*kruiseappsv1alpha1.UnitedDeployment).Spec.Template.CloneSetTemplate.Spec.Template)}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := defaultObjectRestarter(tc.obj.DeepCopyObject())
+			if len(tc.expectedError) > 0 {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+
+			restarted := reflect.New(reflect.TypeOf(tc.obj).Elem()).Interface().(runtime.Object)
+			assert.NoError(t, json.Unmarshal(data, restarted))
+			for _, value := range tc.getRestarted(restarted) {
+				assert.NotEmpty(t, value)
+			}
+		})
+	}
+}
+
+func TestUpdateResourceEnv(t *testing.T) {
+	restartedAt := func(containers []corev1.Container) []string {
+		var values []string
+		for _, container := range containers {
+			for _, env := range container.Env {
+				if env.Name == RestartedEnv {
+					values = append(values, env.Value)
+				}
+			}
+		}
+		return values
+	}
+	containers := []corev1.Container{{Name: "main", Env: []corev1.EnvVar{{Name: RestartedEnv, Value: "before"}}}, {Name: "sidecar"}}
+
+	testCases := []struct {
+		name          string
+		obj           runtime.Object
+		getRestarted  func(obj runtime.Object) []string
+		expectedError string
+	}{
+		{
+			name: "Advanced StatefulSet",
+			obj: &kruiseappsv1beta1.StatefulSet{Spec: kruiseappsv1beta1.StatefulSetSpec{Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: containers},
+			}}},
+			getRestarted: func(obj runtime.Object) []string {
+				return restartedAt(obj.(*kruiseappsv1beta1.StatefulSet).Spec.Template.Spec.Containers)
+			},
+		},
+		{
+			name: "paused Advanced StatefulSet",
+			obj: &kruiseappsv1beta1.StatefulSet{Spec: kruiseappsv1beta1.StatefulSetSpec{UpdateStrategy: kruiseappsv1beta1.StatefulSetUpdateStrategy{
+				RollingUpdate: &kruiseappsv1beta1.RollingUpdateStatefulSetStrategy{Paused: true},
+			}}},
+			expectedError: "can't restart paused advanced statefulset (run rollout resume first)",
+		},
+		{
+			name: "Advanced DaemonSet",
+			obj: &kruiseappsv1alpha1.DaemonSet{Spec: kruiseappsv1alpha1.DaemonSetSpec{Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: containers},
+			}}},
+			getRestarted: func(obj runtime.Object) []string {
+				return restartedAt(obj.(*kruiseappsv1alpha1.DaemonSet).Spec.Template.Spec.Containers)
+			},
+		},
+		{
+			name: "paused Advanced DaemonSet",
+			obj: &kruiseappsv1alpha1.DaemonSet{Spec: kruiseappsv1alpha1.DaemonSetSpec{UpdateStrategy: kruiseappsv1alpha1.DaemonSetUpdateStrategy{
+				RollingUpdate: &kruiseappsv1alpha1.RollingUpdateDaemonSet{Paused: ptr.To(true)},
+			}}},
+			expectedError: "can't restart paused advanced daemonset (run rollout resume first)",
+		},
+		{
+			name:          "SidecarSet",
+			obj:           &kruiseappsv1alpha1.SidecarSet{},
+			expectedError: "restarting by env is not supported for *v1alpha1.SidecarSet",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := tc.obj.DeepCopyObject()
+			err := UpdateResourceEnv(obj)
+			if len(tc.expectedError) > 0 {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			values := tc.getRestarted(obj)
+			assert.Len(t, values, len(containers))
+			for _, value := range values {
+				assert.NotEqual(t, "before", value)
+			}
+		})
+	}
+}
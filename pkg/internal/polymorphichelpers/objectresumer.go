@@ -22,6 +22,7 @@ import (
 	"fmt"
 
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
 	rolloutsapi "github.com/openkruise/kruise-rollout-api/rollouts/v1beta1"
 	appsv1 "k8s.io/api/apps/v1"
 	appsv1beta1 "k8s.io/api/apps/v1beta1"
@@ -29,9 +30,10 @@ import (
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/kubectl/pkg/scheme"
+	"k8s.io/utils/ptr"
 )
 
-// Currently supports Deployments, CloneSet and Kruise Rollout.
+// Currently supports Deployments, CloneSet, Advanced StatefulSet, Advanced DaemonSet, UnitedDeployment and Kruise Rollout.
 func defaultObjectResumer(obj runtime.Object) ([]byte, error) {
 	switch obj := obj.(type) {
 	case *extensionsv1beta1.Deployment:
@@ -69,6 +71,31 @@ func defaultObjectResumer(obj runtime.Object) ([]byte, error) {
 		obj.Spec.UpdateStrategy.Paused = false
 		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
 
+	case *kruiseappsv1beta1.StatefulSet:
+		if obj.Spec.UpdateStrategy.RollingUpdate == nil || !obj.Spec.UpdateStrategy.RollingUpdate.Paused {
+			return nil, errors.New("is not paused")
+		}
+		obj.Spec.UpdateStrategy.RollingUpdate.Paused = false
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1beta1.SchemeGroupVersion), obj)
+
+	case *kruiseappsv1alpha1.DaemonSet:
+		if obj.Spec.UpdateStrategy.RollingUpdate == nil || !ptr.Deref(obj.Spec.UpdateStrategy.RollingUpdate.Paused, false) {
+			return nil, errors.New("is not paused")
+		}
+		obj.Spec.UpdateStrategy.RollingUpdate.Paused = ptr.To(false)
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+
+	case *kruiseappsv1alpha1.UnitedDeployment:
+		paused, err := unitedDeploymentPaused(obj)
+		if err != nil {
+			return nil, fmt.Errorf("resuming is not supported for UnitedDeployment of StatefulSets")
+		}
+		if !paused {
+			return nil, errors.New("is not paused")
+		}
+		setUnitedDeploymentPaused(obj, false)
+		return runtime.Encode(scheme.Codecs.LegacyCodec(kruiseappsv1alpha1.SchemeGroupVersion), obj)
+
 	case *rolloutsapi.Rollout:
 		if !obj.Spec.Strategy.Paused {
 			return nil, errors.New("is not paused")
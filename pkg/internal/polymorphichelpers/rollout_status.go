@@ -18,17 +18,23 @@ limitations under the License.
 package polymorphichelpers
 
 import (
+	"bytes"
 	"fmt"
 
 	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	kruiseappsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	rolloutv1alpha1 "github.com/openkruise/kruise-rollout-api/rollouts/v1alpha1"
+	rolloutv1beta1 "github.com/openkruise/kruise-rollout-api/rollouts/v1beta1"
 
 	appsv1 "k8s.io/api/apps/v1"
 	extensionsv1beta1 "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes"
 	deploymentutil "k8s.io/kubectl/pkg/util/deployment"
+	"k8s.io/kubectl/pkg/util/podutils"
+	"k8s.io/utils/ptr"
 )
 
 // StatusViewer provides an interface for resources that have rollout status.
@@ -37,26 +43,95 @@ type StatusViewer interface {
 	DetailStatus(c kubernetes.Interface, obj runtime.Unstructured, detail bool, revision int64) (string, bool, error)
 }
 
+// RolloutPausedError is returned by a StatusViewer when the rollout is not done and waits to be resumed or approved.
+type RolloutPausedError struct {
+	Message string
+}
+
+func (e *RolloutPausedError) Error() string {
+	return e.Message
+}
+
+// RolloutFailedError is returned by a StatusViewer when the rollout failed or was aborted, so it is not going to be done.
+type RolloutFailedError struct {
+	Message string
+}
+
+func (e *RolloutFailedError) Error() string {
+	return e.Message
+}
+
 // StatusViewerFor returns a StatusViewer for the resource specified by kind.
 func StatusViewerFor(kind schema.GroupKind) (StatusViewer, error) {
 	switch kind {
 	case extensionsv1beta1.SchemeGroupVersion.WithKind("Deployment").GroupKind(),
 		appsv1.SchemeGroupVersion.WithKind("Deployment").GroupKind():
-		return &DeploymentStatusViewer{}, nil
+		return &pausableStatusViewer{StatusViewer: &DeploymentStatusViewer{}, isPaused: pausedAt("spec", "paused")}, nil
 	case extensionsv1beta1.SchemeGroupVersion.WithKind("DaemonSet").GroupKind(),
 		appsv1.SchemeGroupVersion.WithKind("DaemonSet").GroupKind():
 		return &DaemonSetStatusViewer{}, nil
 	case appsv1.SchemeGroupVersion.WithKind("StatefulSet").GroupKind():
 		return &StatefulSetStatusViewer{}, nil
 	case kruiseappsv1alpha1.SchemeGroupVersion.WithKind("CloneSet").GroupKind():
-		return &CloneSetStatusViewer{}, nil
+		return &pausableStatusViewer{StatusViewer: &CloneSetStatusViewer{}, isPaused: pausedAt("spec", "updateStrategy", "paused")}, nil
 
 	case kruiseappsv1beta1.SchemeGroupVersion.WithKind("StatefulSet").GroupKind():
-		return &AdvancedStatefulSetStatusViewer{}, nil
+		return &pausableStatusViewer{StatusViewer: &AdvancedStatefulSetStatusViewer{},
+			isPaused: pausedAt("spec", "updateStrategy", "rollingUpdate", "paused")}, nil
+	case kruiseappsv1alpha1.SchemeGroupVersion.WithKind("DaemonSet").GroupKind():
+		return &pausableStatusViewer{StatusViewer: &AdvancedDaemonSetStatusViewer{},
+			isPaused: pausedAt("spec", "updateStrategy", "rollingUpdate", "paused")}, nil
+	case kruiseappsv1alpha1.SchemeGroupVersion.WithKind("UnitedDeployment").GroupKind():
+		return &pausableStatusViewer{StatusViewer: &UnitedDeploymentStatusViewer{}, isPaused: unitedDeploymentPausedStatus}, nil
+	case rolloutv1beta1.SchemeGroupVersion.WithKind("Rollout").GroupKind():
+		return &RolloutStatusViewer{}, nil
 	}
 	return nil, fmt.Errorf("no status viewer has been implemented for %v", kind)
 }
 
+// pausableStatusViewer returns a RolloutPausedError instead of the status of a paused workload that is not done.
+type pausableStatusViewer struct {
+	StatusViewer
+	isPaused func(obj runtime.Unstructured) bool
+}
+
+func (s *pausableStatusViewer) Status(c kubernetes.Interface, obj runtime.Unstructured, revision int64) (string, bool, error) {
+	status, done, err := s.StatusViewer.Status(c, obj, revision)
+	return s.checkPaused(obj, status, done, err)
+}
+
+func (s *pausableStatusViewer) DetailStatus(c kubernetes.Interface, obj runtime.Unstructured, detail bool, revision int64) (string, bool, error) {
+	status, done, err := s.StatusViewer.DetailStatus(c, obj, detail, revision)
+	return s.checkPaused(obj, status, done, err)
+}
+
+func (s *pausableStatusViewer) checkPaused(obj runtime.Unstructured, status string, done bool, err error) (string, bool, error) {
+	if err != nil || done || !s.isPaused(obj) {
+		return status, done, err
+	}
+	workload := &unstructured.Unstructured{Object: obj.UnstructuredContent()}
+	return status, false, &RolloutPausedError{
+		Message: fmt.Sprintf("rollout of %s %q is paused, waiting to be resumed", workload.GetKind(), workload.GetName()),
+	}
+}
+
+// pausedAt returns whether the workload is paused by the bool field at the path.
+func pausedAt(fields ...string) func(obj runtime.Unstructured) bool {
+	return func(obj runtime.Unstructured) bool {
+		paused, _, _ := unstructured.NestedBool(obj.UnstructuredContent(), fields...)
+		return paused
+	}
+}
+
+func unitedDeploymentPausedStatus(obj runtime.Unstructured) bool {
+	ud := &kruiseappsv1alpha1.UnitedDeployment{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), ud); err != nil {
+		return false
+	}
+	paused, _ := unitedDeploymentPaused(ud)
+	return paused
+}
+
 // DeploymentStatusViewer implements the StatusViewer interface.
 type DeploymentStatusViewer struct{}
 
@@ -72,6 +147,15 @@ type CloneSetStatusViewer struct{}
 // AdvancedStatefulSetStatusViewer  implements the StatusViewer interface
 type AdvancedStatefulSetStatusViewer struct{}
 
+// AdvancedDaemonSetStatusViewer implements the StatusViewer interface.
+type AdvancedDaemonSetStatusViewer struct{}
+
+// UnitedDeploymentStatusViewer implements the StatusViewer interface.
+type UnitedDeploymentStatusViewer struct{}
+
+// RolloutStatusViewer implements the StatusViewer interface for the Rollouts of kruise-rollout.
+type RolloutStatusViewer struct{}
+
 // Status returns a message describing deployment status, and a bool value indicating if the status is considered done.
 func (s *DeploymentStatusViewer) Status(c kubernetes.Interface, obj runtime.Unstructured, revision int64) (string, bool, error) {
 	deployment := &appsv1.Deployment{}
@@ -92,7 +176,7 @@ func (s *DeploymentStatusViewer) Status(c kubernetes.Interface, obj runtime.Unst
 	if deployment.Generation <= deployment.Status.ObservedGeneration {
 		cond := deploymentutil.GetDeploymentCondition(deployment.Status, appsv1.DeploymentProgressing)
 		if cond != nil && cond.Reason == deploymentutil.TimedOutReason {
-			return "", false, fmt.Errorf("deployment %q exceeded its progress deadline", deployment.Name)
+			return "", false, &RolloutFailedError{Message: fmt.Sprintf("deployment %q exceeded its progress deadline", deployment.Name)}
 		}
 		if deployment.Spec.Replicas != nil && deployment.Status.UpdatedReplicas < *deployment.Spec.Replicas {
 			return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d out of %d new replicas have been updated...\n", deployment.Name, deployment.Status.UpdatedReplicas, *deployment.Spec.Replicas), false, nil
@@ -128,7 +212,7 @@ func (s *DeploymentStatusViewer) DetailStatus(c kubernetes.Interface, obj runtim
 	if deployment.Generation <= deployment.Status.ObservedGeneration {
 		cond := deploymentutil.GetDeploymentCondition(deployment.Status, appsv1.DeploymentProgressing)
 		if cond != nil && cond.Reason == deploymentutil.TimedOutReason {
-			return "", false, fmt.Errorf("deployment %q exceeded its progress deadline", deployment.Name)
+			return "", false, &RolloutFailedError{Message: fmt.Sprintf("deployment %q exceeded its progress deadline", deployment.Name)}
 		}
 		if deployment.Spec.Replicas != nil && deployment.Status.UpdatedReplicas < *deployment.Spec.Replicas {
 			return fmt.Sprintf("Waiting for deployment %q rollout to finish: %d out of %d new replicas have been updated...\n", deployment.Name, deployment.Status.UpdatedReplicas, *deployment.Spec.Replicas), false, nil
@@ -389,3 +473,254 @@ func (s *AdvancedStatefulSetStatusViewer) DetailStatus(c kubernetes.Interface, o
 	}
 	return fmt.Sprintf("Advanced StatefulSet rolling update complete %d pods at revision %s...\n", asts.Status.AvailableReplicas, asts.Status.UpdateRevision), true, nil
 }
+
+// Status returns a message describing advanced daemon set status, and a bool value indicating if the status is considered done.
+func (s *AdvancedDaemonSetStatusViewer) Status(c kubernetes.Interface, obj runtime.Unstructured, revision int64) (string, bool, error) {
+	daemon := &kruiseappsv1alpha1.DaemonSet{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), daemon)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to convert %T to %T: %v", obj, daemon, err)
+	}
+
+	if daemon.Spec.UpdateStrategy.Type == kruiseappsv1alpha1.OnDeleteDaemonSetStrategyType {
+		return "", true, fmt.Errorf("rollout status is only available for %s strategy type", kruiseappsv1alpha1.RollingUpdateDaemonSetStrategyType)
+	}
+	if daemon.Generation > daemon.Status.ObservedGeneration {
+		return "Waiting for advanced daemon set spec update to be observed...\n", false, nil
+	}
+
+	// the pods of the partition are kept at the old revision
+	var partition int32
+	if rollingUpdate := daemon.Spec.UpdateStrategy.RollingUpdate; rollingUpdate != nil {
+		partition = ptr.Deref(rollingUpdate.Partition, 0)
+	}
+	desired := daemon.Status.DesiredNumberScheduled
+	if updating := max(desired-partition, 0); daemon.Status.UpdatedNumberScheduled < updating {
+		return fmt.Sprintf("Waiting for advanced daemon set %q rollout to finish: %d out of %d new pods have been updated...\n", daemon.Name, daemon.Status.UpdatedNumberScheduled, updating), false, nil
+	}
+	if daemon.Status.NumberAvailable < desired {
+		return fmt.Sprintf("Waiting for advanced daemon set %q rollout to finish: %d of %d pods are available...\n", daemon.Name, daemon.Status.NumberAvailable, desired), false, nil
+	}
+	if partition > 0 {
+		return fmt.Sprintf("advanced daemon set %q partitioned roll out complete: %d new pods have been updated...\n", daemon.Name, daemon.Status.UpdatedNumberScheduled), true, nil
+	}
+	return fmt.Sprintf("advanced daemon set %q successfully rolled out\n", daemon.Name), true, nil
+}
+
+// DetailStatus returns a message describing advanced daemon set status, and a bool value indicating if the status is considered done.
+func (s *AdvancedDaemonSetStatusViewer) DetailStatus(c kubernetes.Interface, obj runtime.Unstructured, detail bool, revision int64) (string, bool, error) {
+	return s.Status(c, obj, revision)
+}
+
+// Status returns a message describing uniteddeployment status with the progress of each subset, and a bool value
+// indicating if the status is considered done.
+func (s *UnitedDeploymentStatusViewer) Status(c kubernetes.Interface, obj runtime.Unstructured, revision int64) (string, bool, error) {
+	ud := &kruiseappsv1alpha1.UnitedDeployment{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), ud)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to convert %T to %T: %v", obj, ud, err)
+	}
+
+	if ud.Status.ObservedGeneration == 0 || ud.Generation > ud.Status.ObservedGeneration {
+		return fmt.Sprintf("Waiting for UnitedDeployment %q spec update to be observed...\n", ud.Name), false, nil
+	}
+	subsets, err := unitedDeploymentSubsetProgresses(c, ud)
+	if err != nil {
+		return "", false, err
+	}
+
+	done := true
+	buf := &bytes.Buffer{}
+	for _, subset := range subsets {
+		fmt.Fprintf(buf, "  subset %q: %d out of %d new pods have been updated, %d of %d pods are ready\n",
+			subset.name, subset.updated, subset.replicas-subset.partition, subset.ready, subset.replicas)
+		done = done && subset.updated >= subset.replicas-subset.partition && subset.ready >= subset.replicas
+	}
+	if !done {
+		return fmt.Sprintf("Waiting for UnitedDeployment %q rollout to finish:\n%s", ud.Name, buf.String()), false, nil
+	}
+	return fmt.Sprintf("UnitedDeployment %q successfully rolled out:\n%s", ud.Name, buf.String()), true, nil
+}
+
+// DetailStatus returns a message describing uniteddeployment status with the progress of each subset, and a bool value
+// indicating if the status is considered done.
+func (s *UnitedDeploymentStatusViewer) DetailStatus(c kubernetes.Interface, obj runtime.Unstructured, detail bool, revision int64) (string, bool, error) {
+	return s.Status(c, obj, revision)
+}
+
+// unitedDeploymentSubsetProgress is the rollout progress of a subset of a UnitedDeployment.
+type unitedDeploymentSubsetProgress struct {
+	name                                string
+	replicas, partition, updated, ready int32
+}
+
+// unitedDeploymentSubsetProgresses returns the progress of the subsets in the order of the topology. The pods of
+// a subset are told by the subset-name label, and the updated ones by the controller-revision-hash label.
+func unitedDeploymentSubsetProgresses(c kubernetes.Interface, ud *kruiseappsv1alpha1.UnitedDeployment) ([]unitedDeploymentSubsetProgress, error) {
+	updatedRevision := ud.Status.CurrentRevision
+	var partitions map[string]int32
+	if ud.Status.UpdateStatus != nil {
+		updatedRevision = ud.Status.UpdateStatus.UpdatedRevision
+		partitions = ud.Status.UpdateStatus.CurrentPartitions
+	}
+
+	pods, err := listPods(c.CoreV1(), ud.Namespace, ud.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	subsets := make([]unitedDeploymentSubsetProgress, 0, len(ud.Spec.Topology.Subsets))
+	for _, subset := range ud.Spec.Topology.Subsets {
+		progress := unitedDeploymentSubsetProgress{
+			name:      subset.Name,
+			replicas:  ud.Status.SubsetReplicas[subset.Name],
+			partition: partitions[subset.Name],
+		}
+		for i := range pods {
+			pod := &pods[i]
+			if pod.Labels[kruiseappsv1alpha1.SubSetNameLabelKey] != subset.Name {
+				continue
+			}
+			if pod.Labels[kruiseappsv1alpha1.ControllerRevisionHashLabelKey] == updatedRevision {
+				progress.updated++
+			}
+			if podutils.IsPodReady(pod) {
+				progress.ready++
+			}
+		}
+		subsets = append(subsets, progress)
+	}
+	return subsets, nil
+}
+
+// Status returns a message describing the step of the rollout, and a bool value indicating if it is healthy.
+func (s *RolloutStatusViewer) Status(c kubernetes.Interface, obj runtime.Unstructured, revision int64) (string, bool, error) {
+	return s.DetailStatus(c, obj, false, revision)
+}
+
+// DetailStatus returns a message describing the step of the rollout and the message of its status if detail is true,
+// and a bool value indicating if it is healthy.
+func (s *RolloutStatusViewer) DetailStatus(c kubernetes.Interface, obj runtime.Unstructured, detail bool, revision int64) (string, bool, error) {
+	//ignoring revision as the revisions are of the workload of the rollout
+
+	rollout, err := rolloutProgressOf(obj)
+	if err != nil {
+		return "", false, err
+	}
+
+	if rollout.generation > rollout.observedGeneration {
+		return fmt.Sprintf("Waiting for rollout %q spec update to be observed...\n", rollout.name), false, nil
+	}
+	var status string
+	switch rollout.phase {
+	case rolloutv1beta1.RolloutPhaseHealthy:
+		return fmt.Sprintf("rollout %q is healthy\n", rollout.name), true, nil
+	case rolloutv1beta1.RolloutPhaseDisabling, rolloutv1beta1.RolloutPhaseDisabled, rolloutv1beta1.RolloutPhaseTerminating:
+		return "", false, &RolloutFailedError{Message: fmt.Sprintf("rollout %q is %s", rollout.name, rollout.phase)}
+	}
+	if rollout.paused {
+		status = fmt.Sprintf("Waiting for rollout %q to be resumed...\n", rollout.name)
+		return status, false, &RolloutPausedError{Message: fmt.Sprintf("rollout %q is paused, waiting to be resumed", rollout.name)}
+	}
+	switch rollout.phase {
+	case rolloutv1beta1.RolloutPhaseProgressing:
+		switch {
+		case rollout.step == nil:
+			status = fmt.Sprintf("Waiting for rollout %q to start...\n", rollout.name)
+		case rollout.step.CurrentStepState == rolloutv1beta1.CanaryStepStatePaused && rollout.manualPause:
+			status = fmt.Sprintf("Waiting for rollout %q to be approved: %s step %d of %d is paused...\n",
+				rollout.name, rollout.style, rollout.step.CurrentStepIndex, rollout.steps)
+			return status, false, &RolloutPausedError{Message: fmt.Sprintf("rollout %q is paused at %s step %d of %d, waiting to be approved",
+				rollout.name, rollout.style, rollout.step.CurrentStepIndex, rollout.steps)}
+		default:
+			status = fmt.Sprintf("Waiting for rollout %q to finish: %s step %d of %d is in state %s...\n",
+				rollout.name, rollout.style, rollout.step.CurrentStepIndex, rollout.steps, rollout.step.CurrentStepState)
+		}
+	default:
+		status = fmt.Sprintf("Waiting for rollout %q to be initialized...\n", rollout.name)
+	}
+	if detail && len(rollout.message) > 0 {
+		status += rollout.message + "\n"
+	}
+	return status, false, nil
+}
+
+// rolloutProgress is the progress of the steps of a Rollout of either version.
+type rolloutProgress struct {
+	name                           string
+	generation, observedGeneration int64
+	phase                          rolloutv1beta1.RolloutPhase
+	message                        string
+	// style of the steps, canary or blue-green
+	style string
+	steps int
+	// step is the status of the current step, nil if the rollout has not started
+	step *rolloutv1beta1.CommonStatus
+	// manualPause is whether the current step is paused until it is approved, rather than for a duration
+	manualPause bool
+	// paused is whether the whole rollout is paused by its strategy
+	paused bool
+}
+
+func rolloutProgressOf(obj runtime.Unstructured) (*rolloutProgress, error) {
+	if obj.GetObjectKind().GroupVersionKind().GroupVersion() == rolloutv1alpha1.GroupVersion {
+		rollout := &rolloutv1alpha1.Rollout{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), rollout); err != nil {
+			return nil, fmt.Errorf("failed to convert %T to %T: %v", obj, rollout, err)
+		}
+		progress := &rolloutProgress{
+			name:               rollout.Name,
+			generation:         rollout.Generation,
+			observedGeneration: rollout.Status.ObservedGeneration,
+			phase:              rolloutv1beta1.RolloutPhase(rollout.Status.Phase),
+			message:            rollout.Status.Message,
+			style:              "canary",
+			paused:             rollout.Spec.Strategy.Paused,
+		}
+		var steps []rolloutv1alpha1.CanaryStep
+		if rollout.Spec.Strategy.Canary != nil {
+			steps = rollout.Spec.Strategy.Canary.Steps
+		}
+		progress.steps = len(steps)
+		if status := rollout.Status.CanaryStatus; status != nil {
+			progress.step = &rolloutv1beta1.CommonStatus{
+				CurrentStepIndex: status.CurrentStepIndex,
+				CurrentStepState: rolloutv1beta1.CanaryStepState(status.CurrentStepState),
+				Message:          status.Message,
+			}
+			if i := int(status.CurrentStepIndex); i >= 1 && i <= len(steps) {
+				progress.manualPause = steps[i-1].Pause.Duration == nil
+			}
+		}
+		return progress, nil
+	}
+
+	rollout := &rolloutv1beta1.Rollout{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), rollout); err != nil {
+		return nil, fmt.Errorf("failed to convert %T to %T: %v", obj, rollout, err)
+	}
+	progress := &rolloutProgress{
+		name:               rollout.Name,
+		generation:         rollout.Generation,
+		observedGeneration: rollout.Status.ObservedGeneration,
+		phase:              rollout.Status.Phase,
+		message:            rollout.Status.Message,
+		style:              "canary",
+		paused:             rollout.Spec.Strategy.Paused,
+	}
+	steps := rollout.Spec.Strategy.GetSteps()
+	progress.steps = len(steps)
+	if rollout.Spec.Strategy.IsBlueGreenRelease() {
+		progress.style = "blue-green"
+		if rollout.Status.BlueGreenStatus != nil {
+			progress.step = &rollout.Status.BlueGreenStatus.CommonStatus
+		}
+	} else if rollout.Status.CanaryStatus != nil {
+		progress.step = &rollout.Status.CanaryStatus.CommonStatus
+	}
+	if progress.step != nil {
+		if i := int(progress.step.CurrentStepIndex); i >= 1 && i <= len(steps) {
+			progress.manualPause = steps[i-1].Pause.Duration == nil
+		}
+	}
+	return progress, nil
+}
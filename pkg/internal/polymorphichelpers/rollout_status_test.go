@@ -0,0 +1,242 @@
+/*
+Copyright 2021 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package polymorphichelpers
+
+import (
+	"testing"
+
+	kruiseappsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	rolloutv1alpha1 "github.com/openkruise/kruise-rollout-api/rollouts/v1alpha1"
+	rolloutv1beta1 "github.com/openkruise/kruise-rollout-api/rollouts/v1beta1"
+	"github.com/stretchr/testify/assert"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestKruiseStatusViewers(t *testing.T) {
+	toUnstructured := func(t *testing.T, obj runtime.Object, gvk schema.GroupVersionKind) runtime.Unstructured {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		assert.NoError(t, err)
+		u := &unstructured.Unstructured{Object: content}
+		u.SetGroupVersionKind(gvk)
+		return u
+	}
+
+	daemonSet := func(partition int32, paused bool, updated, available int32) runtime.Object {
+		return &kruiseappsv1alpha1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "demo", Generation: 1},
+			Spec: kruiseappsv1alpha1.DaemonSetSpec{UpdateStrategy: kruiseappsv1alpha1.DaemonSetUpdateStrategy{
+				Type:          kruiseappsv1alpha1.RollingUpdateDaemonSetStrategyType,
+				RollingUpdate: &kruiseappsv1alpha1.RollingUpdateDaemonSet{Partition: ptr.To(partition), Paused: ptr.To(paused)},
+			}},
+			Status: kruiseappsv1alpha1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 3,
+				UpdatedNumberScheduled: updated, NumberAvailable: available},
+		}
+	}
+
+	unitedDeployment := &kruiseappsv1alpha1.UnitedDeployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo", Generation: 1},
+		Spec: kruiseappsv1alpha1.UnitedDeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: historyLabels},
+			Topology: kruiseappsv1alpha1.Topology{Subsets: []kruiseappsv1alpha1.Subset{{Name: "zone-a"}, {Name: "zone-b"}}},
+		},
+		Status: kruiseappsv1alpha1.UnitedDeploymentStatus{
+			ObservedGeneration: 1,
+			SubsetReplicas:     map[string]int32{"zone-a": 2, "zone-b": 1},
+			UpdateStatus:       &kruiseappsv1alpha1.UpdateStatus{UpdatedRevision: "demo-2", CurrentPartitions: map[string]int32{"zone-a": 1}},
+		},
+	}
+	unitedDeploymentPod := func(name, subset, revision string, ready bool) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: map[string]string{
+			"app": "demo", kruiseappsv1alpha1.SubSetNameLabelKey: subset, kruiseappsv1alpha1.ControllerRevisionHashLabelKey: revision}}}
+		if ready {
+			pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		}
+		return pod
+	}
+
+	rollout := func(phase rolloutv1beta1.RolloutPhase, state rolloutv1beta1.CanaryStepState) *rolloutv1beta1.Rollout {
+		return &rolloutv1beta1.Rollout{
+			ObjectMeta: metav1.ObjectMeta{Name: "demo", Generation: 1},
+			Spec: rolloutv1beta1.RolloutSpec{Strategy: rolloutv1beta1.RolloutStrategy{Canary: &rolloutv1beta1.CanaryStrategy{
+				Steps: []rolloutv1beta1.CanaryStep{{}, {}, {}},
+			}}},
+			Status: rolloutv1beta1.RolloutStatus{ObservedGeneration: 1, Phase: phase, Message: "in step 2",
+				CanaryStatus: &rolloutv1beta1.CanaryStatus{CommonStatus: rolloutv1beta1.CommonStatus{CurrentStepIndex: 2, CurrentStepState: state}}},
+		}
+	}
+	pausedForDurationRollout := rollout(rolloutv1beta1.RolloutPhaseProgressing, rolloutv1beta1.CanaryStepStatePaused)
+	pausedForDurationRollout.Spec.Strategy.Canary.Steps[1].Pause.Duration = ptr.To[int32](60)
+	pausedRollout := rollout(rolloutv1beta1.RolloutPhaseProgressing, rolloutv1beta1.CanaryStepStateUpgrade)
+	pausedRollout.Spec.Strategy.Paused = true
+	blueGreenRollout := rollout(rolloutv1beta1.RolloutPhaseProgressing, "")
+	blueGreenRollout.Spec.Strategy = rolloutv1beta1.RolloutStrategy{BlueGreen: &rolloutv1beta1.BlueGreenStrategy{
+		Steps: []rolloutv1beta1.CanaryStep{{}, {}},
+	}}
+	blueGreenRollout.Status.CanaryStatus = nil
+	blueGreenRollout.Status.BlueGreenStatus = &rolloutv1beta1.BlueGreenStatus{CommonStatus: rolloutv1beta1.CommonStatus{
+		CurrentStepIndex: 1, CurrentStepState: rolloutv1beta1.CanaryStepStateUpgrade}}
+	alphaRollout := &rolloutv1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo"},
+		Spec: rolloutv1alpha1.RolloutSpec{Strategy: rolloutv1alpha1.RolloutStrategy{Canary: &rolloutv1alpha1.CanaryStrategy{
+			Steps: []rolloutv1alpha1.CanaryStep{{}, {}},
+		}}},
+		Status: rolloutv1alpha1.RolloutStatus{Phase: rolloutv1alpha1.RolloutPhaseProgressing,
+			CanaryStatus: &rolloutv1alpha1.CanaryStatus{CurrentStepIndex: 1, CurrentStepState: rolloutv1alpha1.CanaryStepStatePaused}},
+	}
+
+	adsKind := kruiseappsv1alpha1.SchemeGroupVersion.WithKind("DaemonSet")
+	udKind := kruiseappsv1alpha1.SchemeGroupVersion.WithKind("UnitedDeployment")
+	rolloutKind := rolloutv1beta1.SchemeGroupVersion.WithKind("Rollout")
+	testCases := []struct {
+		name           string
+		obj            runtime.Object
+		gvk            schema.GroupVersionKind
+		pods           []runtime.Object
+		detail         bool
+		expectedStatus string
+		expectedDone   bool
+		expectedErr    error
+	}{
+		{
+			name:           "Advanced DaemonSet updating",
+			obj:            daemonSet(0, false, 1, 3),
+			gvk:            adsKind,
+			expectedStatus: "Waiting for advanced daemon set \"demo\" rollout to finish: 1 out of 3 new pods have been updated...\n",
+		},
+		{
+			name:           "Advanced DaemonSet paused",
+			obj:            daemonSet(0, true, 1, 3),
+			gvk:            adsKind,
+			expectedStatus: "Waiting for advanced daemon set \"demo\" rollout to finish: 1 out of 3 new pods have been updated...\n",
+			expectedErr:    &RolloutPausedError{Message: "rollout of DaemonSet \"demo\" is paused, waiting to be resumed"},
+		},
+		{
+			name:           "Advanced DaemonSet paused once rolled out",
+			obj:            daemonSet(0, true, 3, 3),
+			gvk:            adsKind,
+			expectedStatus: "advanced daemon set \"demo\" successfully rolled out\n",
+			expectedDone:   true,
+		},
+		{
+			name:           "Advanced DaemonSet partitioned",
+			obj:            daemonSet(2, false, 1, 3),
+			gvk:            adsKind,
+			expectedStatus: "advanced daemon set \"demo\" partitioned roll out complete: 1 new pods have been updated...\n",
+			expectedDone:   true,
+		},
+		{
+			name:           "Advanced DaemonSet rolled out",
+			obj:            daemonSet(0, false, 3, 3),
+			gvk:            adsKind,
+			expectedStatus: "advanced daemon set \"demo\" successfully rolled out\n",
+			expectedDone:   true,
+		},
+		{
+			name: "UnitedDeployment updating",
+			obj:  unitedDeployment,
+			gvk:  udKind,
+			pods: []runtime.Object{
+				unitedDeploymentPod("a-1", "zone-a", "demo-2", true),
+				unitedDeploymentPod("a-2", "zone-a", "demo-1", true),
+				unitedDeploymentPod("b-1", "zone-b", "demo-2", false),
+			},
+			expectedStatus: "Waiting for UnitedDeployment \"demo\" rollout to finish:\n" +
+				"  subset \"zone-a\": 1 out of 1 new pods have been updated, 2 of 2 pods are ready\n" +
+				"  subset \"zone-b\": 1 out of 1 new pods have been updated, 0 of 1 pods are ready\n",
+		},
+		{
+			name: "UnitedDeployment rolled out",
+			obj:  unitedDeployment,
+			gvk:  udKind,
+			pods: []runtime.Object{
+				unitedDeploymentPod("a-1", "zone-a", "demo-2", true),
+				unitedDeploymentPod("a-2", "zone-a", "demo-1", true),
+				unitedDeploymentPod("b-1", "zone-b", "demo-2", true),
+			},
+			expectedStatus: "UnitedDeployment \"demo\" successfully rolled out:\n" +
+				"  subset \"zone-a\": 1 out of 1 new pods have been updated, 2 of 2 pods are ready\n" +
+				"  subset \"zone-b\": 1 out of 1 new pods have been updated, 1 of 1 pods are ready\n",
+			expectedDone: true,
+		},
+		{
+			name:           "Rollout paused in canary step",
+			obj:            rollout(rolloutv1beta1.RolloutPhaseProgressing, rolloutv1beta1.CanaryStepStatePaused),
+			gvk:            rolloutKind,
+			detail:         true,
+			expectedStatus: "Waiting for rollout \"demo\" to be approved: canary step 2 of 3 is paused...\n",
+			expectedErr:    &RolloutPausedError{Message: "rollout \"demo\" is paused at canary step 2 of 3, waiting to be approved"},
+		},
+		{
+			name:           "Rollout paused for a duration",
+			obj:            pausedForDurationRollout,
+			gvk:            rolloutKind,
+			detail:         true,
+			expectedStatus: "Waiting for rollout \"demo\" to finish: canary step 2 of 3 is in state StepPaused...\nin step 2\n",
+		},
+		{
+			name:           "Rollout paused by its strategy",
+			obj:            pausedRollout,
+			gvk:            rolloutKind,
+			expectedStatus: "Waiting for rollout \"demo\" to be resumed...\n",
+			expectedErr:    &RolloutPausedError{Message: "rollout \"demo\" is paused, waiting to be resumed"},
+		},
+		{
+			name:           "Rollout in blue-green step",
+			obj:            blueGreenRollout,
+			gvk:            rolloutKind,
+			expectedStatus: "Waiting for rollout \"demo\" to finish: blue-green step 1 of 2 is in state StepUpgrade...\n",
+		},
+		{
+			name:           "Rollout of v1alpha1",
+			obj:            alphaRollout,
+			gvk:            rolloutv1alpha1.SchemeGroupVersion.WithKind("Rollout"),
+			expectedStatus: "Waiting for rollout \"demo\" to be approved: canary step 1 of 2 is paused...\n",
+			expectedErr:    &RolloutPausedError{Message: "rollout \"demo\" is paused at canary step 1 of 2, waiting to be approved"},
+		},
+		{
+			name:           "Rollout healthy",
+			obj:            rollout(rolloutv1beta1.RolloutPhaseHealthy, rolloutv1beta1.CanaryStepStateCompleted),
+			gvk:            rolloutKind,
+			expectedStatus: "rollout \"demo\" is healthy\n",
+			expectedDone:   true,
+		},
+		{
+			name:        "Rollout disabled",
+			obj:         rollout(rolloutv1beta1.RolloutPhaseDisabled, ""),
+			gvk:         rolloutKind,
+			expectedErr: &RolloutFailedError{Message: "rollout \"demo\" is Disabled"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			viewer, err := StatusViewerFor(tc.gvk.GroupKind())
+			assert.NoError(t, err)
+			status, done, err := viewer.DetailStatus(fake.NewSimpleClientset(tc.pods...), toUnstructured(t, tc.obj, tc.gvk), tc.detail, 0)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedStatus, status)
+			assert.Equal(t, tc.expectedDone, done)
+		})
+	}
+}
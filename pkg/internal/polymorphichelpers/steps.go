@@ -3,6 +3,7 @@ package polymorphichelpers
 import (
 	"fmt"
 
+	"github.com/openkruise/kruise-rollout-api/rollouts/v1alpha1"
 	"github.com/openkruise/kruise-rollout-api/rollouts/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -47,6 +48,77 @@ func defaultRolloutRollbackGetter(targetStep int32) func(runtime.Object) ([]byte
 	}
 }
 
+// SkipToEndStep is the target step of defaultRolloutStepApprover to jump to the last step.
+const SkipToEndStep int32 = -1
+
+// defaultRolloutStepApprover returns an approver that makes the rollout jump to a later step after the current one
+// by its NextStepIndex, and approves the current step if it is paused.
+func defaultRolloutStepApprover(targetStep int32) ObjectApproverFunc {
+	return func(obj runtime.Object) ([]byte, error) {
+		switch rollout := obj.(type) {
+		case *v1beta1.Rollout:
+			var status *v1beta1.CommonStatus
+			switch {
+			case rollout.Spec.Strategy.BlueGreen != nil:
+				if rollout.Status.BlueGreenStatus != nil {
+					status = &rollout.Status.BlueGreenStatus.CommonStatus
+				}
+			case rollout.Spec.Strategy.Canary != nil:
+				// canary and partition
+				if rollout.Status.CanaryStatus != nil {
+					status = &rollout.Status.CanaryStatus.CommonStatus
+				}
+			}
+			if status == nil {
+				return nil, fmt.Errorf("no need to approve: not in canary or blue-green progress")
+			}
+			target, err := validateTargetStep(targetStep, len(rollout.Spec.Strategy.GetSteps()), rollout.Status.CurrentStepIndex)
+			if err != nil {
+				return nil, err
+			}
+			status.NextStepIndex = target
+			if status.CurrentStepState == v1beta1.CanaryStepStatePaused {
+				status.CurrentStepState = v1beta1.CanaryStepStateReady
+			}
+			return runtime.Encode(scheme.Codecs.LegacyCodec(v1beta1.GroupVersion), rollout)
+		case *v1alpha1.Rollout:
+			status := rollout.Status.CanaryStatus
+			if status == nil || rollout.Spec.Strategy.Canary == nil {
+				return nil, fmt.Errorf("no need to approve: not in canary progress")
+			}
+			target, err := validateTargetStep(targetStep, len(rollout.Spec.Strategy.Canary.Steps), status.CurrentStepIndex)
+			if err != nil {
+				return nil, err
+			}
+			status.NextStepIndex = target
+			if status.CurrentStepState == v1alpha1.CanaryStepStatePaused {
+				status.CurrentStepState = v1alpha1.CanaryStepStateReady
+			}
+			return runtime.Encode(scheme.Codecs.LegacyCodec(v1alpha1.GroupVersion), rollout)
+		default:
+			return nil, fmt.Errorf("approving to a step is not supported")
+		}
+	}
+}
+
+// validateTargetStep returns the target step if it is one of the steps after the current one, or the last step
+// for SkipToEndStep.
+func validateTargetStep(targetStep int32, steps int, curStep int32) (int32, error) {
+	if targetStep == SkipToEndStep {
+		if int(curStep) >= steps {
+			return 0, fmt.Errorf("already at the last step")
+		}
+		targetStep = int32(steps)
+	}
+	if targetStep < 1 || int(targetStep) > steps {
+		return 0, fmt.Errorf("specified step %d is out of range, the rollout has %d steps", targetStep, steps)
+	}
+	if targetStep <= curStep {
+		return 0, fmt.Errorf("specified step %d is not a later step (current step is %d)", targetStep, curStep)
+	}
+	return targetStep, nil
+}
+
 func findPreviousStepWithNoTrafficAndMostReplicas(steps []v1beta1.CanaryStep, curStep int32) (int32, error) {
 	maxReplicas := 0
 	var targetStep int32 = -1
@@ -6,6 +6,7 @@ import (
 
 	"github.com/openkruise/kruise-rollout-api/rollouts/v1alpha1"
 	"github.com/openkruise/kruise-rollout-api/rollouts/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/kubectl/pkg/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -160,3 +161,128 @@ func TestRolloutRollbackGetter(t *testing.T) {
 		})
 	}
 }
+
+func TestRolloutStepApprover(t *testing.T) {
+	steps := []v1beta1.CanaryStep{{}, {}, {}, {}}
+	getRollout := func(currentIdx int32, state v1beta1.CanaryStepState) []client.Object {
+		canary := &v1beta1.Rollout{
+			Status: v1beta1.RolloutStatus{
+				CurrentStepIndex: currentIdx,
+			},
+		}
+		canary.Spec.Strategy.Canary = &v1beta1.CanaryStrategy{
+			Steps:                        steps,
+			EnableExtraWorkloadForCanary: true,
+		}
+		canary.Status.CanaryStatus = &v1beta1.CanaryStatus{
+			CommonStatus: v1beta1.CommonStatus{CurrentStepIndex: currentIdx, CurrentStepState: state},
+		}
+
+		blueGreen := canary.DeepCopy()
+		blueGreen.Spec.Strategy.BlueGreen = &v1beta1.BlueGreenStrategy{
+			Steps: steps,
+		}
+		blueGreen.Status.BlueGreenStatus = &v1beta1.BlueGreenStatus{
+			CommonStatus: v1beta1.CommonStatus{CurrentStepIndex: currentIdx, CurrentStepState: state},
+		}
+
+		return []client.Object{canary, blueGreen}
+	}
+
+	tests := []struct {
+		name          string
+		rollout       []client.Object
+		targetStep    int32
+		expectedStep  int32
+		expectedState v1beta1.CanaryStepState
+		expectedErr   string
+	}{
+		{
+			name:          "jump to a later step from a paused step",
+			rollout:       getRollout(1, v1beta1.CanaryStepStatePaused),
+			targetStep:    3,
+			expectedStep:  3,
+			expectedState: v1beta1.CanaryStepStateReady,
+		},
+		{
+			name:          "jump to a later step from an upgrading step",
+			rollout:       getRollout(1, v1beta1.CanaryStepStateUpgrade),
+			targetStep:    2,
+			expectedStep:  2,
+			expectedState: v1beta1.CanaryStepStateUpgrade,
+		},
+		{
+			name:          "skip to the last step",
+			rollout:       getRollout(2, v1beta1.CanaryStepStatePaused),
+			targetStep:    SkipToEndStep,
+			expectedStep:  4,
+			expectedState: v1beta1.CanaryStepStateReady,
+		},
+		{
+			name:        "already at the last step",
+			rollout:     getRollout(4, v1beta1.CanaryStepStatePaused),
+			targetStep:  SkipToEndStep,
+			expectedErr: "already at the last step",
+		},
+		{
+			name:        "invalid jump to the same or a previous step",
+			rollout:     getRollout(3, v1beta1.CanaryStepStatePaused),
+			targetStep:  2,
+			expectedErr: "specified step 2 is not a later step (current step is 3)",
+		},
+		{
+			name:        "target step out of range",
+			rollout:     getRollout(1, v1beta1.CanaryStepStatePaused),
+			targetStep:  5,
+			expectedErr: "specified step 5 is out of range, the rollout has 4 steps",
+		},
+		{
+			name:        "not in progress",
+			rollout:     []client.Object{&v1beta1.Rollout{}},
+			targetStep:  2,
+			expectedErr: "no need to approve: not in canary or blue-green progress",
+		},
+		{
+			name:        "not supported object",
+			rollout:     []client.Object{&appsv1.Deployment{}},
+			targetStep:  2,
+			expectedErr: "approving to a step is not supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			approve := defaultRolloutStepApprover(tt.targetStep)
+			for _, rollout := range tt.rollout {
+				data, err := approve(rollout)
+				if tt.expectedErr != "" {
+					if err == nil || err.Error() != tt.expectedErr {
+						t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+
+				var updatedRollout v1beta1.Rollout
+				if err := json.Unmarshal(data, &updatedRollout); err != nil {
+					t.Errorf("failed to unmarshal updated rollout: %v", err)
+					return
+				}
+
+				status := updatedRollout.Status.CanaryStatus.CommonStatus
+				if updatedRollout.Spec.Strategy.GetRollingStyle() == v1beta1.BlueGreenRollingStyle {
+					status = updatedRollout.Status.BlueGreenStatus.CommonStatus
+				}
+				if status.NextStepIndex != tt.expectedStep {
+					t.Errorf("expected next step index %d, got %d", tt.expectedStep, status.NextStepIndex)
+				}
+				if status.CurrentStepState != tt.expectedState {
+					t.Errorf("expected current step state %s, got %s", tt.expectedState, status.CurrentStepState)
+				}
+			}
+		})
+	}
+}
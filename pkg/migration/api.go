@@ -19,34 +19,74 @@ package migration
 import (
 	"github.com/openkruise/kruise-tools/pkg/api"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/util/workqueue"
 )
 
 type Control interface {
 	Submit(src api.ResourceRef, dst api.ResourceRef, opts Options) (Result, error)
+	// Plan returns the steps that Submit would take from the current replicas without changing
+	// any object. With serverDryRun the final replicas are also validated by the API server.
+	Plan(src api.ResourceRef, dst api.ResourceRef, opts Options, serverDryRun bool) (Plan, error)
+	// Resume continues the task recorded in the StateAnnotation of dst.
+	Resume(dst api.ResourceRef) (Result, error)
 	Query(ID types.UID) (Result, error)
+	// Abort stops the task and leaves both workloads as they are, so that it can be resumed
+	// or rolled back later.
+	Abort(ID types.UID) (Result, error)
+	// Rollback scales the source back up and the destination back down in MaxSurge and MaxUnavailable steps,
+	// until both of them have the replicas they had when the task was submitted.
+	Rollback(ID types.UID) (Result, error)
+}
+
+// ControlOptions configures how a Control runs its tasks.
+type ControlOptions struct {
+	// MaxConcurrentReconciles is the number of workers reconciling tasks at the same time.
+	// Defaults to 5.
+	MaxConcurrentReconciles int
+	// RateLimiter limits how fast a task is requeued after it failed to reconcile.
+	// Defaults to workqueue.DefaultControllerRateLimiter().
+	RateLimiter workqueue.RateLimiter
+	// MaxRunningTasksPerNamespace is the maximum number of tasks running in a namespace at the same time,
+	// the others wait until one of them finishes. Their timeout counts from when they start running.
+	// Defaults to no limited.
+	MaxRunningTasksPerNamespace int
 }
 
 type Options struct {
 	// Specify Replicas that should be migrated.
 	// Default to migrate all replicas
-	Replicas *int32
+	Replicas *int32 `json:"replicas,omitempty"`
 	// The maximum number of pods that can be scheduled above the desired number of pods.
+	// Value can be an absolute number (ex: 5) or a percentage of Replicas (ex: 10%),
+	// which is calculated by rounding up.
 	// This can not be 0 if MaxUnavailable is 0.
-	// Defaults to 1.
-	MaxSurge *int32
+	// Defaults to 1 if MaxUnavailable is not set, otherwise 0.
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+	// The maximum number of pods that can be unavailable during migration, so that the source
+	// can be scaled in before the destination is available.
+	// Value can be an absolute number (ex: 5) or a percentage of Replicas (ex: 10%),
+	// which is calculated by rounding down.
+	// Defaults to 0.
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
 	// TimeoutSeconds indicates the timeout seconds that migration exceeded.
 	// Defaults to no limited.
-	TimeoutSeconds *int32
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+	// NodeSelector selects the nodes to hand over when migrating a DaemonSet,
+	// where Replicas, MaxSurge and MaxUnavailable count nodes instead of pods.
+	// Defaults to all nodes running the source.
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
 }
 
 type Result struct {
-	ID      types.UID
-	State   MigrateState
-	Message string
+	ID      types.UID    `json:"id"`
+	State   MigrateState `json:"state"`
+	Message string       `json:"message,omitempty"`
 
-	SrcMigratedReplicas int32
-	DstMigratedReplicas int32
+	SrcMigratedReplicas int32 `json:"srcMigratedReplicas"`
+	DstMigratedReplicas int32 `json:"dstMigratedReplicas"`
 }
 
 type MigrateState string
@@ -55,4 +95,13 @@ const (
 	MigrateExecuting MigrateState = "Executing"
 	MigrateSucceeded MigrateState = "Succeeded"
 	MigrateFailed    MigrateState = "Failed"
+
+	MigrateAborted     MigrateState = "Aborted"
+	MigrateRollingBack MigrateState = "RollingBack"
+	MigrateRolledBack  MigrateState = "RolledBack"
 )
+
+// IsRunning returns whether the task is still scaling the workloads.
+func (s MigrateState) IsRunning() bool {
+	return s == MigrateExecuting || s == MigrateRollingBack
+}
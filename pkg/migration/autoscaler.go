@@ -0,0 +1,216 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AutoscalerAnnotation is set on an autoscaler suspended by a task, to record how to restore it.
+const AutoscalerAnnotation = "migration.kruise.io/suspended-autoscaler"
+
+// ScaledObjectPausedAnnotation pauses the autoscaling of a KEDA ScaledObject.
+const ScaledObjectPausedAnnotation = "autoscaling.keda.sh/paused"
+
+var (
+	HorizontalPodAutoscalerKind = schema.GroupVersionKind{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler"}
+	// ScaledObjectKind is read as unstructured objects since KEDA is not always installed.
+	ScaledObjectKind = schema.GroupVersionKind{Group: "keda.sh", Version: "v1alpha1", Kind: "ScaledObject"}
+)
+
+// suspendedAutoscaler is the value of the AutoscalerAnnotation.
+type suspendedAutoscaler struct {
+	ID types.UID `json:"id"`
+	// Behavior of a HorizontalPodAutoscaler before it was suspended, nil if it was not set.
+	Behavior map[string]interface{} `json:"behavior,omitempty"`
+	// Paused annotation of a ScaledObject before it was suspended, nil if it was not set.
+	Paused *string `json:"paused,omitempty"`
+}
+
+// SuspendAutoscalers suspends the HorizontalPodAutoscalers and KEDA ScaledObjects targeting src or dst, so that
+// they do not fight the task over the replicas. Those already suspended by a task are left as they are.
+func SuspendAutoscalers(c client.Client, ID types.UID, src, dst api.ResourceRef) error {
+	return updateAutoscalers(c, src.Namespace, func(obj *unstructured.Unstructured) (bool, error) {
+		if _, ok := obj.GetAnnotations()[AutoscalerAnnotation]; ok || !(autoscalerTargets(obj, src) || autoscalerTargets(obj, dst)) {
+			return false, nil
+		}
+
+		record := suspendedAutoscaler{ID: ID}
+		if obj.GroupVersionKind() == ScaledObjectKind {
+			if paused, ok := obj.GetAnnotations()[ScaledObjectPausedAnnotation]; ok {
+				record.Paused = &paused
+			}
+			setAnnotation(obj, ScaledObjectPausedAnnotation, "true")
+		} else {
+			behavior, ok, _ := unstructured.NestedMap(obj.Object, "spec", "behavior")
+			if ok {
+				record.Behavior = behavior
+			}
+			// the HorizontalPodAutoscaler keeps its target, but scales it in neither direction
+			for _, direction := range []string{"scaleUp", "scaleDown"} {
+				if err := unstructured.SetNestedField(obj.Object, "Disabled", "spec", "behavior", direction, "selectPolicy"); err != nil {
+					return false, err
+				}
+			}
+		}
+
+		value, err := json.Marshal(record)
+		if err != nil {
+			return false, err
+		}
+		setAnnotation(obj, AutoscalerAnnotation, string(value))
+		return true, nil
+	})
+}
+
+// ResumeAutoscalers restores the autoscalers in the namespace suspended by the task. They are retargeted to target
+// if it is not nil, which is the workload that has all the replicas once the task succeeded or rolled back.
+func ResumeAutoscalers(c client.Client, ID types.UID, namespace string, target *api.ResourceRef) error {
+	return updateAutoscalers(c, namespace, func(obj *unstructured.Unstructured) (bool, error) {
+		value, ok := obj.GetAnnotations()[AutoscalerAnnotation]
+		if !ok {
+			return false, nil
+		}
+		record := suspendedAutoscaler{}
+		if err := json.Unmarshal([]byte(value), &record); err != nil {
+			return false, fmt.Errorf("failed to parse %s of %s %s: %v", AutoscalerAnnotation, obj.GetKind(), obj.GetName(), err)
+		} else if record.ID != ID {
+			return false, nil
+		}
+
+		if target != nil {
+			targetRef := map[string]interface{}{"apiVersion": target.APIVersion, "kind": target.Kind, "name": target.Name}
+			if err := unstructured.SetNestedMap(obj.Object, targetRef, "spec", "scaleTargetRef"); err != nil {
+				return false, err
+			}
+		}
+		if obj.GroupVersionKind() == ScaledObjectKind {
+			if record.Paused != nil {
+				setAnnotation(obj, ScaledObjectPausedAnnotation, *record.Paused)
+			} else {
+				removeAnnotation(obj, ScaledObjectPausedAnnotation)
+			}
+		} else if record.Behavior != nil {
+			if err := unstructured.SetNestedMap(obj.Object, record.Behavior, "spec", "behavior"); err != nil {
+				return false, err
+			}
+		} else {
+			unstructured.RemoveNestedField(obj.Object, "spec", "behavior")
+		}
+		removeAnnotation(obj, AutoscalerAnnotation)
+		return true, nil
+	})
+}
+
+// updateAutoscalers updates the autoscalers in the namespace that mutate changes.
+func updateAutoscalers(c client.Client, namespace string, mutate func(obj *unstructured.Unstructured) (bool, error)) error {
+	autoscalers, err := listAutoscalers(c, namespace)
+	if err != nil {
+		return err
+	}
+	for i := range autoscalers {
+		obj := &autoscalers[i]
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			changed, err := mutate(obj)
+			if err != nil || !changed {
+				return err
+			}
+			if err = c.Update(context.TODO(), obj); errors.IsConflict(err) {
+				// mutate the latest version on retry
+				if getErr := c.Get(context.TODO(), client.ObjectKeyFromObject(obj), obj); getErr != nil {
+					return getErr
+				}
+			}
+			return err
+		})
+		if client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to update %s %s: %v", obj.GetKind(), obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// listAutoscalers returns the HorizontalPodAutoscalers and ScaledObjects in the namespace, except the
+// HorizontalPodAutoscalers that KEDA manages for ScaledObjects. ScaledObjects are skipped if KEDA is not installed.
+func listAutoscalers(reader client.Reader, namespace string) ([]unstructured.Unstructured, error) {
+	var autoscalers []unstructured.Unstructured
+	for _, gvk := range []schema.GroupVersionKind{HorizontalPodAutoscalerKind, ScaledObjectKind} {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := reader.List(context.TODO(), list, client.InNamespace(namespace)); err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to list %s: %v", gvk.Kind, err)
+		}
+		for i := range list.Items {
+			if owner := metav1.GetControllerOf(&list.Items[i]); owner != nil && owner.Kind == ScaledObjectKind.Kind {
+				continue
+			}
+			autoscalers = append(autoscalers, list.Items[i])
+		}
+	}
+	return autoscalers, nil
+}
+
+// autoscalerTargets returns whether the scaleTargetRef of the autoscaler is ref. The target of a ScaledObject
+// defaults to a Deployment.
+func autoscalerTargets(obj *unstructured.Unstructured, ref api.ResourceRef) bool {
+	apiVersion, _, _ := unstructured.NestedString(obj.Object, "spec", "scaleTargetRef", "apiVersion")
+	kind, _, _ := unstructured.NestedString(obj.Object, "spec", "scaleTargetRef", "kind")
+	name, _, _ := unstructured.NestedString(obj.Object, "spec", "scaleTargetRef", "name")
+	if obj.GroupVersionKind() == ScaledObjectKind && len(kind) == 0 {
+		apiVersion, kind = api.DeploymentKind.GroupVersion().String(), api.DeploymentKind.Kind
+	}
+	return targetsRef(ref, apiVersion, kind, name)
+}
+
+// targetsRef returns whether a reference to the object with the given apiVersion, kind and name is ref,
+// regardless of the version.
+func targetsRef(ref api.ResourceRef, apiVersion, kind, name string) bool {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	gvk := ref.GetGroupVersionKind()
+	return err == nil && gv.Group == gvk.Group && kind == gvk.Kind && name == ref.Name
+}
+
+func setAnnotation(obj client.Object, key, value string) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[key] = value
+	obj.SetAnnotations(annotations)
+}
+
+func removeAnnotation(obj client.Object, key string) {
+	annotations := obj.GetAnnotations()
+	delete(annotations, key)
+	obj.SetAnnotations(annotations)
+}
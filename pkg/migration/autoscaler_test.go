@@ -0,0 +1,136 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSuspendAndResumeAutoscalers(t *testing.T) {
+	src, dst := api.NewDeploymentRef("default", "demo"), api.NewCloneSetRef("default", "demo")
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	scheme.AddKnownTypeWithName(ScaledObjectKind, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(ScaledObjectKind.GroupVersion().WithKind(ScaledObjectKind.Kind+"List"), &unstructured.UnstructuredList{})
+
+	behavior := &autoscalingv2.HorizontalPodAutoscalerBehavior{
+		ScaleDown: &autoscalingv2.HPAScalingRules{StabilizationWindowSeconds: ptr.To[int32](60)},
+	}
+	hpa := func(name, target string, behavior *autoscalingv2.HorizontalPodAutoscalerBehavior) *autoscalingv2.HorizontalPodAutoscaler {
+		return &autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: target},
+				MaxReplicas:    10,
+				Behavior:       behavior,
+			},
+		}
+	}
+	scaledObject := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "keda.sh/v1alpha1",
+		"kind":       "ScaledObject",
+		"metadata":   map[string]interface{}{"namespace": "default", "name": "demo"},
+		"spec":       map[string]interface{}{"scaleTargetRef": map[string]interface{}{"name": "demo"}},
+	}}
+
+	testCases := []struct {
+		name           string
+		target         *api.ResourceRef
+		expectedTarget string
+	}{
+		{
+			name:           "retarget once succeeded",
+			target:         &dst,
+			expectedTarget: "CloneSet",
+		},
+		{
+			name:           "keep targets once aborted",
+			expectedTarget: "Deployment",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				hpa("demo", "demo", behavior.DeepCopy()),
+				hpa("no-behavior", "demo", nil),
+				hpa("other", "other", nil),
+				scaledObject.DeepCopy(),
+			).Build()
+			getHPA := func(name string) *autoscalingv2.HorizontalPodAutoscaler {
+				obj := &autoscalingv2.HorizontalPodAutoscaler{}
+				assert.NoError(t, c.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: name}, obj))
+				return obj
+			}
+			getScaledObject := func() *unstructured.Unstructured {
+				obj := &unstructured.Unstructured{}
+				obj.SetGroupVersionKind(ScaledObjectKind)
+				assert.NoError(t, c.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "demo"}, obj))
+				return obj
+			}
+
+			assert.NoError(t, SuspendAutoscalers(c, "task", src, dst))
+			disabled := ptr.To(autoscalingv2.DisabledPolicySelect)
+			for _, name := range []string{"demo", "no-behavior"} {
+				suspended := getHPA(name)
+				assert.Contains(t, suspended.Annotations, AutoscalerAnnotation)
+				assert.Equal(t, disabled, suspended.Spec.Behavior.ScaleUp.SelectPolicy)
+				assert.Equal(t, disabled, suspended.Spec.Behavior.ScaleDown.SelectPolicy)
+			}
+			assert.Equal(t, behavior.ScaleDown.StabilizationWindowSeconds, getHPA("demo").Spec.Behavior.ScaleDown.StabilizationWindowSeconds)
+			assert.Empty(t, getHPA("other").Annotations)
+			assert.Equal(t, "true", getScaledObject().GetAnnotations()[ScaledObjectPausedAnnotation])
+
+			// suspended again on resume, without losing how to restore them
+			assert.NoError(t, SuspendAutoscalers(c, "task", src, dst))
+			// autoscalers of other tasks are left as they are
+			assert.NoError(t, ResumeAutoscalers(c, "other-task", "default", tc.target))
+			assert.Contains(t, getHPA("demo").Annotations, AutoscalerAnnotation)
+
+			assert.NoError(t, ResumeAutoscalers(c, "task", "default", tc.target))
+			resumed := getHPA("demo")
+			assert.NotContains(t, resumed.Annotations, AutoscalerAnnotation)
+			assert.Equal(t, behavior, resumed.Spec.Behavior)
+			assert.Equal(t, tc.expectedTarget, resumed.Spec.ScaleTargetRef.Kind)
+			assert.Nil(t, getHPA("no-behavior").Spec.Behavior)
+			assert.Equal(t, "other", getHPA("other").Spec.ScaleTargetRef.Name)
+
+			so := getScaledObject()
+			assert.Empty(t, so.GetAnnotations())
+			kind, _, _ := unstructured.NestedString(so.Object, "spec", "scaleTargetRef", "kind")
+			if tc.target != nil {
+				assert.Equal(t, tc.expectedTarget, kind)
+			} else {
+				assert.Empty(t, kind)
+			}
+		})
+	}
+}
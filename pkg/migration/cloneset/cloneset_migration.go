@@ -19,288 +19,296 @@ package cloneset
 import (
 	"context"
 	"fmt"
-	"sync"
-	"time"
-
-	"k8s.io/client-go/discovery"
-
-	apps "k8s.io/api/apps/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/types"
-	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/uuid"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/restmapper"
-	"k8s.io/client-go/util/workqueue"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
-	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
 	"github.com/openkruise/kruise-tools/pkg/api"
 	"github.com/openkruise/kruise-tools/pkg/migration"
 	"github.com/openkruise/kruise-tools/pkg/utils"
 )
 
-var (
-	// TODO: make it as an option
-	maxConcurrentReconciles = 5
-)
-
+// control migrates replicas between a Deployment and a CloneSet by scaling them.
 type control struct {
-	client   client.Client
-	cache    cache.Cache
-	queue    workqueue.RateLimitingInterface
-	stopChan <-chan struct{}
-
-	sync.RWMutex
-	tasks          map[types.UID]*task
-	executingTasks map[api.ResourceRef]*task
-	handledGVKs    map[schema.GroupVersionKind]struct{}
+	*migration.Controller[taskExtra]
 }
 
-type task struct {
-	ID                types.UID
-	creationTimestamp metav1.Time
-
-	src  api.ResourceRef
-	dst  api.ResourceRef
-	opts migration.Options
+// task is a migration task between a Deployment and a CloneSet.
+type task = migration.Task[taskExtra]
 
-	srcUpdatedGeneration int64
-	dstUpdatedGeneration int64
-
-	mu     sync.Mutex
-	result migration.Result
+// taskExtra is the part of a task that is recorded in migration.State.Extra.
+type taskExtra struct {
+	// replicas of src and dst when the task was submitted
+	SrcReplicas int32 `json:"srcReplicas"`
+	DstReplicas int32 `json:"dstReplicas"`
 }
 
 var _ migration.Control = &control{}
+var _ migration.Validator = &control{}
+var _ migration.AutoscaledHandover[taskExtra] = &control{}
 
-func NewControl(cfg *rest.Config, stopChan <-chan struct{}) (migration.Control, error) {
-	scheme := api.GetScheme()
-	c, err := rest.HTTPClientFor(cfg)
-	if err != nil {
-		return nil, err
-	}
-	// Get a mapper
-	dc, err := discovery.NewDiscoveryClientForConfigAndClient(cfg, c)
+func NewControl(cfg *rest.Config, stopChan <-chan struct{}, opts migration.ControlOptions) (migration.Control, error) {
+	c, informerCache, err := migration.NewClientAndCache(cfg)
 	if err != nil {
 		return nil, err
 	}
-	gr, err := restmapper.GetAPIGroupResources(dc)
+	recorder, err := migration.StartEventRecorder(cfg, stopChan)
 	if err != nil {
 		return nil, err
 	}
-	mapper := restmapper.NewDiscoveryRESTMapper(gr)
+	ctrl := newControl(c, informerCache, informerCache, recorder, opts)
+	ctrl.Start(stopChan)
+	return ctrl, nil
+}
 
-	ctrl := &control{
-		stopChan:       stopChan,
-		queue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "cloneset-migration-control"),
-		tasks:          make(map[types.UID]*task),
-		executingTasks: make(map[api.ResourceRef]*task),
-		handledGVKs:    make(map[schema.GroupVersionKind]struct{}),
-	}
+func newControl(c client.Client, reader client.Reader, informers cache.Informers, recorder record.EventRecorder, opts migration.ControlOptions) *control {
+	ctrl := &control{}
+	ctrl.Controller = migration.NewController[taskExtra](c, reader, informers, recorder, "cloneset-migration-control", opts, ctrl)
+	return ctrl
+}
 
-	if ctrl.client, err = client.New(cfg, client.Options{Scheme: scheme, Mapper: mapper}); err != nil {
-		return nil, err
+// Submit migrates replicas from a Deployment to a CloneSet, or from a CloneSet back to a Deployment.
+func (c *control) Submit(src api.ResourceRef, dst api.ResourceRef, opts migration.Options) (migration.Result, error) {
+	t, err := c.newTask(src, dst, opts)
+	if err != nil {
+		return migration.Result{}, err
 	}
+	return c.StartTask(t)
+}
 
-	if ctrl.cache, err = cache.New(cfg, cache.Options{Scheme: scheme, Mapper: mapper}); err != nil {
-		return nil, err
+func (c *control) Plan(src api.ResourceRef, dst api.ResourceRef, opts migration.Options, serverDryRun bool) (migration.Plan, error) {
+	t, err := c.newTask(src, dst, opts)
+	if err != nil {
+		return migration.Plan{}, err
 	}
 
-	go func() {
-		_ = ctrl.cache.Start(context.TODO())
-	}()
-	// Wait for the caches to sync.
-	ctrl.cache.WaitForCacheSync(context.TODO())
+	steps, err := planSteps(t)
+	if err != nil {
+		return migration.Plan{}, err
+	}
 
-	for i := 0; i < maxConcurrentReconciles; i++ {
-		// Process work items
-		go wait.Until(ctrl.worker, time.Second, stopChan)
+	if serverDryRun {
+		srcWorkload, dstWorkload, err := getWorkloads(c.Client, &t.Src, &t.Dst)
+		if err != nil {
+			return migration.Plan{}, err
+		}
+		*srcWorkload.replicas = t.Extra.SrcReplicas - *t.Opts.Replicas
+		*dstWorkload.replicas = t.Extra.DstReplicas + *t.Opts.Replicas
+		if err := c.Client.Update(context.TODO(), srcWorkload.Object, client.DryRunAll); err != nil {
+			return migration.Plan{}, fmt.Errorf("failed to scale %v: %v", t.Src, err)
+		}
+		if err := c.Client.Update(context.TODO(), dstWorkload.Object, client.DryRunAll); err != nil {
+			return migration.Plan{}, fmt.Errorf("failed to scale %v: %v", t.Dst, err)
+		}
 	}
 
-	return ctrl, nil
+	return migration.Plan{Src: t.Src, Dst: t.Dst, Options: t.Opts, Steps: steps, Warnings: t.Warnings}, nil
 }
 
-func (c *control) Submit(src api.ResourceRef, dst api.ResourceRef, opts migration.Options) (migration.Result, error) {
-	if opts.Replicas != nil && *opts.Replicas <= 0 {
-		return migration.Result{}, fmt.Errorf("invalid replicas %v", *opts.Replicas)
-	} else if src.GetGroupVersionKind() != api.DeploymentKind {
-		return migration.Result{}, fmt.Errorf("invalid src type, currently only support %v", api.DeploymentKind.String())
-	} else if dst.GetGroupVersionKind() != api.CloneSetKind {
-		return migration.Result{}, fmt.Errorf("invalid dst type, must be %v", api.CloneSetKind.String())
+// Validate runs the pre-flight validation that Submit refuses the task on errors of.
+func (c *control) Validate(src api.ResourceRef, dst api.ResourceRef, opts migration.Options) (migration.Validation, error) {
+	srcWorkload, dstWorkload, err := getWorkloads(c.Client, &src, &dst)
+	if err != nil {
+		return migration.Validation{}, err
 	}
+	return validate(c.Client, src, dst, srcWorkload, dstWorkload, opts)
+}
 
+func (c *control) newTask(src api.ResourceRef, dst api.ResourceRef, opts migration.Options) (*task, error) {
 	srcGVK := src.GetGroupVersionKind()
 	dstGVK := dst.GetGroupVersionKind()
 
-	srcDeployment, dstCloneSet, err := getDeploymentAndCloneSetObjects(c.client, &src, &dst)
-	if err != nil {
-		return migration.Result{}, err
+	if opts.Replicas != nil && *opts.Replicas <= 0 {
+		return nil, fmt.Errorf("invalid replicas %v", *opts.Replicas)
+	} else if !(srcGVK == api.DeploymentKind && dstGVK == api.CloneSetKind) && !(srcGVK == api.CloneSetKind && dstGVK == api.DeploymentKind) {
+		return nil, fmt.Errorf("invalid src and dst type, currently only support between %v and %v",
+			api.DeploymentKind.String(), api.CloneSetKind.String())
 	}
 
-	if opts.Replicas == nil {
-		opts.Replicas = srcDeployment.Spec.Replicas
-	}
-	if opts.MaxSurge == nil {
-		opts.MaxSurge = func() *int32 { var i int32 = 1; return &i }()
-	}
-	if *opts.MaxSurge <= 0 {
-		return migration.Result{}, fmt.Errorf("maxSurge must be integar more than zore")
+	if err := migration.CheckNotExecuting(c.Client, dst); err != nil {
+		return nil, err
 	}
-
-	c.Lock()
-	defer c.Unlock()
-	if _, ok := c.executingTasks[src]; ok {
-		return migration.Result{}, fmt.Errorf("already existing migration task for %v", src)
-	} else if _, ok := c.executingTasks[dst]; ok {
-		return migration.Result{}, fmt.Errorf("already existing migration task for %v", dst)
+	srcWorkload, dstWorkload, err := getWorkloads(c.Client, &src, &dst)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := c.addEventHandler(srcGVK); err != nil {
-		return migration.Result{}, err
+	validation, err := validate(c.Client, src, dst, srcWorkload, dstWorkload, opts)
+	if err != nil {
+		return nil, err
+	} else if err := validation.Err(); err != nil {
+		return nil, err
 	}
-	if err := c.addEventHandler(dstGVK); err != nil {
-		return migration.Result{}, err
+	if err := checkTraffic(c.Client, src, dst, srcWorkload, dstWorkload); err != nil {
+		return nil, err
 	}
 
-	id := uuid.NewUUID()
-	t := task{
-		ID:                id,
-		creationTimestamp: metav1.Now(),
-
-		src:  src,
-		dst:  dst,
-		opts: opts,
-
-		srcUpdatedGeneration: srcDeployment.Generation,
-		dstUpdatedGeneration: dstCloneSet.Generation,
-
-		result: migration.Result{ID: id, State: migration.MigrateExecuting},
+	if opts.Replicas == nil {
+		opts.Replicas = srcWorkload.replicas
 	}
-	c.tasks[t.ID] = &t
-	c.executingTasks[t.src] = &t
-	c.executingTasks[t.dst] = &t
-
-	// must enqueue once
-	c.queue.Add(id)
-
-	return t.result, nil
-}
-
-func (c *control) Query(ID types.UID) (migration.Result, error) {
-	t := c.getTask(ID)
-	if t == nil {
-		return migration.Result{}, fmt.Errorf("not found ID %v", ID)
+	t, err := migration.NewTask(src, dst, opts, taskExtra{SrcReplicas: *srcWorkload.replicas, DstReplicas: *dstWorkload.replicas})
+	if err != nil {
+		return nil, err
 	}
-
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	return t.result, nil
+	t.SrcUpdatedGeneration = srcWorkload.GetGeneration()
+	t.DstUpdatedGeneration = dstWorkload.GetGeneration()
+	t.Warnings = validation.Warnings
+	return t, nil
 }
 
-func (c *control) addEventHandler(gvk schema.GroupVersionKind) error {
-	if _, ok := c.handledGVKs[gvk]; !ok {
-		informer, err := c.cache.GetInformerForKind(context.Background(), gvk)
+// Resume continues the task recorded in dst. The migrated replicas are recalculated from
+// the current replicas of both workloads, since the recorded result may lag behind them.
+func (c *control) Resume(dst api.ResourceRef) (migration.Result, error) {
+	return c.ResumeTask(dst, func(t *task) (int32, int32, error) {
+		srcWorkload, dstWorkload, err := getWorkloads(c.Client, &t.Src, &t.Dst)
 		if err != nil {
-			return fmt.Errorf("failed to get informer for %v: %v", gvk, err)
+			return 0, 0, err
 		}
-		if gvk == api.DeploymentKind {
-			informer.AddEventHandler(&deploymentHandler{ctrl: c})
-		} else if gvk == api.CloneSetKind {
-			informer.AddEventHandler(&cloneSetHandler{ctrl: c})
-		} else {
-			return fmt.Errorf("unsupported gvk %v", gvk)
-		}
-		c.handledGVKs[gvk] = struct{}{}
-	}
-	return nil
+		t.SrcUpdatedGeneration = srcWorkload.GetGeneration()
+		t.DstUpdatedGeneration = dstWorkload.GetGeneration()
+		return utils.Int32Max(0, utils.Int32Min(t.Extra.SrcReplicas-*srcWorkload.replicas, *t.Opts.Replicas)),
+			utils.Int32Max(0, utils.Int32Min(*dstWorkload.replicas-t.Extra.DstReplicas, *t.Opts.Replicas)), nil
+	})
 }
 
-func (c *control) worker() {
-	for c.processNextWorkItem() {
+// Reconcile scales dst out and src in by turns, or the other way round when rolling back.
+func (c *control) Reconcile(task *task) error {
+	result := task.Result()
+	switch result.State {
+	case migration.MigrateExecuting:
+		if result.DstMigratedReplicas == *task.Opts.Replicas && result.SrcMigratedReplicas == *task.Opts.Replicas {
+			c.FinishTask(task, migration.MigrateSucceeded, "")
+			return nil
+		}
+	case migration.MigrateRollingBack:
+		if result.DstMigratedReplicas == 0 && result.SrcMigratedReplicas == 0 {
+			c.FinishTask(task, migration.MigrateRolledBack, "")
+			return nil
+		}
 	}
-}
 
-func (c *control) processNextWorkItem() bool {
-	obj, shutdown := c.queue.Get()
-	if shutdown {
-		// Stop working
-		return false
+	srcWorkload, dstWorkload, err := getWorkloads(c.Reader, &task.Src, &task.Dst)
+	if err != nil {
+		c.FinishTask(task, migration.MigrateFailed, err.Error())
+		return nil
 	}
-	defer c.queue.Done(obj)
 
-	err := c.reconcile(obj.(types.UID))
-	if err == nil {
-		c.queue.Forget(obj)
-		return true
+	if srcWorkload.GetGeneration() < task.SrcUpdatedGeneration || dstWorkload.GetGeneration() < task.DstUpdatedGeneration {
+		// cache has not synced
+		return nil
+	} else if srcWorkload.GetGeneration() != srcWorkload.observedGeneration || dstWorkload.GetGeneration() != dstWorkload.observedGeneration {
+		// workload controller has not reconciled
+		return nil
 	}
 
-	utilruntime.HandleError(fmt.Errorf("sync %q failed with %v", obj, err))
-	c.queue.AddRateLimited(obj)
+	if result.State == migration.MigrateRollingBack {
+		return c.rollback(task, result, srcWorkload, dstWorkload)
+	}
 
-	return true
-}
+	// Services and budgets may have been changed since the task was submitted
+	if err := checkTraffic(c.Client, task.Src, task.Dst, srcWorkload, dstWorkload); err != nil {
+		if _, ok := err.(trafficError); ok {
+			c.FinishTask(task, migration.MigrateFailed, err.Error())
+			return nil
+		}
+		return err
+	}
 
-func (c *control) reconcile(ID types.UID) error {
-	task := c.getTask(ID)
-	if task.result.State != migration.MigrateExecuting {
-		return nil
-	} else if task.result.DstMigratedReplicas == *task.opts.Replicas && task.result.SrcMigratedReplicas == *task.opts.Replicas {
-		c.finishTask(task, migration.MigrateSucceeded, "")
-		return nil
-	} else if task.opts.TimeoutSeconds != nil && time.Since(task.creationTimestamp.Time) > time.Duration(*task.opts.TimeoutSeconds)*time.Second {
-		c.finishTask(task, migration.MigrateFailed, fmt.Sprintf("task timeout exceeded"))
+	// dst need scale out
+	if maxScaleOut := nextScaleOut(task, result); maxScaleOut > 0 {
+		*dstWorkload.replicas += maxScaleOut
+		if err := c.Client.Update(context.TODO(), dstWorkload.Object); err != nil {
+			return err
+		}
+		task.DstUpdatedGeneration = dstWorkload.GetGeneration()
+		c.UpdateTask(task, 0, maxScaleOut)
 		return nil
 	}
 
-	srcDeployment, dstCloneSet, err := getDeploymentAndCloneSetObjects(c.cache, &task.src, &task.dst)
+	// src need scale in, as long as the available pods do not drop below MaxUnavailable
+	dstReady, err := c.readyReplicas(dstWorkload)
 	if err != nil {
-		c.finishTask(task, migration.MigrateFailed, err.Error())
-		return nil
+		return err
 	}
-
-	if srcDeployment.Generation < task.srcUpdatedGeneration || dstCloneSet.Generation < task.dstUpdatedGeneration {
-		// cache has not synced
-		return nil
-	} else if srcDeployment.Generation != srcDeployment.Status.ObservedGeneration || dstCloneSet.Generation != dstCloneSet.Status.ObservedGeneration {
-		// workload controller has not reconciled
+	dstAvailable := utils.Int32Max(0, utils.Int32Min(dstReady-task.Extra.DstReplicas, result.DstMigratedReplicas))
+	if maxScaleIn := nextScaleIn(task, result, *srcWorkload.replicas, dstAvailable); maxScaleIn > 0 {
+		*srcWorkload.replicas -= maxScaleIn
+		if err := c.Client.Update(context.TODO(), srcWorkload.Object); err != nil {
+			return err
+		}
+		task.SrcUpdatedGeneration = srcWorkload.GetGeneration()
+		c.UpdateTask(task, maxScaleIn, 0)
 		return nil
 	}
 
-	// dst need scale out
-	if task.result.DstMigratedReplicas < *task.opts.Replicas {
-		deltaSurge := *task.opts.MaxSurge - (task.result.DstMigratedReplicas - task.result.SrcMigratedReplicas)
-		deltaReplicas := *task.opts.Replicas - task.result.DstMigratedReplicas
-		maxScaleOut := utils.Int32Min(deltaSurge, deltaReplicas)
+	return nil
+}
+
+// planSteps simulates reconcile from the initial replicas of the task, assuming that
+// all pods become available right after each step.
+func planSteps(t *task) ([]migration.Step, error) {
+	return migration.PlanSteps(t.Extra.SrcReplicas, t.Extra.DstReplicas, t.Opts, func(result migration.Result) (migration.StepAction, int32) {
+		if scaleOut := nextScaleOut(t, result); scaleOut > 0 {
+			return migration.StepScaleOutDst, scaleOut
+		}
+		return migration.StepScaleInSrc, nextScaleIn(t, result, t.Extra.SrcReplicas-result.SrcMigratedReplicas, result.DstMigratedReplicas)
+	})
+}
+
+// nextScaleOut returns the replicas that dst can scale out by without exceeding MaxSurge.
+func nextScaleOut(t *task, result migration.Result) int32 {
+	deltaSurge := t.MaxSurge - (result.DstMigratedReplicas - result.SrcMigratedReplicas)
+	deltaReplicas := *t.Opts.Replicas - result.DstMigratedReplicas
+	return utils.Int32Max(0, utils.Int32Min(deltaSurge, deltaReplicas))
+}
+
+// nextScaleIn returns the replicas that src can scale in by, which have been replaced by the
+// available pods scaled out by dst, or are allowed to be unavailable by MaxUnavailable.
+func nextScaleIn(t *task, result migration.Result, srcReplicas, dstAvailable int32) int32 {
+	deltaReplicas := *t.Opts.Replicas - result.SrcMigratedReplicas
+	deltaAvailable := dstAvailable + t.MaxUnavailable - result.SrcMigratedReplicas
+	return utils.Int32Max(0, utils.Int32Min(srcReplicas, deltaReplicas, deltaAvailable))
+}
+
+// rollback is the reverse of migration, with src scaling back out and dst scaling back in
+// as src becomes available.
+func (c *control) rollback(task *task, result migration.Result, srcWorkload, dstWorkload *workload) error {
+	// src need scale back out
+	if result.SrcMigratedReplicas > 0 {
+		deltaSurge := task.MaxSurge - (result.DstMigratedReplicas - result.SrcMigratedReplicas)
+		maxScaleOut := utils.Int32Min(deltaSurge, result.SrcMigratedReplicas)
 
 		if maxScaleOut > 0 {
-			*dstCloneSet.Spec.Replicas += maxScaleOut
-			if err := c.client.Update(context.TODO(), dstCloneSet); err != nil {
+			*srcWorkload.replicas += maxScaleOut
+			if err := c.Client.Update(context.TODO(), srcWorkload.Object); err != nil {
 				return err
 			}
-			task.dstUpdatedGeneration = dstCloneSet.Generation
-			c.updateTask(task, 0, maxScaleOut)
+			task.SrcUpdatedGeneration = srcWorkload.GetGeneration()
+			c.UpdateTask(task, -maxScaleOut, 0)
 			return nil
 		}
 	}
 
-	// src need scale in
-	if task.result.SrcMigratedReplicas < *task.opts.Replicas {
-		deltaReplicas := *task.opts.Replicas - task.result.SrcMigratedReplicas
-		deltaMigrated := task.result.DstMigratedReplicas - task.result.SrcMigratedReplicas
-		maxScaleIn := utils.Int32Min(*srcDeployment.Spec.Replicas, deltaReplicas, deltaMigrated)
+	// dst need scale back in, as long as the available pods do not drop below MaxUnavailable
+	if result.DstMigratedReplicas > 0 {
+		srcReady, err := c.readyReplicas(srcWorkload)
+		if err != nil {
+			return err
+		}
+		deltaAvailable := srcReady - task.Extra.SrcReplicas + result.DstMigratedReplicas + task.MaxUnavailable
+		maxScaleIn := utils.Int32Min(*dstWorkload.replicas, result.DstMigratedReplicas, deltaAvailable)
 
-		// must wait for all pods in CloneSet available
-		if maxScaleIn > 0 && *dstCloneSet.Spec.Replicas == dstCloneSet.Status.AvailableReplicas {
-			*srcDeployment.Spec.Replicas -= maxScaleIn
-			if err := c.client.Update(context.TODO(), srcDeployment); err != nil {
+		if maxScaleIn > 0 {
+			*dstWorkload.replicas -= maxScaleIn
+			if err := c.Client.Update(context.TODO(), dstWorkload.Object); err != nil {
 				return err
 			}
-			task.srcUpdatedGeneration = srcDeployment.Generation
-			c.updateTask(task, maxScaleIn, 0)
+			task.DstUpdatedGeneration = dstWorkload.GetGeneration()
+			c.UpdateTask(task, 0, -maxScaleIn)
 			return nil
 		}
 	}
@@ -308,43 +316,87 @@ func (c *control) reconcile(ID types.UID) error {
 	return nil
 }
 
-func (c *control) getTask(ID types.UID) *task {
-	c.RLock()
-	defer c.RUnlock()
-	return c.tasks[ID]
+// validate runs the pre-flight validation of the selectors, labels and autoscalers of both workloads.
+func validate(reader client.Reader, src, dst api.ResourceRef, srcWorkload, dstWorkload *workload, opts migration.Options) (migration.Validation, error) {
+	partial := opts.Replicas != nil && srcWorkload.replicas != nil && *opts.Replicas < *srcWorkload.replicas
+	return migration.ValidateWorkloads(reader,
+		migration.Workload{Ref: src, UID: srcWorkload.GetUID(), Selector: srcWorkload.selector, TemplateLabels: srcWorkload.templateLabels},
+		migration.Workload{Ref: dst, UID: dstWorkload.GetUID(), Selector: dstWorkload.selector, TemplateLabels: dstWorkload.templateLabels},
+		partial)
+}
+
+// trafficError is returned by checkTraffic when the pods of dst would not be served like those of src.
+type trafficError string
+
+func (e trafficError) Error() string {
+	return string(e)
+}
+
+// checkTraffic returns a trafficError if a Service, PodDisruptionBudget or PodUnavailableBudget
+// selects the pods of src but not the pods of dst.
+func checkTraffic(reader client.Reader, src, dst api.ResourceRef, srcWorkload, dstWorkload *workload) error {
+	problems, err := migration.CheckTraffic(reader,
+		migration.PodTarget{Ref: src, Labels: srcWorkload.templateLabels},
+		migration.PodTarget{Ref: dst, Labels: dstWorkload.templateLabels})
+	if err != nil {
+		return err
+	} else if len(problems) > 0 {
+		return trafficError(strings.Join(problems, "; "))
+	}
+	return nil
 }
 
-func (c *control) updateTask(t *task, srcMigratedReplicas, dstMigratedReplicas int32) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	t.result.SrcMigratedReplicas += srcMigratedReplicas
-	t.result.DstMigratedReplicas += dstMigratedReplicas
+// readyReplicas returns the available replicas of the workload, limited to the pods that are
+// ready endpoints of all the Services selecting them, so that no traffic is lost when the
+// other workload is scaled in.
+func (c *control) readyReplicas(w *workload) (int32, error) {
+	services, err := migration.ServicesSelecting(c.Client, w.GetNamespace(), w.templateLabels)
+	if err != nil {
+		return 0, err
+	} else if len(services) == 0 {
+		return w.availableReplicas, nil
+	}
+
+	pods, err := listPods(c.Client, w)
+	if err != nil {
+		return 0, err
+	}
+	ready := sets.New[string]()
+	for _, pod := range pods {
+		ready.Insert(pod.Name)
+	}
+	for _, svc := range services {
+		endpoints, err := migration.ReadyEndpointPods(c.Client, svc)
+		if err != nil {
+			return 0, err
+		}
+		ready = ready.Intersection(endpoints)
+	}
+	return utils.Int32Min(w.availableReplicas, int32(ready.Len())), nil
 }
 
-func (c *control) finishTask(t *task, state migration.MigrateState, message string) {
-	func() {
-		t.mu.Lock()
-		defer t.mu.Unlock()
-		t.result.State = state
-		t.result.Message = message
-	}()
-
-	c.Lock()
-	defer c.Unlock()
-	delete(c.executingTasks, t.src)
-	delete(c.executingTasks, t.dst)
+// AutoscalerTarget returns the workload that the autoscalers suspended by the task are retargeted to once it finished
+// with result, which is the one left with all the replicas, or nil to keep their targets.
+func (c *control) AutoscalerTarget(t *task, result migration.Result) *api.ResourceRef {
+	switch {
+	case result.State == migration.MigrateSucceeded && result.SrcMigratedReplicas >= t.Extra.SrcReplicas:
+		return &t.Dst
+	case result.State == migration.MigrateRolledBack:
+		return &t.Src
+	}
+	return nil
 }
 
-func getDeploymentAndCloneSetObjects(reader client.Reader, src, dst *api.ResourceRef) (*apps.Deployment, *appsv1alpha1.CloneSet, error) {
-	srcDeployment := apps.Deployment{}
-	if err := reader.Get(context.TODO(), src.GetNamespacedName(), &srcDeployment); err != nil {
-		return nil, nil, fmt.Errorf("failed to get %v: %v", src, err)
+func getWorkloads(reader client.Reader, src, dst *api.ResourceRef) (*workload, *workload, error) {
+	srcWorkload, err := getWorkload(reader, src)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	dstCloneSet := appsv1alpha1.CloneSet{}
-	if err := reader.Get(context.TODO(), dst.GetNamespacedName(), &dstCloneSet); err != nil {
-		return nil, nil, fmt.Errorf("failed to get %v: %v", dst, err)
+	dstWorkload, err := getWorkload(reader, dst)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return &srcDeployment, &dstCloneSet, nil
+	return srcWorkload, dstWorkload, nil
 }
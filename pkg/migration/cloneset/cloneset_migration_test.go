@@ -0,0 +1,155 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloneset
+
+import (
+	"context"
+	"testing"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPlanSteps(t *testing.T) {
+	testCases := []struct {
+		name           string
+		srcReplicas    int32
+		dstReplicas    int32
+		replicas       int32
+		maxSurge       int32
+		maxUnavailable int32
+		expected       []migration.Step
+		expectErr      bool
+	}{
+		{
+			name:        "one by one",
+			srcReplicas: 2,
+			replicas:    2,
+			maxSurge:    1,
+			expected: []migration.Step{
+				{Action: migration.StepScaleOutDst, Replicas: 1, SrcReplicas: 2, DstReplicas: 1},
+				{Action: migration.StepScaleInSrc, Replicas: 1, SrcReplicas: 1, DstReplicas: 1},
+				{Action: migration.StepScaleOutDst, Replicas: 1, SrcReplicas: 1, DstReplicas: 2},
+				{Action: migration.StepScaleInSrc, Replicas: 1, SrcReplicas: 0, DstReplicas: 2},
+			},
+		},
+		{
+			name:        "part of replicas with max surge",
+			srcReplicas: 5,
+			dstReplicas: 1,
+			replicas:    3,
+			maxSurge:    2,
+			expected: []migration.Step{
+				{Action: migration.StepScaleOutDst, Replicas: 2, SrcReplicas: 5, DstReplicas: 3},
+				{Action: migration.StepScaleInSrc, Replicas: 2, SrcReplicas: 3, DstReplicas: 3},
+				{Action: migration.StepScaleOutDst, Replicas: 1, SrcReplicas: 3, DstReplicas: 4},
+				{Action: migration.StepScaleInSrc, Replicas: 1, SrcReplicas: 2, DstReplicas: 4},
+			},
+		},
+		{
+			name:           "scale in first with max unavailable",
+			srcReplicas:    3,
+			replicas:       3,
+			maxUnavailable: 2,
+			expected: []migration.Step{
+				{Action: migration.StepScaleInSrc, Replicas: 2, SrcReplicas: 1, DstReplicas: 0},
+				{Action: migration.StepScaleOutDst, Replicas: 2, SrcReplicas: 1, DstReplicas: 2},
+				{Action: migration.StepScaleInSrc, Replicas: 1, SrcReplicas: 0, DstReplicas: 2},
+				{Action: migration.StepScaleOutDst, Replicas: 1, SrcReplicas: 0, DstReplicas: 3},
+			},
+		},
+		{
+			name:        "more replicas than src",
+			srcReplicas: 1,
+			replicas:    2,
+			maxSurge:    1,
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			steps, err := planSteps(&task{
+				Opts:           migration.Options{Replicas: ptr.To(tc.replicas)},
+				MaxSurge:       tc.maxSurge,
+				MaxUnavailable: tc.maxUnavailable,
+				Extra:          taskExtra{SrcReplicas: tc.srcReplicas, DstReplicas: tc.dstReplicas},
+			})
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, steps)
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	src, dst := api.NewDeploymentRef("default", "demo"), api.NewCloneSetRef("default", "demo")
+	// half of 4 replicas have been migrated
+	c := fake.NewClientBuilder().WithScheme(api.GetScheme()).WithObjects(
+		&apps.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"},
+			Spec:       apps.DeploymentSpec{Replicas: ptr.To[int32](2)},
+			Status:     apps.DeploymentStatus{AvailableReplicas: 2},
+		},
+		&appsv1alpha1.CloneSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"},
+			Spec:       appsv1alpha1.CloneSetSpec{Replicas: ptr.To[int32](2)},
+			Status:     appsv1alpha1.CloneSetStatus{AvailableReplicas: 2},
+		},
+	).WithStatusSubresource(&apps.Deployment{}, &appsv1alpha1.CloneSet{}).Build()
+	ctrl := newControl(c, c, &informertest.FakeInformers{Scheme: api.GetScheme()}, &record.FakeRecorder{}, migration.ControlOptions{})
+
+	task, err := migration.NewTask(src, dst, migration.Options{Replicas: ptr.To[int32](4)}, taskExtra{SrcReplicas: 4})
+	assert.NoError(t, err)
+	_, err = ctrl.StartTask(task)
+	assert.NoError(t, err)
+	ctrl.UpdateTask(task, 2, 2)
+	_, err = ctrl.Rollback(task.ID)
+	assert.NoError(t, err)
+
+	deploy, cs := &apps.Deployment{}, &appsv1alpha1.CloneSet{}
+	for i := 0; i < 10 && task.Result().State == migration.MigrateRollingBack; i++ {
+		assert.NoError(t, ctrl.Reconcile(task))
+
+		// all pods become available right after each step, and src never surges over MaxSurge
+		assert.NoError(t, c.Get(context.TODO(), src.GetNamespacedName(), deploy))
+		assert.NoError(t, c.Get(context.TODO(), dst.GetNamespacedName(), cs))
+		assert.LessOrEqual(t, *deploy.Spec.Replicas+*cs.Spec.Replicas, int32(4)+task.MaxSurge)
+		deploy.Status.AvailableReplicas = *deploy.Spec.Replicas
+		cs.Status.AvailableReplicas = *cs.Spec.Replicas
+		assert.NoError(t, c.Status().Update(context.TODO(), deploy))
+		assert.NoError(t, c.Status().Update(context.TODO(), cs))
+	}
+
+	result := task.Result()
+	assert.Equal(t, migration.MigrateRolledBack, result.State)
+	assert.Equal(t, int32(0), result.SrcMigratedReplicas)
+	assert.Equal(t, int32(0), result.DstMigratedReplicas)
+	assert.Equal(t, int32(4), *deploy.Spec.Replicas)
+	assert.Equal(t, int32(0), *cs.Spec.Replicas)
+}
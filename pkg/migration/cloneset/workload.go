@@ -0,0 +1,121 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloneset
+
+import (
+	"context"
+	"fmt"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// workload is a Deployment or CloneSet, with the fields that migration reads and scales.
+// replicas points into the spec of Object, so changing it changes Object.
+type workload struct {
+	client.Object
+	replicas           *int32
+	availableReplicas  int32
+	observedGeneration int64
+	selector           *metav1.LabelSelector
+	templateLabels     map[string]string
+}
+
+func getWorkload(reader client.Reader, ref *api.ResourceRef) (*workload, error) {
+	switch ref.GetGroupVersionKind() {
+	case api.DeploymentKind:
+		d := &apps.Deployment{}
+		if err := reader.Get(context.TODO(), ref.GetNamespacedName(), d); err != nil {
+			return nil, fmt.Errorf("failed to get %v: %v", ref, err)
+		}
+		if d.Spec.Replicas == nil {
+			d.Spec.Replicas = new(int32)
+		}
+		return &workload{
+			Object:             d,
+			replicas:           d.Spec.Replicas,
+			availableReplicas:  d.Status.AvailableReplicas,
+			observedGeneration: d.Status.ObservedGeneration,
+			selector:           d.Spec.Selector,
+			templateLabels:     d.Spec.Template.Labels,
+		}, nil
+	case api.CloneSetKind:
+		cs := &appsv1alpha1.CloneSet{}
+		if err := reader.Get(context.TODO(), ref.GetNamespacedName(), cs); err != nil {
+			return nil, fmt.Errorf("failed to get %v: %v", ref, err)
+		}
+		if cs.Spec.Replicas == nil {
+			cs.Spec.Replicas = new(int32)
+		}
+		return &workload{
+			Object:             cs,
+			replicas:           cs.Spec.Replicas,
+			availableReplicas:  cs.Status.AvailableReplicas,
+			observedGeneration: cs.Status.ObservedGeneration,
+			selector:           cs.Spec.Selector,
+			templateLabels:     cs.Spec.Template.Labels,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported gvk %v", ref.GetGroupVersionKind())
+}
+
+// listPods returns the pods that are not being deleted and are controlled by the workload,
+// directly for a CloneSet and through its ReplicaSets for a Deployment.
+func listPods(reader client.Reader, w *workload) ([]*v1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(w.selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse selector of %s: %v", w.GetName(), err)
+	}
+	opts := []client.ListOption{client.InNamespace(w.GetNamespace()), client.MatchingLabelsSelector{Selector: selector}}
+
+	owners := sets.New[types.UID](w.GetUID())
+	if _, ok := w.Object.(*apps.Deployment); ok {
+		rsList := &apps.ReplicaSetList{}
+		if err := reader.List(context.TODO(), rsList, opts...); err != nil {
+			return nil, fmt.Errorf("failed to list ReplicaSets of %s: %v", w.GetName(), err)
+		}
+		owners = sets.New[types.UID]()
+		for i := range rsList.Items {
+			if metav1.IsControlledBy(&rsList.Items[i], w.Object) {
+				owners.Insert(rsList.Items[i].UID)
+			}
+		}
+	}
+
+	podList := &v1.PodList{}
+	if err := reader.List(context.TODO(), podList, opts...); err != nil {
+		return nil, fmt.Errorf("failed to list pods of %s: %v", w.GetName(), err)
+	}
+	var pods []*v1.Pod
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if owner := metav1.GetControllerOf(pod); owner != nil && owners.Has(owner.UID) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
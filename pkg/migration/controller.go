@@ -0,0 +1,569 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Task is a migration task run by a Controller. E is the progress specific to the kind of its workloads,
+// which is recorded in State.Extra.
+type Task[E any] struct {
+	ID                types.UID
+	CreationTimestamp metav1.Time
+	// StartTimestamp is when the task was submitted or resumed, which the timeout counts from.
+	StartTimestamp metav1.Time
+
+	Src  api.ResourceRef
+	Dst  api.ResourceRef
+	Opts Options
+
+	// absolute values of Opts.MaxSurge and Opts.MaxUnavailable
+	MaxSurge       int32
+	MaxUnavailable int32
+
+	SrcUpdatedGeneration int64
+	DstUpdatedGeneration int64
+
+	// Warnings of the pre-flight validation, recorded as events when the task starts.
+	Warnings []string
+
+	Extra E
+
+	mu     sync.Mutex
+	result Result
+	// abortedState is the state that the task was aborted in, which it continues in once resumed.
+	abortedState MigrateState
+}
+
+// NewTask returns an executing task that migrates opts.Replicas from src to dst.
+func NewTask[E any](src, dst api.ResourceRef, opts Options, extra E) (*Task[E], error) {
+	SetDefaultMaxSurge(&opts)
+	maxSurge, maxUnavailable, err := ResolveMaxSurgeAndMaxUnavailable(&opts)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.NewUUID()
+	return &Task[E]{
+		ID:                id,
+		CreationTimestamp: metav1.Now(),
+		StartTimestamp:    metav1.Now(),
+
+		Src:  src,
+		Dst:  dst,
+		Opts: opts,
+
+		MaxSurge:       maxSurge,
+		MaxUnavailable: maxUnavailable,
+
+		Extra: extra,
+
+		result: Result{ID: id, State: MigrateExecuting},
+	}, nil
+}
+
+// LoadTask returns the task recorded in the StateAnnotation of dst. An aborted task continues from where it stopped,
+// rolling back if it was aborted while rolling back.
+func LoadTask[E any](reader client.Reader, dst api.ResourceRef) (*Task[E], error) {
+	state, err := LoadState(reader, dst)
+	if err != nil {
+		return nil, err
+	}
+	t := &Task[E]{
+		ID:                state.Result.ID,
+		CreationTimestamp: state.CreationTimestamp,
+		StartTimestamp:    metav1.Now(),
+
+		Src:  state.Src,
+		Dst:  state.Dst,
+		Opts: state.Options,
+
+		result:       state.Result,
+		abortedState: state.AbortedState,
+	}
+	if err := json.Unmarshal(state.Extra, &t.Extra); err != nil {
+		return nil, fmt.Errorf("failed to parse migration state of %v: %v", dst, err)
+	}
+	if t.MaxSurge, t.MaxUnavailable, err = ResolveMaxSurgeAndMaxUnavailable(&t.Opts); err != nil {
+		return nil, err
+	}
+	if t.result.State == MigrateAborted {
+		// an aborted task continues from where it stopped
+		t.result.State = MigrateExecuting
+		if t.abortedState == MigrateRollingBack {
+			t.result.State = MigrateRollingBack
+		}
+		t.result.Message = ""
+	}
+	return t, nil
+}
+
+// Result returns the current result of the task.
+func (t *Task[E]) Result() Result {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.result
+}
+
+// Handover is the part of a Controller specific to the kind of the workloads, which hands the replicas
+// over from the source to the destination, or back when the task is rolling back.
+type Handover[E any] interface {
+	// Reconcile takes the next step of the running task, and finishes it once there is none.
+	// The task is requeued with rate limiting on errors.
+	Reconcile(t *Task[E]) error
+}
+
+// AutoscaledHandover is implemented by the handovers of workloads that may be scaled by autoscalers,
+// which are suspended while the tasks are running.
+type AutoscaledHandover[E any] interface {
+	Handover[E]
+	// AutoscalerTarget returns the workload that the autoscalers suspended by the task are retargeted to once it
+	// finished with result, which is the one left with all the replicas, or nil to keep their targets.
+	AutoscalerTarget(t *Task[E], result Result) *api.ResourceRef
+}
+
+// Controller runs migration tasks on a work queue, reconciling a task on the events of its workloads,
+// and implements the parts of Control shared by all kinds of workloads.
+type Controller[E any] struct {
+	// Client updates the workloads and saves the tasks.
+	Client client.Client
+	// Reader reads the workloads on reconciling, which is usually a cache.
+	Reader   client.Reader
+	Recorder *EventRecorder
+
+	informers cache.Informers
+	workers   int
+	queue     workqueue.RateLimitingInterface
+	limiter   *NamespaceLimiter
+	handover  Handover[E]
+
+	sync.RWMutex
+	tasks          map[types.UID]*Task[E]
+	executingTasks map[api.ResourceRef]*Task[E]
+	handledGVKs    map[schema.GroupVersionKind]struct{}
+}
+
+// NewClientAndCache returns a client of cfg, and a started and synced cache sharing the same mapper.
+func NewClientAndCache(cfg *rest.Config) (client.Client, cache.Cache, error) {
+	scheme := api.GetScheme()
+	hc, err := rest.HTTPClientFor(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Get a mapper
+	dc, err := discovery.NewDiscoveryClientForConfigAndClient(cfg, hc)
+	if err != nil {
+		return nil, nil, err
+	}
+	gr, err := restmapper.GetAPIGroupResources(dc)
+	if err != nil {
+		return nil, nil, err
+	}
+	mapper := restmapper.NewDiscoveryRESTMapper(gr)
+
+	c, err := client.New(cfg, client.Options{Scheme: scheme, Mapper: mapper})
+	if err != nil {
+		return nil, nil, err
+	}
+	informerCache, err := cache.New(cfg, cache.Options{Scheme: scheme, Mapper: mapper})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	go func() {
+		_ = informerCache.Start(context.TODO())
+	}()
+	// Wait for the caches to sync.
+	informerCache.WaitForCacheSync(context.TODO())
+	return c, informerCache, nil
+}
+
+// NewController returns a controller of the tasks handed over by handover, which reads the workloads from reader,
+// watches them by informers and records their events with recorder. The workers are started by Start.
+func NewController[E any](c client.Client, reader client.Reader, informers cache.Informers, recorder record.EventRecorder,
+	name string, opts ControlOptions, handover Handover[E]) *Controller[E] {
+	SetDefaultControlOptions(&opts)
+	return &Controller[E]{
+		Client:   c,
+		Reader:   reader,
+		Recorder: NewEventRecorder(c, recorder),
+
+		informers: informers,
+		workers:   opts.MaxConcurrentReconciles,
+		queue:     workqueue.NewNamedRateLimitingQueue(opts.RateLimiter, name),
+		limiter:   NewNamespaceLimiter(opts.MaxRunningTasksPerNamespace),
+		handover:  handover,
+
+		tasks:          make(map[types.UID]*Task[E]),
+		executingTasks: make(map[api.ResourceRef]*Task[E]),
+		handledGVKs:    make(map[schema.GroupVersionKind]struct{}),
+	}
+}
+
+// Start runs the workers reconciling the tasks until stopChan is closed.
+func (c *Controller[E]) Start(stopChan <-chan struct{}) {
+	for i := 0; i < c.workers; i++ {
+		// Process work items
+		go wait.Until(c.worker, time.Second, stopChan)
+	}
+	go func() {
+		<-stopChan
+		c.queue.ShutDown()
+	}()
+}
+
+// StartTask saves the task and runs it.
+func (c *Controller[E]) StartTask(t *Task[E]) (Result, error) {
+	c.Lock()
+	defer c.Unlock()
+	if err := c.registerTask(t); err != nil {
+		return Result{}, err
+	}
+	return t.Result(), nil
+}
+
+// ResumeTask continues the task recorded in dst. progress returns the migrated replicas of a running task
+// recalculated from its workloads, since the recorded result may lag behind them, and sets the updated
+// generations of the task.
+func (c *Controller[E]) ResumeTask(dst api.ResourceRef, progress func(t *Task[E]) (int32, int32, error)) (Result, error) {
+	t, err := LoadTask[E](c.Client, dst)
+	if err != nil {
+		return Result{}, err
+	}
+	if !t.result.State.IsRunning() {
+		c.Lock()
+		defer c.Unlock()
+		c.tasks[t.ID] = t
+		return t.result, nil
+	}
+
+	if t.result.SrcMigratedReplicas, t.result.DstMigratedReplicas, err = progress(t); err != nil {
+		return Result{}, err
+	}
+	return c.StartTask(t)
+}
+
+// registerTask saves the task and enqueues it on events of its workloads. c must be locked.
+func (c *Controller[E]) registerTask(t *Task[E]) error {
+	if other, ok := c.executingTasks[t.Src]; ok && other != t {
+		return fmt.Errorf("already existing migration task for %v", t.Src)
+	} else if other, ok := c.executingTasks[t.Dst]; ok && other != t {
+		return fmt.Errorf("already existing migration task for %v", t.Dst)
+	}
+
+	if err := c.addEventHandler(t.Src.GetGroupVersionKind()); err != nil {
+		return err
+	}
+	if err := c.addEventHandler(t.Dst.GetGroupVersionKind()); err != nil {
+		return err
+	}
+	_, autoscaled := c.handover.(AutoscaledHandover[E])
+	// without a saved task nothing would resume the autoscalers suspended here, unless the task was already running
+	resumeOnError := func(err error) error {
+		if !autoscaled || c.executingTasks[t.Src] == t {
+			return err
+		} else if resumeErr := ResumeAutoscalers(c.Client, t.ID, t.Src.Namespace, nil); resumeErr != nil {
+			return fmt.Errorf("%v, and failed to resume the autoscalers: %v", err, resumeErr)
+		}
+		return err
+	}
+	if autoscaled {
+		if err := SuspendAutoscalers(c.Client, t.ID, t.Src, t.Dst); err != nil {
+			return resumeOnError(err)
+		}
+	}
+	if err := c.saveTask(t); err != nil {
+		return resumeOnError(err)
+	}
+
+	c.tasks[t.ID] = t
+	c.executingTasks[t.Src] = t
+	c.executingTasks[t.Dst] = t
+
+	// must enqueue once
+	c.queue.Add(t.ID)
+
+	if t.Result().State == MigrateRollingBack {
+		c.Recorder.Eventf(t.Src, t.Dst, v1.EventTypeNormal, EventReasonRollingBack, "Migration task %v rolling back", t.ID)
+	} else {
+		c.Recorder.Eventf(t.Src, t.Dst, v1.EventTypeNormal, EventReasonStarted, "Migration task %v started from %s %s to %s %s",
+			t.ID, t.Src.Kind, t.Src.Name, t.Dst.Kind, t.Dst.Name)
+		for _, warning := range t.Warnings {
+			c.Recorder.Eventf(t.Src, t.Dst, v1.EventTypeWarning, EventReasonValidationWarning, "Migration task %v: %s", t.ID, warning)
+		}
+	}
+	return nil
+}
+
+func (c *Controller[E]) Query(ID types.UID) (Result, error) {
+	t := c.getTask(ID)
+	if t == nil {
+		return Result{}, fmt.Errorf("not found ID %v", ID)
+	}
+	return t.Result(), nil
+}
+
+// Abort stops the task, and the workloads are left as they are until it is resumed or rolled back.
+func (c *Controller[E]) Abort(ID types.UID) (Result, error) {
+	t := c.getTask(ID)
+	if t == nil {
+		return Result{}, fmt.Errorf("not found ID %v", ID)
+	}
+
+	t.mu.Lock()
+	state := t.result.State
+	if !state.IsRunning() {
+		t.mu.Unlock()
+		return Result{}, fmt.Errorf("migration task %v is %s, can not abort it", ID, state)
+	}
+	t.abortedState = state
+	t.mu.Unlock()
+
+	c.FinishTask(t, MigrateAborted, "task aborted")
+	return t.Result(), nil
+}
+
+// Rollback reverts the migrated replicas of the task, whether it is still executing or has finished.
+func (c *Controller[E]) Rollback(ID types.UID) (Result, error) {
+	t := c.getTask(ID)
+	if t == nil {
+		return Result{}, fmt.Errorf("not found ID %v", ID)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	t.mu.Lock()
+	previous := t.result
+	if previous.State == MigrateRollingBack || previous.State == MigrateRolledBack {
+		t.mu.Unlock()
+		return previous, nil
+	}
+	t.result.State = MigrateRollingBack
+	t.result.Message = ""
+	t.mu.Unlock()
+	t.StartTimestamp = metav1.Now()
+
+	if err := c.registerTask(t); err != nil {
+		t.mu.Lock()
+		t.result = previous
+		t.mu.Unlock()
+		return Result{}, err
+	}
+	return t.Result(), nil
+}
+
+// Requeue reconciles the task again after the duration, when it waits for something not watched by the controller.
+func (c *Controller[E]) Requeue(t *Task[E], after time.Duration) {
+	c.queue.AddAfter(t.ID, after)
+}
+
+// UpdateTask adds the replicas that have been scaled in by src and scaled out by dst to the result of the task.
+func (c *Controller[E]) UpdateTask(t *Task[E], srcMigratedReplicas, dstMigratedReplicas int32) {
+	func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.result.SrcMigratedReplicas += srcMigratedReplicas
+		t.result.DstMigratedReplicas += dstMigratedReplicas
+	}()
+
+	if err := c.saveTask(t); err != nil {
+		utilruntime.HandleError(err)
+	}
+
+	result := t.Result()
+	c.Recorder.Eventf(t.Src, t.Dst, v1.EventTypeNormal, EventReasonProgressed, "Migration task %v progressed: %s %s scaled in %d, %s %s scaled out %d",
+		t.ID, t.Src.Kind, t.Src.Name, result.SrcMigratedReplicas, t.Dst.Kind, t.Dst.Name, result.DstMigratedReplicas)
+}
+
+// FinishTask stops running the task with the state.
+func (c *Controller[E]) FinishTask(t *Task[E], state MigrateState, message string) {
+	func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.result.State = state
+		t.result.Message = message
+	}()
+
+	if err := c.saveTask(t); err != nil {
+		utilruntime.HandleError(err)
+	}
+	result := t.Result()
+	c.Recorder.RecordFinished(t.Src, t.Dst, result)
+	if handover, ok := c.handover.(AutoscaledHandover[E]); ok {
+		if err := ResumeAutoscalers(c.Client, t.ID, t.Src.Namespace, handover.AutoscalerTarget(t, result)); err != nil {
+			utilruntime.HandleError(err)
+		}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	delete(c.executingTasks, t.Src)
+	delete(c.executingTasks, t.Dst)
+	c.limiter.Release(t.Src.Namespace, t.ID)
+}
+
+func (c *Controller[E]) saveTask(t *Task[E]) error {
+	extra, err := json.Marshal(t.Extra)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	result, abortedState := t.result, t.abortedState
+	t.mu.Unlock()
+	state := &State{
+		Src:               t.Src,
+		Dst:               t.Dst,
+		Options:           t.Opts,
+		Result:            result,
+		CreationTimestamp: t.CreationTimestamp,
+		Extra:             extra,
+	}
+	if result.State == MigrateAborted {
+		state.AbortedState = abortedState
+	}
+	return SaveState(c.Client, state)
+}
+
+func (c *Controller[E]) getTask(ID types.UID) *Task[E] {
+	c.RLock()
+	defer c.RUnlock()
+	return c.tasks[ID]
+}
+
+func (c *Controller[E]) addEventHandler(gvk schema.GroupVersionKind) error {
+	if _, ok := c.handledGVKs[gvk]; !ok {
+		informer, err := c.informers.GetInformerForKind(context.Background(), gvk)
+		if err != nil {
+			return fmt.Errorf("failed to get informer for %v: %v", gvk, err)
+		}
+		if _, err := informer.AddEventHandler(&workloadHandler[E]{ctrl: c, gvk: gvk}); err != nil {
+			return fmt.Errorf("failed to add event handler for %v: %v", gvk, err)
+		}
+		c.handledGVKs[gvk] = struct{}{}
+	}
+	return nil
+}
+
+func (c *Controller[E]) worker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *Controller[E]) processNextWorkItem() bool {
+	obj, shutdown := c.queue.Get()
+	if shutdown {
+		// Stop working
+		return false
+	}
+	defer c.queue.Done(obj)
+
+	err := c.reconcile(obj.(types.UID))
+	if err == nil {
+		c.queue.Forget(obj)
+		return true
+	}
+
+	utilruntime.HandleError(fmt.Errorf("sync %q failed with %v", obj, err))
+	c.queue.AddRateLimited(obj)
+
+	return true
+}
+
+func (c *Controller[E]) reconcile(ID types.UID) error {
+	t := c.getTask(ID)
+	if t == nil || !t.Result().State.IsRunning() {
+		return nil
+	}
+	if !c.limiter.Admit(t.Src.Namespace, t.ID) {
+		// too many tasks are running in the namespace, the timeout counts from when it starts running
+		t.StartTimestamp = metav1.Now()
+		c.queue.AddAfter(ID, time.Second)
+		return nil
+	}
+	if t.Opts.TimeoutSeconds != nil && time.Since(t.StartTimestamp.Time) > time.Duration(*t.Opts.TimeoutSeconds)*time.Second {
+		c.FinishTask(t, MigrateFailed, "task timeout exceeded")
+		return nil
+	}
+	return c.handover.Reconcile(t)
+}
+
+// workloadHandler enqueues the executing task of a workload of the gvk on its events.
+type workloadHandler[E any] struct {
+	ctrl *Controller[E]
+	gvk  schema.GroupVersionKind
+}
+
+var _ toolscache.ResourceEventHandler = &workloadHandler[struct{}]{}
+
+func (h *workloadHandler[E]) OnAdd(obj interface{}, isInInitialList bool) {
+	h.enqueue(obj)
+}
+
+func (h *workloadHandler[E]) OnUpdate(oldObj interface{}, newObj interface{}) {
+	h.enqueue(newObj)
+}
+
+func (h *workloadHandler[E]) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	h.enqueue(obj)
+}
+
+func (h *workloadHandler[E]) enqueue(obj interface{}) {
+	o, ok := obj.(client.Object)
+	if !ok {
+		return
+	}
+	ref := api.ResourceRef{
+		APIVersion: h.gvk.GroupVersion().String(),
+		Kind:       h.gvk.Kind,
+		Namespace:  o.GetNamespace(),
+		Name:       o.GetName(),
+	}
+
+	h.ctrl.RLock()
+	defer h.ctrl.RUnlock()
+	if t, ok := h.ctrl.executingTasks[ref]; ok {
+		h.ctrl.queue.Add(t.ID)
+	}
+}
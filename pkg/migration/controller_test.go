@@ -0,0 +1,143 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"testing"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+
+	apps "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type testExtra struct {
+	Step int32 `json:"step"`
+}
+
+// testHandover migrates one replica on each reconcile.
+type testHandover struct {
+	ctrl *Controller[testExtra]
+}
+
+func (h *testHandover) Reconcile(t *Task[testExtra]) error {
+	if result := t.Result(); result.SrcMigratedReplicas < *t.Opts.Replicas {
+		t.Extra.Step++
+		h.ctrl.UpdateTask(t, 1, 1)
+	} else {
+		h.ctrl.FinishTask(t, MigrateSucceeded, "")
+	}
+	return nil
+}
+
+func TestController(t *testing.T) {
+	src, dst := api.NewDeploymentRef("default", "demo"), api.NewCloneSetRef("default", "demo")
+	c := fake.NewClientBuilder().WithScheme(api.GetScheme()).WithObjects(
+		&apps.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"}},
+		&appsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"}},
+	).Build()
+	handover := &testHandover{}
+	ctrl := NewController[testExtra](c, c, &informertest.FakeInformers{Scheme: api.GetScheme()}, &record.FakeRecorder{}, "test", ControlOptions{}, handover)
+	handover.ctrl = ctrl
+
+	task, err := NewTask(src, dst, Options{Replicas: ptr.To[int32](2)}, testExtra{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), task.MaxSurge)
+	_, err = ctrl.StartTask(task)
+	assert.NoError(t, err)
+
+	other, err := NewTask(src, api.NewCloneSetRef("default", "other"), Options{Replicas: ptr.To[int32](1)}, testExtra{})
+	assert.NoError(t, err)
+	_, err = ctrl.StartTask(other)
+	assert.EqualError(t, err, "already existing migration task for {apps/v1 Deployment default demo}")
+
+	assert.NoError(t, ctrl.reconcile(task.ID))
+	result, err := ctrl.Abort(task.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, Result{ID: task.ID, State: MigrateAborted, Message: "task aborted", SrcMigratedReplicas: 1, DstMigratedReplicas: 1}, result)
+	_, err = ctrl.Abort(task.ID)
+	assert.Error(t, err)
+
+	// the aborted task is resumed from its saved state
+	resumed, err := LoadTask[testExtra](c, dst)
+	assert.NoError(t, err)
+	assert.Equal(t, MigrateExecuting, resumed.Result().State)
+	assert.Equal(t, testExtra{Step: 1}, resumed.Extra)
+
+	result, err = ctrl.Rollback(task.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, MigrateRollingBack, result.State)
+	result, err = ctrl.Rollback(task.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, MigrateRollingBack, result.State)
+
+	state, err := LoadState(c, dst)
+	assert.NoError(t, err)
+	assert.Equal(t, MigrateRollingBack, state.Result.State)
+
+	// a task aborted while rolling back keeps rolling back once resumed
+	result, err = ctrl.Abort(task.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, MigrateAborted, result.State)
+	state, err = LoadState(c, dst)
+	assert.NoError(t, err)
+	assert.Equal(t, MigrateRollingBack, state.AbortedState)
+	resumed, err = LoadTask[testExtra](c, dst)
+	assert.NoError(t, err)
+	assert.Equal(t, MigrateRollingBack, resumed.Result().State)
+}
+
+// autoscaledHandover is a testHandover whose workloads may be scaled by autoscalers.
+type autoscaledHandover struct {
+	testHandover
+}
+
+func (h *autoscaledHandover) AutoscalerTarget(t *Task[testExtra], result Result) *api.ResourceRef {
+	return nil
+}
+
+func TestAutoscalersResumedOnSaveFailure(t *testing.T) {
+	src, dst := api.NewDeploymentRef("default", "demo"), api.NewCloneSetRef("default", "demo")
+	// the task can not be saved since dst does not exist
+	c := fake.NewClientBuilder().WithScheme(api.GetScheme()).WithObjects(
+		&apps.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"}},
+		&autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1", Kind: "Deployment", Name: "demo"}}},
+	).Build()
+	handover := &autoscaledHandover{}
+	ctrl := NewController[testExtra](c, c, &informertest.FakeInformers{Scheme: api.GetScheme()}, &record.FakeRecorder{}, "test", ControlOptions{}, handover)
+	handover.ctrl = ctrl
+
+	task, err := NewTask(src, dst, Options{Replicas: ptr.To[int32](2)}, testExtra{})
+	assert.NoError(t, err)
+	_, err = ctrl.StartTask(task)
+	assert.Error(t, err)
+
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+	assert.NoError(t, c.Get(context.TODO(), src.GetNamespacedName(), hpa))
+	assert.NotContains(t, hpa.Annotations, AutoscalerAnnotation)
+	assert.Nil(t, hpa.Spec.Behavior)
+}
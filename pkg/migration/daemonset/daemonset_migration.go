@@ -0,0 +1,600 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemonset
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/kubectl/pkg/util/podutils"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+	"github.com/openkruise/kruise-tools/pkg/utils"
+)
+
+// control migrates a DaemonSet to an Advanced DaemonSet node by node. The nodes are labeled as pending
+// before the destination is created, the source only runs on nodes labeled as pending or migrating,
+// and the destination on all the nodes but the pending ones, so each batch of nodes goes through:
+//  1. the nodes are labeled as migrating, and the destination starts next to the source;
+//  2. once the destination pods are available, the nodes are labeled as migrated and the
+//     source pods on them are deleted.
+//
+// If MaxSurge is zero, the nodes are labeled as migrated directly instead, so that the source
+// pods are deleted before the destination starts, and the next batch waits for the destination
+// pods to be available.
+//
+// Once the source runs on no node any more, it is retired and the node labels are removed, so that
+// the destination runs on every node, including those joining later, without changing its pod template.
+//
+// Replicas, MaxSurge and MaxUnavailable in migration.Options count nodes.
+type control struct {
+	*migration.Controller[taskExtra]
+}
+
+// task is a migration task from a DaemonSet to an Advanced DaemonSet.
+type task = migration.Task[taskExtra]
+
+// taskExtra is the part of a task that is recorded in migration.State.Extra.
+type taskExtra struct {
+	// Nodes to hand over, in order
+	Nodes []string `json:"nodes"`
+	// SrcUpdateStrategy is restored once the source runs on no node any more.
+	SrcUpdateStrategy apps.DaemonSetUpdateStrategy `json:"srcUpdateStrategy"`
+}
+
+var _ migration.Control = &control{}
+
+func NewControl(cfg *rest.Config, stopChan <-chan struct{}, opts migration.ControlOptions) (migration.Control, error) {
+	c, informerCache, err := migration.NewClientAndCache(cfg)
+	if err != nil {
+		return nil, err
+	}
+	recorder, err := migration.StartEventRecorder(cfg, stopChan)
+	if err != nil {
+		return nil, err
+	}
+	ctrl := newControl(c, informerCache, informerCache, recorder, opts)
+	ctrl.Start(stopChan)
+	return ctrl, nil
+}
+
+func newControl(c client.Client, reader client.Reader, informers cache.Informers, recorder record.EventRecorder, opts migration.ControlOptions) *control {
+	ctrl := &control{}
+	ctrl.Controller = migration.NewController[taskExtra](c, reader, informers, recorder, "daemonset-migration-control", opts, ctrl)
+	return ctrl
+}
+
+func (c *control) Submit(src api.ResourceRef, dst api.ResourceRef, opts migration.Options) (migration.Result, error) {
+	t, err := c.newTask(src, dst, opts)
+	if err != nil {
+		return migration.Result{}, err
+	}
+	return c.StartTask(t)
+}
+
+func (c *control) Plan(src api.ResourceRef, dst api.ResourceRef, opts migration.Options, serverDryRun bool) (migration.Plan, error) {
+	t, err := c.newTask(src, dst, opts)
+	if err != nil {
+		return migration.Plan{}, err
+	}
+	srcDaemonSet, dstDaemonSet, err := getDaemonSetObjects(c.Client, &t.Src, &t.Dst)
+	if err != nil {
+		return migration.Plan{}, err
+	}
+
+	steps, err := planSteps(t, srcDaemonSet.Status.DesiredNumberScheduled, dstDaemonSet.Status.DesiredNumberScheduled)
+	if err != nil {
+		return migration.Plan{}, err
+	}
+
+	if serverDryRun {
+		AddNodeSelectorRequirement(&srcDaemonSet.Spec.Template, SrcNodeSelectorRequirement(t.Src))
+		srcDaemonSet.Spec.UpdateStrategy = apps.DaemonSetUpdateStrategy{Type: apps.OnDeleteDaemonSetStrategyType}
+		if err := c.Client.Update(context.TODO(), srcDaemonSet, client.DryRunAll); err != nil {
+			return migration.Plan{}, fmt.Errorf("failed to update %v: %v", t.Src, err)
+		}
+	}
+
+	return migration.Plan{Src: t.Src, Dst: t.Dst, Options: t.Opts, Steps: steps}, nil
+}
+
+func (c *control) newTask(src api.ResourceRef, dst api.ResourceRef, opts migration.Options) (*task, error) {
+	if opts.Replicas != nil && *opts.Replicas <= 0 {
+		return nil, fmt.Errorf("invalid replicas %v", *opts.Replicas)
+	} else if src.GetGroupVersionKind() != api.DaemonSetKind {
+		return nil, fmt.Errorf("invalid src type, currently only support %v", api.DaemonSetKind.String())
+	} else if dst.GetGroupVersionKind() != api.AdvancedDaemonSetKind {
+		return nil, fmt.Errorf("invalid dst type, must be %v", api.AdvancedDaemonSetKind.String())
+	}
+
+	if err := migration.CheckNotExecuting(c.Client, dst); err != nil {
+		return nil, err
+	}
+	srcDaemonSet, dstDaemonSet, err := getDaemonSetObjects(c.Client, &src, &dst)
+	if err != nil {
+		return nil, err
+	}
+	if !HasNodeSelectorRequirement(&dstDaemonSet.Spec.Template, DstNodeSelectorRequirement(src)) {
+		return nil, fmt.Errorf("%v must not run on nodes labeled with %s=%s, create it with --create", dst, NodeLabelKey(src), NodePending)
+	}
+
+	nodes, err := c.getNodesToMigrate(srcDaemonSet, opts.NodeSelector)
+	if err != nil {
+		return nil, err
+	} else if len(nodes) == 0 {
+		return nil, fmt.Errorf("no node to migrate for %v", src)
+	}
+
+	if opts.Replicas == nil {
+		opts.Replicas = func() *int32 { i := int32(len(nodes)); return &i }()
+	}
+	if *opts.Replicas > int32(len(nodes)) {
+		return nil, fmt.Errorf("replicas %v is more than %v nodes to migrate", *opts.Replicas, len(nodes))
+	}
+
+	t, err := migration.NewTask(src, dst, opts, taskExtra{Nodes: nodes[:*opts.Replicas], SrcUpdateStrategy: srcDaemonSet.Spec.UpdateStrategy})
+	if err != nil {
+		return nil, err
+	}
+	t.SrcUpdatedGeneration = srcDaemonSet.Generation
+	t.DstUpdatedGeneration = dstDaemonSet.Generation
+	return t, nil
+}
+
+// Resume continues the task recorded in dst. The migrated nodes are recalculated from
+// the node labels, since the recorded result may lag behind them.
+func (c *control) Resume(dst api.ResourceRef) (migration.Result, error) {
+	return c.ResumeTask(dst, func(t *task) (int32, int32, error) {
+		srcDaemonSet, dstDaemonSet, err := getDaemonSetObjects(c.Client, &t.Src, &t.Dst)
+		if err != nil {
+			return 0, 0, err
+		}
+		t.SrcUpdatedGeneration = srcDaemonSet.Generation
+		t.DstUpdatedGeneration = dstDaemonSet.Generation
+		if HasNodeSelectorRequirement(&srcDaemonSet.Spec.Template, RetiredNodeSelectorRequirement(t.Src)) {
+			// the node labels may have been removed already
+			n := int32(len(t.Extra.Nodes))
+			return n, n, nil
+		}
+		return c.getLabeledNodes(t)
+	})
+}
+
+// Reconcile hands the next batch of nodes over to dst, or back to src when rolling back.
+func (c *control) Reconcile(task *task) error {
+	result := task.Result()
+	srcDaemonSet, dstDaemonSet, err := getDaemonSetObjects(c.Reader, &task.Src, &task.Dst)
+	if err != nil {
+		c.FinishTask(task, migration.MigrateFailed, err.Error())
+		return nil
+	}
+
+	if srcDaemonSet.Generation < task.SrcUpdatedGeneration || dstDaemonSet.Generation < task.DstUpdatedGeneration {
+		// cache has not synced
+		return nil
+	} else if srcDaemonSet.Generation != srcDaemonSet.Status.ObservedGeneration || dstDaemonSet.Generation != dstDaemonSet.Status.ObservedGeneration {
+		// workload controller has not reconciled
+		return nil
+	}
+
+	if result.State == migration.MigrateRollingBack {
+		return c.rollback(task, result, srcDaemonSet, dstDaemonSet)
+	}
+
+	// keep src on the nodes that are not handed over yet, without rolling the pods that still run on them.
+	// Nodes that joined since dst was created are left to src first, as the others are.
+	srcRequirement := SrcNodeSelectorRequirement(task.Src)
+	if !HasNodeSelectorRequirement(&srcDaemonSet.Spec.Template, srcRequirement) &&
+		!HasNodeSelectorRequirement(&srcDaemonSet.Spec.Template, RetiredNodeSelectorRequirement(task.Src)) {
+		if err := LabelPendingNodes(c.Client, task.Src); err != nil {
+			return err
+		}
+		AddNodeSelectorRequirement(&srcDaemonSet.Spec.Template, srcRequirement)
+		srcDaemonSet.Spec.UpdateStrategy = apps.DaemonSetUpdateStrategy{Type: apps.OnDeleteDaemonSetStrategyType}
+		if err := c.Client.Update(context.TODO(), srcDaemonSet); err != nil {
+			return err
+		}
+		task.SrcUpdatedGeneration = srcDaemonSet.Generation
+		return nil
+	}
+
+	srcMigrated := result.SrcMigratedReplicas
+	dstMigrated := result.DstMigratedReplicas
+
+	// dst has been started on a batch of nodes, hand them over once dst pods are available
+	if dstMigrated > srcMigrated {
+		nodes := task.Extra.Nodes[srcMigrated:dstMigrated]
+		if available, err := c.allPodsAvailable(dstDaemonSet, dstDaemonSet.Spec.Selector, dstDaemonSet.Spec.MinReadySeconds, nodes); err != nil {
+			return err
+		} else if !available {
+			c.Requeue(task, time.Second)
+			return nil
+		}
+
+		if err := c.labelNodes(task, nodes, NodeMigrated); err != nil {
+			return err
+		}
+		c.UpdateTask(task, int32(len(nodes)), 0)
+		return nil
+	}
+
+	// wait for src pods on migrated nodes to be deleted before the next batch
+	if srcMigrated > 0 {
+		pods, err := c.getPodsOnNodes(srcDaemonSet, srcDaemonSet.Spec.Selector, task.Extra.Nodes[:srcMigrated])
+		if err != nil {
+			return err
+		} else if len(pods) > 0 {
+			c.Requeue(task, time.Second)
+			return nil
+		}
+	}
+
+	// without surge, dst pods on migrated nodes start after src pods are deleted
+	if task.MaxSurge == 0 && srcMigrated > 0 {
+		if available, err := c.allPodsAvailable(dstDaemonSet, dstDaemonSet.Spec.Selector, dstDaemonSet.Spec.MinReadySeconds, task.Extra.Nodes[:srcMigrated]); err != nil {
+			return err
+		} else if !available {
+			c.Requeue(task, time.Second)
+			return nil
+		}
+	}
+
+	if batch := nextBatch(task, result); batch > 0 {
+		if task.MaxSurge == 0 {
+			if err := c.labelNodes(task, task.Extra.Nodes[dstMigrated:dstMigrated+batch], NodeMigrated); err != nil {
+				return err
+			}
+			c.UpdateTask(task, batch, batch)
+			return nil
+		}
+
+		if err := c.labelNodes(task, task.Extra.Nodes[dstMigrated:dstMigrated+batch], NodeMigrating); err != nil {
+			return err
+		}
+		c.UpdateTask(task, 0, batch)
+		return nil
+	}
+
+	// src runs on no node any more, so every node has been handed over. Otherwise nodes that
+	// are left to src keep the labels.
+	if srcDaemonSet.Status.DesiredNumberScheduled == 0 && srcDaemonSet.Status.CurrentNumberScheduled == 0 {
+		if retired, err := c.retire(task, srcDaemonSet); err != nil || !retired {
+			return err
+		}
+	}
+
+	c.FinishTask(task, migration.MigrateSucceeded, "")
+	return nil
+}
+
+// planSteps simulates reconcile from the nodes that src and dst run on, assuming that
+// all pods become available right after each step.
+func planSteps(t *task, srcNodes, dstNodes int32) ([]migration.Step, error) {
+	return migration.PlanSteps(srcNodes, dstNodes, t.Opts, func(result migration.Result) (migration.StepAction, int32) {
+		if result.DstMigratedReplicas > result.SrcMigratedReplicas {
+			return migration.StepScaleInSrc, result.DstMigratedReplicas - result.SrcMigratedReplicas
+		} else if result.SrcMigratedReplicas > result.DstMigratedReplicas {
+			return migration.StepScaleOutDst, result.SrcMigratedReplicas - result.DstMigratedReplicas
+		} else if t.MaxSurge == 0 {
+			return migration.StepScaleInSrc, nextBatch(t, result)
+		}
+		return migration.StepScaleOutDst, nextBatch(t, result)
+	})
+}
+
+// nextBatch returns the number of nodes to hand over in the next step, which is limited by MaxSurge,
+// or by MaxUnavailable if there is no surge.
+func nextBatch(t *task, result migration.Result) int32 {
+	size := t.MaxSurge
+	if size == 0 {
+		size = t.MaxUnavailable
+	}
+	return utils.Int32Max(0, utils.Int32Min(size, *t.Opts.Replicas-result.DstMigratedReplicas))
+}
+
+// retire keeps src away from all nodes, including those that join later, and leaves the nodes to dst:
+//  1. the node affinity of src is replaced to match no node, and its original update strategy is restored;
+//  2. the node labels are removed, which the node affinity of dst already holds without, so that its
+//     pod template is left as it is and its pods are not rolled again.
+//
+// It returns whether all of them are done.
+func (c *control) retire(task *task, srcDaemonSet *apps.DaemonSet) (bool, error) {
+	retiredRequirement := RetiredNodeSelectorRequirement(task.Src)
+	if !HasNodeSelectorRequirement(&srcDaemonSet.Spec.Template, retiredRequirement) ||
+		!equality.Semantic.DeepEqual(srcDaemonSet.Spec.UpdateStrategy, task.Extra.SrcUpdateStrategy) {
+		RemoveNodeSelectorRequirement(&srcDaemonSet.Spec.Template, SrcNodeSelectorRequirement(task.Src))
+		AddNodeSelectorRequirement(&srcDaemonSet.Spec.Template, retiredRequirement)
+		srcDaemonSet.Spec.UpdateStrategy = task.Extra.SrcUpdateStrategy
+		if err := c.Client.Update(context.TODO(), srcDaemonSet); err != nil {
+			return false, err
+		}
+		task.SrcUpdatedGeneration = srcDaemonSet.Generation
+		return false, nil
+	}
+
+	// nodes handed over by earlier partial tasks of src are labeled as well
+	nodeList := &v1.NodeList{}
+	if err := c.Client.List(context.TODO(), nodeList, client.HasLabels{NodeLabelKey(task.Src)}); err != nil {
+		return false, fmt.Errorf("failed to list nodes: %v", err)
+	}
+	nodes := make([]string, 0, len(nodeList.Items))
+	for i := range nodeList.Items {
+		nodes = append(nodes, nodeList.Items[i].Name)
+	}
+	if err := c.labelNodes(task, nodes, ""); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// restore is the reverse of retire, it labels the nodes of the task as migrated again and the other nodes
+// as pending, and restores the node affinity of src, so that the nodes can be given back to src.
+func (c *control) restore(task *task, srcDaemonSet *apps.DaemonSet) error {
+	if err := c.labelNodes(task, task.Extra.Nodes, NodeMigrated); err != nil {
+		return err
+	} else if err := LabelPendingNodes(c.Client, task.Src); err != nil {
+		return err
+	}
+
+	RemoveNodeSelectorRequirement(&srcDaemonSet.Spec.Template, RetiredNodeSelectorRequirement(task.Src))
+	AddNodeSelectorRequirement(&srcDaemonSet.Spec.Template, SrcNodeSelectorRequirement(task.Src))
+	srcDaemonSet.Spec.UpdateStrategy = apps.DaemonSetUpdateStrategy{Type: apps.OnDeleteDaemonSetStrategyType}
+	if err := c.Client.Update(context.TODO(), srcDaemonSet); err != nil {
+		return err
+	}
+	task.SrcUpdatedGeneration = srcDaemonSet.Generation
+	return nil
+}
+
+// rollback hands the nodes back from the last migrated one, which is the reverse of migration:
+//  1. a batch of migrated nodes is labeled as migrating again, and the source starts next to the destination;
+//  2. once the source pods are available, the nodes are labeled as pending and the destination pods on them
+//     are deleted.
+//
+// If MaxSurge is zero, the batch is labeled as pending directly instead, and the next batch waits for the
+// source pods to be available. At last the node affinity and update strategy of the source are restored,
+// while the nodes stay labeled as pending to keep the destination away from them.
+func (c *control) rollback(task *task, result migration.Result, srcDaemonSet *apps.DaemonSet, dstDaemonSet *appsv1alpha1.DaemonSet) error {
+	// src has been retired after the task succeeded
+	if HasNodeSelectorRequirement(&srcDaemonSet.Spec.Template, RetiredNodeSelectorRequirement(task.Src)) {
+		return c.restore(task, srcDaemonSet)
+	}
+
+	srcMigrated := result.SrcMigratedReplicas
+	dstMigrated := result.DstMigratedReplicas
+
+	// src has been started on a batch of nodes, give them back once src pods are available
+	if dstMigrated > srcMigrated {
+		nodes := task.Extra.Nodes[srcMigrated:dstMigrated]
+		if available, err := c.allPodsAvailable(srcDaemonSet, srcDaemonSet.Spec.Selector, srcDaemonSet.Spec.MinReadySeconds, nodes); err != nil {
+			return err
+		} else if !available {
+			c.Requeue(task, time.Second)
+			return nil
+		}
+
+		if err := c.labelNodes(task, nodes, NodePending); err != nil {
+			return err
+		}
+		c.UpdateTask(task, 0, -int32(len(nodes)))
+		return nil
+	}
+
+	// wait for dst pods on given back nodes to be deleted before the next batch
+	pods, err := c.getPodsOnNodes(dstDaemonSet, dstDaemonSet.Spec.Selector, task.Extra.Nodes[dstMigrated:])
+	if err != nil {
+		return err
+	} else if len(pods) > 0 {
+		c.Requeue(task, time.Second)
+		return nil
+	}
+
+	// without surge, src pods on given back nodes start after dst pods are deleted
+	if task.MaxSurge == 0 {
+		if available, err := c.allPodsAvailable(srcDaemonSet, srcDaemonSet.Spec.Selector, srcDaemonSet.Spec.MinReadySeconds, task.Extra.Nodes[srcMigrated:]); err != nil {
+			return err
+		} else if !available {
+			c.Requeue(task, time.Second)
+			return nil
+		}
+	}
+
+	if srcMigrated > 0 {
+		if task.MaxSurge == 0 {
+			batch := utils.Int32Min(task.MaxUnavailable, srcMigrated)
+			if err := c.labelNodes(task, task.Extra.Nodes[srcMigrated-batch:srcMigrated], NodePending); err != nil {
+				return err
+			}
+			c.UpdateTask(task, -batch, -batch)
+			return nil
+		}
+
+		batch := utils.Int32Min(task.MaxSurge, srcMigrated)
+		if err := c.labelNodes(task, task.Extra.Nodes[srcMigrated-batch:srcMigrated], NodeMigrating); err != nil {
+			return err
+		}
+		c.UpdateTask(task, -batch, 0)
+		return nil
+	}
+
+	// src runs on all the nodes again, so it does not need to be kept away from any of them
+	srcRequirement := SrcNodeSelectorRequirement(task.Src)
+	if HasNodeSelectorRequirement(&srcDaemonSet.Spec.Template, srcRequirement) ||
+		!equality.Semantic.DeepEqual(srcDaemonSet.Spec.UpdateStrategy, task.Extra.SrcUpdateStrategy) {
+		RemoveNodeSelectorRequirement(&srcDaemonSet.Spec.Template, srcRequirement)
+		srcDaemonSet.Spec.UpdateStrategy = task.Extra.SrcUpdateStrategy
+		if err := c.Client.Update(context.TODO(), srcDaemonSet); err != nil {
+			return err
+		}
+		task.SrcUpdatedGeneration = srcDaemonSet.Generation
+		return nil
+	}
+
+	c.FinishTask(task, migration.MigrateRolledBack, "")
+	return nil
+}
+
+// getNodesToMigrate returns the sorted names of nodes that run src pods and match the node selector.
+func (c *control) getNodesToMigrate(src *apps.DaemonSet, nodeSelector *metav1.LabelSelector) ([]string, error) {
+	pods, err := c.getPodsOnNodes(src, src.Spec.Selector, nil)
+	if err != nil {
+		return nil, err
+	}
+	nodes := sets.New[string]()
+	for node := range pods {
+		nodes.Insert(node)
+	}
+
+	if nodeSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(nodeSelector)
+		if err != nil {
+			return nil, err
+		}
+		nodeList := &v1.NodeList{}
+		if err := c.Client.List(context.TODO(), nodeList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, err
+		}
+		selected := sets.New[string]()
+		for i := range nodeList.Items {
+			selected.Insert(nodeList.Items[i].Name)
+		}
+		nodes = nodes.Intersection(selected)
+	}
+
+	return sets.List(nodes), nil
+}
+
+// allPodsAvailable returns whether owner has an available pod on each of the nodes.
+func (c *control) allPodsAvailable(owner client.Object, labelSelector *metav1.LabelSelector, minReadySeconds int32, nodes []string) (bool, error) {
+	pods, err := c.getPodsOnNodes(owner, labelSelector, nodes)
+	if err != nil {
+		return false, err
+	}
+	for _, node := range nodes {
+		pod, ok := pods[node]
+		if !ok || !podutils.IsPodAvailable(pod, minReadySeconds, metav1.Now()) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// getPodsOnNodes returns the active pods controlled by owner, keyed by node name.
+// All nodes are considered if nodes is nil.
+func (c *control) getPodsOnNodes(owner client.Object, labelSelector *metav1.LabelSelector, nodes []string) (map[string]*v1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	} else if selector.Empty() {
+		selector = labels.Nothing()
+	}
+
+	podList := &v1.PodList{}
+	if err := c.Client.List(context.TODO(), podList, client.InNamespace(owner.GetNamespace()), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	nodeSet := sets.New(nodes...)
+	pods := make(map[string]*v1.Pod)
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if !metav1.IsControlledBy(pod, owner) || pod.Spec.NodeName == "" {
+			continue
+		} else if nodes != nil && !nodeSet.Has(pod.Spec.NodeName) {
+			continue
+		}
+		pods[pod.Spec.NodeName] = pod
+	}
+	return pods, nil
+}
+
+// labelNodes sets the node label of the task to value, or removes it if value is empty.
+func (c *control) labelNodes(t *task, nodes []string, value string) error {
+	key := NodeLabelKey(t.Src)
+	for _, name := range nodes {
+		node := &v1.Node{}
+		if err := c.Client.Get(context.TODO(), types.NamespacedName{Name: name}, node); err != nil {
+			return fmt.Errorf("failed to get node %s: %v", name, err)
+		}
+		if current, ok := node.Labels[key]; current == value && (ok || value == "") {
+			continue
+		}
+		patch := client.MergeFrom(node.DeepCopy())
+		if value == "" {
+			delete(node.Labels, key)
+		} else {
+			if node.Labels == nil {
+				node.Labels = make(map[string]string)
+			}
+			node.Labels[key] = value
+		}
+		if err := c.Client.Patch(context.TODO(), node, patch); err != nil {
+			return fmt.Errorf("failed to label node %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// getLabeledNodes counts the leading nodes of the task that have been labeled as migrated,
+// and as migrating or migrated.
+func (c *control) getLabeledNodes(t *task) (migrated int32, migrating int32, err error) {
+	key := NodeLabelKey(t.Src)
+	for _, name := range t.Extra.Nodes {
+		node := &v1.Node{}
+		if err := c.Client.Get(context.TODO(), types.NamespacedName{Name: name}, node); err != nil {
+			return 0, 0, fmt.Errorf("failed to get node %s: %v", name, err)
+		}
+		switch node.Labels[key] {
+		case NodeMigrated:
+			migrated++
+			migrating++
+		case NodeMigrating:
+			migrating++
+		default:
+			return migrated, migrating, nil
+		}
+	}
+	return migrated, migrating, nil
+}
+
+func getDaemonSetObjects(reader client.Reader, src, dst *api.ResourceRef) (*apps.DaemonSet, *appsv1alpha1.DaemonSet, error) {
+	srcDaemonSet := apps.DaemonSet{}
+	if err := reader.Get(context.TODO(), src.GetNamespacedName(), &srcDaemonSet); err != nil {
+		return nil, nil, fmt.Errorf("failed to get %v: %v", src, err)
+	}
+
+	dstDaemonSet := appsv1alpha1.DaemonSet{}
+	if err := reader.Get(context.TODO(), dst.GetNamespacedName(), &dstDaemonSet); err != nil {
+		return nil, nil, fmt.Errorf("failed to get %v: %v", dst, err)
+	}
+
+	return &srcDaemonSet, &dstDaemonSet, nil
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemonset
+
+import (
+	"context"
+	"testing"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// matchesNode evaluates the required node affinity of the template against the node labels.
+func matchesNode(t *testing.T, template *v1.PodTemplateSpec, node *v1.Node) bool {
+	affinity := template.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+	operators := map[v1.NodeSelectorOperator]selection.Operator{
+		v1.NodeSelectorOpIn:           selection.In,
+		v1.NodeSelectorOpNotIn:        selection.NotIn,
+		v1.NodeSelectorOpExists:       selection.Exists,
+		v1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	}
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		selector := labels.NewSelector()
+		for _, r := range term.MatchExpressions {
+			requirement, err := labels.NewRequirement(r.Key, operators[r.Operator], r.Values)
+			assert.NoError(t, err)
+			selector = selector.Add(*requirement)
+		}
+		if selector.Matches(labels.Set(node.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNodeJoiningAfterMigration(t *testing.T) {
+	src, dst := api.NewDaemonSetRef("default", "demo"), api.NewAdvancedDaemonSetRef("default", "demo")
+	key := NodeLabelKey(src)
+	rollingUpdate := apps.DaemonSetUpdateStrategy{Type: apps.RollingUpdateDaemonSetStrategyType}
+
+	// both nodes have been handed over, and src runs on no node any more
+	srcDaemonSet := &apps.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"},
+		Spec:       apps.DaemonSetSpec{UpdateStrategy: apps.DaemonSetUpdateStrategy{Type: apps.OnDeleteDaemonSetStrategyType}},
+	}
+	AddNodeSelectorRequirement(&srcDaemonSet.Spec.Template, SrcNodeSelectorRequirement(src))
+	dstDaemonSet := &appsv1alpha1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"}}
+	AddNodeSelectorRequirement(&dstDaemonSet.Spec.Template, DstNodeSelectorRequirement(src))
+	c := fake.NewClientBuilder().WithScheme(api.GetScheme()).WithObjects(
+		srcDaemonSet, dstDaemonSet,
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{key: NodeMigrated}}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2", Labels: map[string]string{key: NodeMigrated}}},
+	).Build()
+	ctrl := newControl(c, c, &informertest.FakeInformers{Scheme: api.GetScheme()}, &record.FakeRecorder{}, migration.ControlOptions{})
+
+	task, err := migration.NewTask(src, dst, migration.Options{Replicas: ptr.To[int32](2)},
+		taskExtra{Nodes: []string{"node-1", "node-2"}, SrcUpdateStrategy: rollingUpdate})
+	assert.NoError(t, err)
+	_, err = ctrl.StartTask(task)
+	assert.NoError(t, err)
+	ctrl.UpdateTask(task, 2, 2)
+
+	for i := 0; i < 5 && task.Result().State == migration.MigrateExecuting; i++ {
+		assert.NoError(t, ctrl.Reconcile(task))
+	}
+	assert.Equal(t, migration.MigrateSucceeded, task.Result().State)
+
+	newSrc, newDst := &apps.DaemonSet{}, &appsv1alpha1.DaemonSet{}
+	assert.NoError(t, c.Get(context.TODO(), src.GetNamespacedName(), newSrc))
+	assert.NoError(t, c.Get(context.TODO(), dst.GetNamespacedName(), newDst))
+	assert.Equal(t, rollingUpdate, newSrc.Spec.UpdateStrategy)
+	// the pod template of dst is left as it is, so that its pods are not rolled again
+	assert.Equal(t, dstDaemonSet.Spec.Template, newDst.Spec.Template)
+
+	nodeList := &v1.NodeList{}
+	assert.NoError(t, c.List(context.TODO(), nodeList, client.HasLabels{key}))
+	assert.Empty(t, nodeList.Items)
+
+	joined := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-3"}}
+	assert.NoError(t, c.Create(context.TODO(), joined))
+	assert.False(t, matchesNode(t, &newSrc.Spec.Template, joined))
+	assert.True(t, matchesNode(t, &newDst.Spec.Template, joined))
+
+	// rolling back restores the handover before the nodes are given back, and leaves the joined node to src
+	_, err = ctrl.Rollback(task.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, ctrl.Reconcile(task))
+	assert.NoError(t, c.Get(context.TODO(), src.GetNamespacedName(), newSrc))
+	assert.NoError(t, c.Get(context.TODO(), dst.GetNamespacedName(), newDst))
+	assert.NoError(t, c.Get(context.TODO(), client.ObjectKeyFromObject(joined), joined))
+	assert.True(t, HasNodeSelectorRequirement(&newSrc.Spec.Template, SrcNodeSelectorRequirement(src)))
+	assert.False(t, HasNodeSelectorRequirement(&newSrc.Spec.Template, RetiredNodeSelectorRequirement(src)))
+	assert.Equal(t, dstDaemonSet.Spec.Template, newDst.Spec.Template)
+	assert.Equal(t, NodePending, joined.Labels[key])
+	assert.True(t, matchesNode(t, &newSrc.Spec.Template, joined))
+	assert.False(t, matchesNode(t, &newDst.Spec.Template, joined))
+	for _, name := range []string{"node-1", "node-2"} {
+		node := &v1.Node{}
+		assert.NoError(t, c.Get(context.TODO(), client.ObjectKey{Name: name}, node))
+		assert.Equal(t, NodeMigrated, node.Labels[key])
+	}
+}
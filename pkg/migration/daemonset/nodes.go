@@ -0,0 +1,196 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemonset
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	nodeLabelPrefix = "daemonset.migration.kruise.io/"
+
+	// NodePending is set on nodes that are left to the source, before the destination is created.
+	NodePending = "pending"
+	// NodeMigrating is set on nodes where the destination has been started next to the source.
+	NodeMigrating = "migrating"
+	// NodeMigrated is set on nodes that have been handed over to the destination.
+	NodeMigrated = "migrated"
+	// NodeRetired is never set on nodes, it only keeps a retired source away from all of them.
+	NodeRetired = "retired"
+)
+
+// NodeLabelKey returns the node label that tracks the handover of the given source DaemonSet.
+// Nodes are cluster scoped, so the key contains both namespace and name of the source.
+func NodeLabelKey(src api.ResourceRef) string {
+	name := fmt.Sprintf("%s.%s", src.Namespace, src.Name)
+	if len(name) > 63 {
+		hash := fmt.Sprintf("%x", sha256.Sum256([]byte(name)))
+		name = fmt.Sprintf("%s-%s", name[:52], hash[:10])
+	}
+	return nodeLabelPrefix + name
+}
+
+// SrcNodeSelectorRequirement only allows the source on nodes that have not been handed over yet,
+// so that nodes joining during the migration only run the destination.
+func SrcNodeSelectorRequirement(src api.ResourceRef) v1.NodeSelectorRequirement {
+	return v1.NodeSelectorRequirement{
+		Key:      NodeLabelKey(src),
+		Operator: v1.NodeSelectorOpIn,
+		Values:   []string{NodePending, NodeMigrating},
+	}
+}
+
+// DstNodeSelectorRequirement keeps the destination away from nodes that are left to the source.
+// It holds on nodes without the label, so once the labels are removed the destination runs on every
+// node, including those that join later, without changing its pod template.
+func DstNodeSelectorRequirement(src api.ResourceRef) v1.NodeSelectorRequirement {
+	return v1.NodeSelectorRequirement{
+		Key:      NodeLabelKey(src),
+		Operator: v1.NodeSelectorOpNotIn,
+		Values:   []string{NodePending},
+	}
+}
+
+// RetiredNodeSelectorRequirement keeps the source away from all nodes, including those that
+// join after every node has been handed over and are never labeled.
+func RetiredNodeSelectorRequirement(src api.ResourceRef) v1.NodeSelectorRequirement {
+	return v1.NodeSelectorRequirement{
+		Key:      NodeLabelKey(src),
+		Operator: v1.NodeSelectorOpIn,
+		Values:   []string{NodeRetired},
+	}
+}
+
+// LabelPendingNodes labels every node without the node label of src as pending, so that they are left to src
+// and kept away from the destination.
+func LabelPendingNodes(c client.Client, src api.ResourceRef) error {
+	key := NodeLabelKey(src)
+	nodeList := &v1.NodeList{}
+	if err := c.List(context.TODO(), nodeList); err != nil {
+		return fmt.Errorf("failed to list nodes: %v", err)
+	}
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		if _, ok := node.Labels[key]; ok {
+			continue
+		}
+		patch := client.MergeFrom(node.DeepCopy())
+		if node.Labels == nil {
+			node.Labels = make(map[string]string)
+		}
+		node.Labels[key] = NodePending
+		if err := c.Patch(context.TODO(), node, patch); err != nil {
+			return fmt.Errorf("failed to label node %s: %v", node.Name, err)
+		}
+	}
+	return nil
+}
+
+// HasNodeSelectorRequirement returns whether every required node selector term of the pod
+// template contains the given requirement.
+func HasNodeSelectorRequirement(template *v1.PodTemplateSpec, requirement v1.NodeSelectorRequirement) bool {
+	affinity := template.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return false
+	}
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) == 0 {
+		return false
+	}
+	for i := range terms {
+		if !termHasRequirement(&terms[i], requirement) {
+			return false
+		}
+	}
+	return true
+}
+
+// AddNodeSelectorRequirement adds the requirement to every required node selector term of the
+// pod template, so that it is ANDed with the existing node affinity.
+func AddNodeSelectorRequirement(template *v1.PodTemplateSpec, requirement v1.NodeSelectorRequirement) {
+	if template.Spec.Affinity == nil {
+		template.Spec.Affinity = &v1.Affinity{}
+	}
+	if template.Spec.Affinity.NodeAffinity == nil {
+		template.Spec.Affinity.NodeAffinity = &v1.NodeAffinity{}
+	}
+	nodeAffinity := template.Spec.Affinity.NodeAffinity
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &v1.NodeSelector{}
+	}
+	selector := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(selector.NodeSelectorTerms) == 0 {
+		selector.NodeSelectorTerms = []v1.NodeSelectorTerm{{}}
+	}
+	for i := range selector.NodeSelectorTerms {
+		if !termHasRequirement(&selector.NodeSelectorTerms[i], requirement) {
+			selector.NodeSelectorTerms[i].MatchExpressions = append(selector.NodeSelectorTerms[i].MatchExpressions, requirement)
+		}
+	}
+}
+
+// RemoveNodeSelectorRequirement is the reverse of AddNodeSelectorRequirement, it removes the
+// requirement from every required node selector term and drops what has become empty.
+func RemoveNodeSelectorRequirement(template *v1.PodTemplateSpec, requirement v1.NodeSelectorRequirement) {
+	affinity := template.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return
+	}
+	selector := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+
+	var terms []v1.NodeSelectorTerm
+	for _, term := range selector.NodeSelectorTerms {
+		var expressions []v1.NodeSelectorRequirement
+		for _, r := range term.MatchExpressions {
+			if !equality.Semantic.DeepEqual(r, requirement) {
+				expressions = append(expressions, r)
+			}
+		}
+		term.MatchExpressions = expressions
+		if len(term.MatchExpressions) > 0 || len(term.MatchFields) > 0 {
+			terms = append(terms, term)
+		}
+	}
+	selector.NodeSelectorTerms = terms
+
+	if len(selector.NodeSelectorTerms) == 0 {
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = nil
+	}
+	if equality.Semantic.DeepEqual(*affinity.NodeAffinity, v1.NodeAffinity{}) {
+		affinity.NodeAffinity = nil
+	}
+	if equality.Semantic.DeepEqual(*affinity, v1.Affinity{}) {
+		template.Spec.Affinity = nil
+	}
+}
+
+func termHasRequirement(term *v1.NodeSelectorTerm, requirement v1.NodeSelectorRequirement) bool {
+	for _, r := range term.MatchExpressions {
+		if equality.Semantic.DeepEqual(r, requirement) {
+			return true
+		}
+	}
+	return false
+}
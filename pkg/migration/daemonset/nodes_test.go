@@ -0,0 +1,175 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemonset
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNodeLabelKey(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  api.ResourceRef
+	}{
+		{
+			name: "short name",
+			src:  api.NewDaemonSetRef("kube-system", "node-agent"),
+		},
+		{
+			name: "long name",
+			src:  api.NewDaemonSetRef("kube-system", strings.Repeat("node-agent", 10)),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key := NodeLabelKey(tc.src)
+			assert.Empty(t, validation.IsQualifiedName(key))
+			assert.Equal(t, key, NodeLabelKey(tc.src))
+		})
+	}
+}
+
+func TestAddNodeSelectorRequirement(t *testing.T) {
+	requirement := SrcNodeSelectorRequirement(api.NewDaemonSetRef("default", "agent"))
+	zoneRequirement := v1.NodeSelectorRequirement{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a"}}
+
+	testCases := []struct {
+		name          string
+		template      *v1.PodTemplateSpec
+		expectedTerms []v1.NodeSelectorTerm
+	}{
+		{
+			name:     "no affinity",
+			template: &v1.PodTemplateSpec{},
+			expectedTerms: []v1.NodeSelectorTerm{
+				{MatchExpressions: []v1.NodeSelectorRequirement{requirement}},
+			},
+		},
+		{
+			name: "added to every term",
+			template: &v1.PodTemplateSpec{Spec: v1.PodSpec{Affinity: &v1.Affinity{NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{
+					{MatchExpressions: []v1.NodeSelectorRequirement{zoneRequirement}},
+					{MatchExpressions: []v1.NodeSelectorRequirement{requirement}},
+				}},
+			}}}},
+			expectedTerms: []v1.NodeSelectorTerm{
+				{MatchExpressions: []v1.NodeSelectorRequirement{zoneRequirement, requirement}},
+				{MatchExpressions: []v1.NodeSelectorRequirement{requirement}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.False(t, HasNodeSelectorRequirement(tc.template, requirement))
+			AddNodeSelectorRequirement(tc.template, requirement)
+			assert.True(t, HasNodeSelectorRequirement(tc.template, requirement))
+			assert.Equal(t, tc.expectedTerms, tc.template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms)
+		})
+	}
+}
+
+func TestRemoveNodeSelectorRequirement(t *testing.T) {
+	requirement := SrcNodeSelectorRequirement(api.NewDaemonSetRef("default", "agent"))
+	zoneRequirement := v1.NodeSelectorRequirement{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a"}}
+	podAffinity := &v1.PodAffinity{RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{{TopologyKey: "zone"}}}
+
+	testCases := []struct {
+		name     string
+		template *v1.PodTemplateSpec
+		expected *v1.Affinity
+	}{
+		{
+			name:     "no affinity",
+			template: &v1.PodTemplateSpec{},
+			expected: nil,
+		},
+		{
+			name: "added to no affinity",
+			template: func() *v1.PodTemplateSpec {
+				template := &v1.PodTemplateSpec{}
+				AddNodeSelectorRequirement(template, requirement)
+				return template
+			}(),
+			expected: nil,
+		},
+		{
+			name: "added to existing terms",
+			template: func() *v1.PodTemplateSpec {
+				template := &v1.PodTemplateSpec{Spec: v1.PodSpec{Affinity: &v1.Affinity{PodAffinity: podAffinity, NodeAffinity: &v1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{
+						{MatchExpressions: []v1.NodeSelectorRequirement{zoneRequirement}},
+					}},
+				}}}}
+				AddNodeSelectorRequirement(template, requirement)
+				return template
+			}(),
+			expected: &v1.Affinity{PodAffinity: podAffinity, NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{
+					{MatchExpressions: []v1.NodeSelectorRequirement{zoneRequirement}},
+				}},
+			}},
+		},
+		{
+			name: "only node affinity added",
+			template: func() *v1.PodTemplateSpec {
+				template := &v1.PodTemplateSpec{Spec: v1.PodSpec{Affinity: &v1.Affinity{PodAffinity: podAffinity}}}
+				AddNodeSelectorRequirement(template, requirement)
+				return template
+			}(),
+			expected: &v1.Affinity{PodAffinity: podAffinity},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			RemoveNodeSelectorRequirement(tc.template, requirement)
+			assert.False(t, HasNodeSelectorRequirement(tc.template, requirement))
+			assert.Equal(t, tc.expected, tc.template.Spec.Affinity)
+		})
+	}
+}
+
+func TestLabelPendingNodes(t *testing.T) {
+	src := api.NewDaemonSetRef("default", "agent")
+	key := NodeLabelKey(src)
+	c := fake.NewClientBuilder().WithScheme(api.GetScheme()).WithObjects(
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2", Labels: map[string]string{key: NodeMigrated}}},
+	).Build()
+
+	assert.NoError(t, LabelPendingNodes(c, src))
+	nodeList := &v1.NodeList{}
+	assert.NoError(t, c.List(context.TODO(), nodeList))
+	labels := map[string]string{}
+	for _, node := range nodeList.Items {
+		labels[node.Name] = node.Labels[key]
+	}
+	// the nodes that are handed over keep their labels
+	assert.Equal(t, map[string]string{"node-1": NodePending, "node-2": NodeMigrated}, labels)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Reasons of the events recorded on the workloads of a task.
+const (
+	EventReasonStarted     = "MigrationStarted"
+	EventReasonProgressed  = "MigrationProgressed"
+	EventReasonRollingBack = "MigrationRollingBack"
+	EventReasonSucceeded   = "MigrationSucceeded"
+	EventReasonFailed      = "MigrationFailed"
+	EventReasonAborted     = "MigrationAborted"
+	EventReasonRolledBack  = "MigrationRolledBack"
+
+	EventReasonValidationWarning = "MigrationValidationWarning"
+)
+
+// StartEventRecorder returns a recorder of the migration controls, whose events are sent to the API server of cfg
+// until stopChan is closed.
+func StartEventRecorder(cfg *rest.Config, stopChan <-chan struct{}) (record.EventRecorder, error) {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+	go func() {
+		<-stopChan
+		broadcaster.Shutdown()
+	}()
+	return broadcaster.NewRecorder(api.GetScheme(), v1.EventSource{Component: "kruise-migration"}), nil
+}
+
+// EventRecorder records the events of a task on both of its workloads, so that they can be seen with kubectl describe.
+type EventRecorder struct {
+	reader   client.Reader
+	recorder record.EventRecorder
+}
+
+// NewEventRecorder returns a recorder of the workloads read by reader.
+func NewEventRecorder(reader client.Reader, recorder record.EventRecorder) *EventRecorder {
+	return &EventRecorder{reader: reader, recorder: recorder}
+}
+
+// Eventf records an event on src and dst.
+func (r *EventRecorder) Eventf(src, dst api.ResourceRef, eventtype, reason, messageFmt string, args ...interface{}) {
+	for _, ref := range []api.ResourceRef{src, dst} {
+		// the uid is required for the event to be listed with the object
+		obj := &metav1.PartialObjectMetadata{}
+		obj.SetGroupVersionKind(ref.GetGroupVersionKind())
+		if err := r.reader.Get(context.TODO(), ref.GetNamespacedName(), obj); err != nil {
+			utilruntime.HandleError(fmt.Errorf("failed to record event %s on %v: %v", reason, ref, err))
+			continue
+		}
+		r.recorder.Eventf(obj, eventtype, reason, messageFmt, args...)
+	}
+}
+
+// RecordFinished records the event of the state that a task finished in.
+func (r *EventRecorder) RecordFinished(src, dst api.ResourceRef, result Result) {
+	switch result.State {
+	case MigrateSucceeded:
+		r.Eventf(src, dst, v1.EventTypeNormal, EventReasonSucceeded, "Migration task %v succeeded", result.ID)
+	case MigrateFailed:
+		r.Eventf(src, dst, v1.EventTypeWarning, EventReasonFailed, "Migration task %v failed: %s", result.ID, result.Message)
+	case MigrateAborted:
+		r.Eventf(src, dst, v1.EventTypeWarning, EventReasonAborted, "Migration task %v aborted: %s", result.ID, result.Message)
+	case MigrateRolledBack:
+		r.Eventf(src, dst, v1.EventTypeNormal, EventReasonRolledBack, "Migration task %v rolled back", result.ID)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"testing"
+	"time"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEventRecorder(t *testing.T) {
+	src, dst := api.NewDeploymentRef("default", "demo"), api.NewCloneSetRef("default", "demo")
+
+	testCases := []struct {
+		name            string
+		result          Result
+		expectedType    string
+		expectedReason  string
+		expectedMessage string
+	}{
+		{
+			name:            "succeeded",
+			result:          Result{ID: "task", State: MigrateSucceeded},
+			expectedType:    v1.EventTypeNormal,
+			expectedReason:  EventReasonSucceeded,
+			expectedMessage: "Migration task task succeeded",
+		},
+		{
+			name:            "failed",
+			result:          Result{ID: "task", State: MigrateFailed, Message: "task timeout exceeded"},
+			expectedType:    v1.EventTypeWarning,
+			expectedReason:  EventReasonFailed,
+			expectedMessage: "Migration task task failed: task timeout exceeded",
+		},
+		{
+			name:   "still executing",
+			result: Result{ID: "task", State: MigrateExecuting},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(api.GetScheme()).WithObjects(
+				&apps.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo", UID: "deployment-uid"}},
+				&appsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo", UID: "cloneset-uid"}},
+			).Build()
+			broadcaster := record.NewBroadcaster()
+			defer broadcaster.Shutdown()
+			events := make(chan *v1.Event, 2)
+			broadcaster.StartEventWatcher(func(event *v1.Event) { events <- event })
+			recorder := broadcaster.NewRecorder(api.GetScheme(), v1.EventSource{Component: "kruise-migration"})
+			NewEventRecorder(c, recorder).RecordFinished(src, dst, tc.result)
+
+			if len(tc.expectedReason) == 0 {
+				select {
+				case event := <-events:
+					t.Fatalf("unexpected event %v", event)
+				case <-time.After(100 * time.Millisecond):
+				}
+				return
+			}
+			var recorded []*v1.Event
+			for len(recorded) < 2 {
+				select {
+				case event := <-events:
+					recorded = append(recorded, event)
+				case <-time.After(wait.ForeverTestTimeout):
+					t.Fatalf("expected 2 events, got %d", len(recorded))
+				}
+			}
+			uids := map[types.UID]string{}
+			for _, event := range recorded {
+				assert.Equal(t, tc.expectedType, event.Type)
+				assert.Equal(t, tc.expectedReason, event.Reason)
+				assert.Equal(t, tc.expectedMessage, event.Message)
+				uids[event.InvolvedObject.UID] = event.InvolvedObject.Kind
+			}
+			assert.Equal(t, map[types.UID]string{"deployment-uid": "Deployment", "cloneset-uid": "CloneSet"}, uids)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// NamespaceLimiter limits the number of tasks running in each namespace at the same time.
+type NamespaceLimiter struct {
+	mu      sync.Mutex
+	max     int
+	running map[string]sets.Set[types.UID]
+}
+
+// NewNamespaceLimiter returns a limiter of max tasks per namespace, 0 means no limited.
+func NewNamespaceLimiter(max int) *NamespaceLimiter {
+	return &NamespaceLimiter{max: max, running: make(map[string]sets.Set[types.UID])}
+}
+
+// Admit returns whether the task can run in the namespace, and keeps it running until it is released.
+func (l *NamespaceLimiter) Admit(namespace string, ID types.UID) bool {
+	if l.max <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	running, ok := l.running[namespace]
+	if !ok {
+		running = sets.New[types.UID]()
+		l.running[namespace] = running
+	}
+	if running.Has(ID) {
+		return true
+	} else if running.Len() >= l.max {
+		return false
+	}
+	running.Insert(ID)
+	return true
+}
+
+// Release stops counting the task as running in the namespace.
+func (l *NamespaceLimiter) Release(namespace string, ID types.UID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if running, ok := l.running[namespace]; ok {
+		running.Delete(ID)
+		if running.Len() == 0 {
+			delete(l.running, namespace)
+		}
+	}
+}
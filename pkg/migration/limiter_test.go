@@ -0,0 +1,44 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestNamespaceLimiter(t *testing.T) {
+	l := NewNamespaceLimiter(2)
+	assert.True(t, l.Admit("a", "task-1"))
+	assert.True(t, l.Admit("a", "task-2"))
+	assert.False(t, l.Admit("a", "task-3"))
+	// admitted tasks keep running
+	assert.True(t, l.Admit("a", "task-1"))
+	// other namespaces are not limited by a
+	assert.True(t, l.Admit("b", "task-4"))
+
+	l.Release("a", "task-1")
+	assert.True(t, l.Admit("a", "task-3"))
+	assert.False(t, l.Admit("a", "task-1"))
+
+	unlimited := NewNamespaceLimiter(0)
+	for _, id := range []string{"task-1", "task-2", "task-3"} {
+		assert.True(t, unlimited.Admit("a", types.UID(id)))
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// SetDefaultMaxSurge sets MaxSurge to 1 if neither MaxSurge nor MaxUnavailable is set.
+func SetDefaultMaxSurge(opts *Options) {
+	if opts.MaxSurge == nil && opts.MaxUnavailable == nil {
+		maxSurge := intstr.FromInt32(1)
+		opts.MaxSurge = &maxSurge
+	}
+}
+
+// ResolveMaxSurgeAndMaxUnavailable returns the absolute MaxSurge and MaxUnavailable of opts,
+// scaling percentages by Replicas like the rolling update of a Deployment.
+func ResolveMaxSurgeAndMaxUnavailable(opts *Options) (int32, int32, error) {
+	if opts.Replicas == nil {
+		return 0, 0, fmt.Errorf("replicas must be set")
+	}
+	zero := intstr.FromInt32(0)
+	surge, unavailable := &zero, &zero
+	if opts.MaxSurge != nil {
+		surge = opts.MaxSurge
+	}
+	if opts.MaxUnavailable != nil {
+		unavailable = opts.MaxUnavailable
+	}
+
+	maxSurge, err := intstr.GetScaledValueFromIntOrPercent(surge, int(*opts.Replicas), true)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid maxSurge %v: %v", surge.String(), err)
+	}
+	maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(unavailable, int(*opts.Replicas), false)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid maxUnavailable %v: %v", unavailable.String(), err)
+	}
+
+	if maxSurge < 0 || maxUnavailable < 0 {
+		return 0, 0, fmt.Errorf("maxSurge %v and maxUnavailable %v can not be negative", surge.String(), unavailable.String())
+	} else if maxSurge == 0 && maxUnavailable == 0 {
+		return 0, 0, fmt.Errorf("maxSurge %v and maxUnavailable %v can not both be zero for %d replicas",
+			surge.String(), unavailable.String(), *opts.Replicas)
+	}
+	return int32(maxSurge), int32(maxUnavailable), nil
+}
+
+// SetDefaultControlOptions sets the defaults of the unset options.
+func SetDefaultControlOptions(opts *ControlOptions) {
+	if opts.MaxConcurrentReconciles <= 0 {
+		opts.MaxConcurrentReconciles = 5
+	}
+	if opts.RateLimiter == nil {
+		opts.RateLimiter = workqueue.DefaultControllerRateLimiter()
+	}
+}
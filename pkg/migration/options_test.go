@@ -0,0 +1,84 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+func TestResolveMaxSurgeAndMaxUnavailable(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		maxSurge               *intstr.IntOrString
+		maxUnavailable         *intstr.IntOrString
+		expectedMaxSurge       int32
+		expectedMaxUnavailable int32
+		expectErr              bool
+	}{
+		{
+			name:             "default",
+			expectedMaxSurge: 1,
+		},
+		{
+			name:                   "only max unavailable",
+			maxUnavailable:         ptr.To(intstr.FromInt32(2)),
+			expectedMaxUnavailable: 2,
+		},
+		{
+			name:                   "percentages",
+			maxSurge:               ptr.To(intstr.FromString("25%")),
+			maxUnavailable:         ptr.To(intstr.FromString("25%")),
+			expectedMaxSurge:       3,
+			expectedMaxUnavailable: 2,
+		},
+		{
+			name:           "both zero",
+			maxSurge:       ptr.To(intstr.FromInt32(0)),
+			maxUnavailable: ptr.To(intstr.FromString("5%")),
+			expectErr:      true,
+		},
+		{
+			name:      "negative",
+			maxSurge:  ptr.To(intstr.FromInt32(-1)),
+			expectErr: true,
+		},
+		{
+			name:      "invalid percentage",
+			maxSurge:  ptr.To(intstr.FromString("a%")),
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := &Options{Replicas: ptr.To[int32](10), MaxSurge: tc.maxSurge, MaxUnavailable: tc.maxUnavailable}
+			SetDefaultMaxSurge(opts)
+			maxSurge, maxUnavailable, err := ResolveMaxSurgeAndMaxUnavailable(opts)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedMaxSurge, maxSurge)
+			assert.Equal(t, tc.expectedMaxUnavailable, maxUnavailable)
+		})
+	}
+}
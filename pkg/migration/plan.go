@@ -0,0 +1,77 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"fmt"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+)
+
+// Plan is the sequence of steps that a migration task would take from the current replicas.
+type Plan struct {
+	Src     api.ResourceRef `json:"src"`
+	Dst     api.ResourceRef `json:"dst"`
+	Options Options         `json:"options"`
+	Steps   []Step          `json:"steps"`
+	// Warnings of the pre-flight validation, which do not refuse the task.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type StepAction string
+
+const (
+	StepScaleOutDst StepAction = "ScaleOutDst"
+	StepScaleInSrc  StepAction = "ScaleInSrc"
+)
+
+// Step scales one of the workloads by Replicas, after which they have SrcReplicas and DstReplicas.
+type Step struct {
+	Action   StepAction `json:"action"`
+	Replicas int32      `json:"replicas"`
+
+	SrcReplicas int32 `json:"srcReplicas"`
+	DstReplicas int32 `json:"dstReplicas"`
+}
+
+// PlanSteps simulates a task with the given initial replicas. next returns the replicas that src
+// scales in or dst scales out by in the next step from the result, and zero if neither can.
+func PlanSteps(srcReplicas, dstReplicas int32, opts Options, next func(result Result) (StepAction, int32)) ([]Step, error) {
+	var steps []Step
+	result := Result{}
+	for result.SrcMigratedReplicas < *opts.Replicas || result.DstMigratedReplicas < *opts.Replicas {
+		action, replicas := next(result)
+		if replicas <= 0 {
+			return nil, fmt.Errorf("can not migrate %d replicas, stuck after src scaled in %d and dst scaled out %d",
+				*opts.Replicas, result.SrcMigratedReplicas, result.DstMigratedReplicas)
+		}
+
+		switch action {
+		case StepScaleOutDst:
+			result.DstMigratedReplicas += replicas
+		case StepScaleInSrc:
+			result.SrcMigratedReplicas += replicas
+		}
+		steps = append(steps, Step{
+			Action:      action,
+			Replicas:    replicas,
+			SrcReplicas: srcReplicas - result.SrcMigratedReplicas,
+			DstReplicas: dstReplicas + result.DstMigratedReplicas,
+		})
+	}
+	return steps, nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Authorizer decides whether the user of a request may access the workloads of migrations.
+type Authorizer interface {
+	// Authenticate returns the user of the request, or an Unauthorized error if it can not be authenticated.
+	Authenticate(r *http.Request) (authenticationv1.UserInfo, error)
+	// Authorize returns a Forbidden error if the user may not do the verb on any of the resources.
+	// A resource without a name stands for all of its kind in the namespace.
+	Authorize(ctx context.Context, user authenticationv1.UserInfo, verb string, refs ...api.ResourceRef) error
+}
+
+// reviewAuthorizer authenticates the bearer token of a request with a TokenReview, and authorizes
+// the user with a SubjectAccessReview on each resource, like the API server would.
+type reviewAuthorizer struct {
+	client client.Client
+}
+
+// NewReviewAuthorizer returns an authorizer that allows a request if its bearer token belongs to a user
+// who may access the resources. c must be allowed to create TokenReviews and SubjectAccessReviews.
+func NewReviewAuthorizer(c client.Client) Authorizer {
+	return &reviewAuthorizer{client: c}
+}
+
+func (a *reviewAuthorizer) Authenticate(r *http.Request) (authenticationv1.UserInfo, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || len(token) == 0 {
+		return authenticationv1.UserInfo{}, apierrors.NewUnauthorized("a bearer token is required")
+	}
+	tokenReview := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	if err := a.client.Create(r.Context(), tokenReview); err != nil {
+		return authenticationv1.UserInfo{}, apierrors.NewInternalError(fmt.Errorf("failed to review token: %v", err))
+	} else if !tokenReview.Status.Authenticated {
+		return authenticationv1.UserInfo{}, apierrors.NewUnauthorized(fmt.Sprintf("invalid bearer token: %s", tokenReview.Status.Error))
+	}
+	return tokenReview.Status.User, nil
+}
+
+func (a *reviewAuthorizer) Authorize(ctx context.Context, user authenticationv1.UserInfo, verb string, refs ...api.ResourceRef) error {
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	for _, ref := range refs {
+		gvk := ref.GetGroupVersionKind()
+		mapping, err := a.client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return apierrors.NewInternalError(fmt.Errorf("failed to get resource of %v: %v", ref, err))
+		}
+		review := &authorizationv1.SubjectAccessReview{Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: ref.Namespace,
+				Verb:      verb,
+				Group:     gvk.Group,
+				Version:   gvk.Version,
+				Resource:  mapping.Resource.Resource,
+				Name:      ref.Name,
+			},
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+			Extra:  extra,
+		}}
+		if err := a.client.Create(ctx, review); err != nil {
+			return apierrors.NewInternalError(fmt.Errorf("failed to review access to %v: %v", ref, err))
+		} else if !review.Status.Allowed {
+			reason := fmt.Errorf("user %q may not %s it", user.Username, verb)
+			if len(ref.Namespace) > 0 {
+				reason = fmt.Errorf("user %q may not %s it in namespace %q", user.Username, verb, ref.Namespace)
+			}
+			return apierrors.NewForbidden(mapping.Resource.GroupResource(), ref.Name, reason)
+		}
+	}
+	return nil
+}
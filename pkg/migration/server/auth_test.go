@@ -0,0 +1,149 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestReviewAuthorizer(t *testing.T) {
+	src, dst := api.NewDeploymentRef("default", "demo"), api.NewCloneSetRef("default", "demo")
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(api.DeploymentKind, meta.RESTScopeNamespace)
+	mapper.Add(api.CloneSetKind, meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Node"), meta.RESTScopeRoot)
+
+	// the token "alice" authenticates alice, who may only update deployments and get clonesets
+	var reviews []authorizationv1.ResourceAttributes
+	c := fake.NewClientBuilder().WithScheme(api.GetScheme()).WithRESTMapper(mapper).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			switch review := obj.(type) {
+			case *authenticationv1.TokenReview:
+				if review.Spec.Token == "alice" {
+					review.Status.Authenticated = true
+					review.Status.User = authenticationv1.UserInfo{Username: "alice", Groups: []string{"dev"}}
+				}
+			case *authorizationv1.SubjectAccessReview:
+				assert.Equal(t, "alice", review.Spec.User)
+				assert.Equal(t, []string{"dev"}, review.Spec.Groups)
+				reviews = append(reviews, *review.Spec.ResourceAttributes)
+				attributes := review.Spec.ResourceAttributes
+				review.Status.Allowed = attributes.Resource == "deployments" && attributes.Verb == "update" ||
+					attributes.Resource == "clonesets" && attributes.Verb == "get"
+			}
+			return nil
+		},
+	}).Build()
+	authorizer := NewReviewAuthorizer(c)
+
+	testCases := []struct {
+		name            string
+		header          string
+		verb            string
+		refs            []api.ResourceRef
+		expectedStatus  int32
+		expectedReviews []authorizationv1.ResourceAttributes
+	}{
+		{
+			name:           "no token",
+			refs:           []api.ResourceRef{src, dst},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid token",
+			header:         "Bearer bob",
+			refs:           []api.ResourceRef{src, dst},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "allowed",
+			header: "Bearer alice",
+			verb:   "update",
+			refs:   []api.ResourceRef{src},
+			expectedReviews: []authorizationv1.ResourceAttributes{
+				{Namespace: "default", Verb: "update", Group: "apps", Version: "v1", Resource: "deployments", Name: "demo"},
+			},
+		},
+		{
+			name:           "forbidden",
+			header:         "Bearer alice",
+			verb:           "update",
+			refs:           []api.ResourceRef{src, dst},
+			expectedStatus: http.StatusForbidden,
+			expectedReviews: []authorizationv1.ResourceAttributes{
+				{Namespace: "default", Verb: "update", Group: "apps", Version: "v1", Resource: "deployments", Name: "demo"},
+				{Namespace: "default", Verb: "update", Group: "apps.kruise.io", Version: "v1alpha1", Resource: "clonesets", Name: "demo"},
+			},
+		},
+		{
+			name:   "allowed to get",
+			header: "Bearer alice",
+			verb:   "get",
+			refs:   []api.ResourceRef{dst},
+			expectedReviews: []authorizationv1.ResourceAttributes{
+				{Namespace: "default", Verb: "get", Group: "apps.kruise.io", Version: "v1alpha1", Resource: "clonesets", Name: "demo"},
+			},
+		},
+		{
+			name:           "forbidden to patch nodes",
+			header:         "Bearer alice",
+			verb:           "patch",
+			refs:           []api.ResourceRef{{APIVersion: "v1", Kind: "Node"}},
+			expectedStatus: http.StatusForbidden,
+			expectedReviews: []authorizationv1.ResourceAttributes{
+				{Verb: "patch", Version: "v1", Resource: "nodes"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reviews = nil
+			req := httptest.NewRequest(http.MethodPost, "/migrations", nil)
+			if len(tc.header) > 0 {
+				req.Header.Set("Authorization", tc.header)
+			}
+
+			user, err := authorizer.Authenticate(req)
+			if err == nil {
+				err = authorizer.Authorize(req.Context(), user, tc.verb, tc.refs...)
+			}
+			if tc.expectedStatus == 0 {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Equal(t, tc.expectedStatus, err.(apierrors.APIStatus).Status().Code)
+			}
+			assert.Equal(t, tc.expectedReviews, reviews)
+		})
+	}
+}
@@ -0,0 +1,393 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ControlFunc returns the control that migrates replicas into dst.
+type ControlFunc func(dst api.ResourceRef) (migration.Control, error)
+
+// SubmitRequest is the body of a request to submit a migration task.
+type SubmitRequest struct {
+	Src     api.ResourceRef   `json:"src"`
+	Dst     api.ResourceRef   `json:"dst"`
+	Options migration.Options `json:"options"`
+}
+
+// errorResponse is the body of a failed request.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server runs migration tasks in a long-lived process, and takes work from an HTTP API. The requests reading
+// a task are only served for users that the Authorizer allows to get its destination, and those changing a task
+// for users allowed to update both of its workloads, and the nodes or persistent volume claims it hands over:
+//
+//	GET  /healthz
+//	GET  /migrations?namespace=NAMESPACE             lists the tasks recorded in the cluster that the user may get
+//	POST /migrations                                 submits a task from a SubmitRequest
+//	GET  /migrations/{namespace}/{id}                returns the result of a task
+//	POST /migrations/{namespace}/{id}/resume         resumes a task recorded in the cluster
+//	POST /migrations/{namespace}/{id}/abort          aborts a running task
+//	POST /migrations/{namespace}/{id}/rollback       rolls a task back
+type Server struct {
+	reader     client.Reader
+	authorizer Authorizer
+	newControl ControlFunc
+
+	mu sync.Mutex
+	// controls are created on the first task into each kind of destination.
+	controls map[schema.GroupVersionKind]migration.Control
+	// tasks are the tasks submitted or resumed by the server.
+	tasks map[types.UID]*runningTask
+}
+
+// access is a verb that the user of a request must be allowed to do on resources.
+type access struct {
+	verb string
+	refs []api.ResourceRef
+}
+
+// getAccess is the access needed to read the tasks into dst.
+func getAccess(dst api.ResourceRef) access {
+	return access{verb: "get", refs: []api.ResourceRef{dst}}
+}
+
+// migrateAccesses are the accesses needed to change the tasks from src into dst, which include the resources
+// changed by the server besides the workloads.
+func migrateAccesses(src, dst api.ResourceRef) []access {
+	accesses := []access{{verb: "update", refs: []api.ResourceRef{src, dst}}}
+	switch dst.GetGroupVersionKind() {
+	case api.AdvancedDaemonSetKind:
+		// the nodes are labeled as they are handed over
+		nodes := api.ResourceRef{APIVersion: "v1", Kind: "Node"}
+		accesses = append(accesses, access{verb: "patch", refs: []api.ResourceRef{nodes}})
+	case api.AdvancedStatefulSetKind:
+		// the persistent volume claims are handed over by their owner references
+		pvcs := api.ResourceRef{APIVersion: "v1", Kind: "PersistentVolumeClaim", Namespace: dst.Namespace}
+		accesses = append(accesses, access{verb: "update", refs: []api.ResourceRef{pvcs}})
+	}
+	return accesses
+}
+
+// runningTask is a task submitted or resumed by the server, with the control running it.
+type runningTask struct {
+	ctrl migration.Control
+	src  api.ResourceRef
+	dst  api.ResourceRef
+}
+
+// New returns a server reading the recorded tasks with reader, authorizing the requests with authorizer,
+// and running the tasks with the controls from newControl.
+func New(reader client.Reader, authorizer Authorizer, newControl ControlFunc) *Server {
+	return &Server{
+		reader:     reader,
+		authorizer: authorizer,
+		newControl: newControl,
+		controls:   make(map[schema.GroupVersionKind]migration.Control),
+		tasks:      make(map[types.UID]*runningTask),
+	}
+}
+
+// ResumeRunning resumes the tasks recorded as running in the namespace, such as those of a previous server
+// that exited. An empty namespace resumes them in all namespaces.
+func (s *Server) ResumeRunning(namespace string) error {
+	states, err := migration.ListStates(s.reader, namespace)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range states {
+		if !states[i].Result.State.IsRunning() {
+			continue
+		}
+		result, err := s.resume(&states[i])
+		if err != nil {
+			// the other tasks are still resumed
+			errs = append(errs, fmt.Errorf("failed to resume migration task %v: %v", states[i].Result.ID, err))
+			continue
+		}
+		klog.Infof("Resumed migration task %v from %v to %v, %s", result.ID, states[i].Src, states[i].Dst, result.State)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// Handler returns the handler of the HTTP API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("GET /migrations", s.list)
+	mux.HandleFunc("POST /migrations", s.submit)
+	mux.HandleFunc("GET /migrations/{namespace}/{id}", s.query)
+	mux.HandleFunc("POST /migrations/{namespace}/{id}/resume", s.resumeTask)
+	mux.HandleFunc("POST /migrations/{namespace}/{id}/abort", s.abort)
+	mux.HandleFunc("POST /migrations/{namespace}/{id}/rollback", s.rollback)
+	return mux
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	states, err := migration.ListStates(s.reader, r.URL.Query().Get("namespace"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	allowed := []migration.State{}
+	for i := range states {
+		// the tasks that the user may not read are left out rather than failing the list
+		access := getAccess(states[i].Dst)
+		if err := s.authorizer.Authorize(r.Context(), user, access.verb, access.refs...); apierrors.IsForbidden(err) {
+			continue
+		} else if err != nil {
+			writeAuthError(w, err)
+			return
+		}
+		allowed = append(allowed, states[i])
+	}
+	writeJSON(w, http.StatusOK, allowed)
+}
+
+func (s *Server) submit(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	req := SubmitRequest{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid migration request: %v", err))
+		return
+	} else if len(req.Src.Namespace) == 0 || req.Src.Namespace != req.Dst.Namespace {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("src and dst must be in the same namespace"))
+		return
+	} else if !s.authorize(w, r, user, migrateAccesses(req.Src, req.Dst)...) {
+		return
+	}
+
+	ctrl, err := s.getControl(req.Dst)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	result, err := ctrl.Submit(req.Src, req.Dst, req.Options)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	s.trackTask(result.ID, &runningTask{ctrl: ctrl, src: req.Src, dst: req.Dst})
+	klog.Infof("Submitted migration task %v from %v to %v", result.ID, req.Src, req.Dst)
+	writeJSON(w, http.StatusCreated, result)
+}
+
+func (s *Server) query(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	id := types.UID(r.PathValue("id"))
+	if t := s.getTask(id); t != nil {
+		if !s.authorize(w, r, user, getAccess(t.dst)) {
+			return
+		}
+		result, err := t.ctrl.Query(id)
+		writeResult(w, result, err)
+		return
+	}
+
+	// tasks of other processes are only read from the cluster
+	state, err := migration.GetState(s.reader, r.PathValue("namespace"), id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	} else if !s.authorize(w, r, user, getAccess(state.Dst)) {
+		return
+	}
+	writeJSON(w, http.StatusOK, state.Result)
+}
+
+func (s *Server) resumeTask(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	state, err := migration.GetState(s.reader, r.PathValue("namespace"), types.UID(r.PathValue("id")))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	} else if !s.authorize(w, r, user, migrateAccesses(state.Src, state.Dst)...) {
+		return
+	}
+	result, err := s.resume(state)
+	writeResult(w, result, err)
+}
+
+func (s *Server) abort(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	id := types.UID(r.PathValue("id"))
+	t := s.getTask(id)
+	if t == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("migration task %v is not running in this server", id))
+		return
+	} else if !s.authorize(w, r, user, migrateAccesses(t.src, t.dst)...) {
+		return
+	}
+	result, err := t.ctrl.Abort(id)
+	writeResult(w, result, err)
+}
+
+func (s *Server) rollback(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	id := types.UID(r.PathValue("id"))
+	t := s.getTask(id)
+	if t == nil {
+		// a task of another process is loaded first to roll back from where it stopped
+		state, err := migration.GetState(s.reader, r.PathValue("namespace"), id)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		} else if !s.authorize(w, r, user, migrateAccesses(state.Src, state.Dst)...) {
+			return
+		}
+		if _, err := s.resume(state); err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		t = s.getTask(id)
+	} else if !s.authorize(w, r, user, migrateAccesses(t.src, t.dst)...) {
+		return
+	}
+	result, err := t.ctrl.Rollback(id)
+	writeResult(w, result, err)
+}
+
+// resume resumes the task of the state with the control of its destination.
+func (s *Server) resume(state *migration.State) (migration.Result, error) {
+	ctrl, err := s.getControl(state.Dst)
+	if err != nil {
+		return migration.Result{}, err
+	}
+	result, err := ctrl.Resume(state.Dst)
+	if err != nil {
+		return result, err
+	}
+	s.trackTask(result.ID, &runningTask{ctrl: ctrl, src: state.Src, dst: state.Dst})
+	return result, nil
+}
+
+// authenticate writes the error response and returns false if the user of the request can not be authenticated.
+func (s *Server) authenticate(w http.ResponseWriter, r *http.Request) (authenticationv1.UserInfo, bool) {
+	user, err := s.authorizer.Authenticate(r)
+	if err != nil {
+		writeAuthError(w, err)
+		return user, false
+	}
+	return user, true
+}
+
+// authorize writes the error response and returns false if the user may not do all the accesses.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, user authenticationv1.UserInfo, accesses ...access) bool {
+	for _, access := range accesses {
+		if err := s.authorizer.Authorize(r.Context(), user, access.verb, access.refs...); err != nil {
+			writeAuthError(w, err)
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) getControl(dst api.ResourceRef) (migration.Control, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	gvk := dst.GetGroupVersionKind()
+	if ctrl, ok := s.controls[gvk]; ok {
+		return ctrl, nil
+	}
+	ctrl, err := s.newControl(dst)
+	if err != nil {
+		return nil, err
+	}
+	s.controls[gvk] = ctrl
+	return ctrl, nil
+}
+
+func (s *Server) getTask(ID types.UID) *runningTask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tasks[ID]
+}
+
+func (s *Server) trackTask(ID types.UID, t *runningTask) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[ID] = t
+}
+
+func writeResult(w http.ResponseWriter, result migration.Result, err error) {
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func writeAuthError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if statusErr, ok := err.(apierrors.APIStatus); ok {
+		status = int(statusErr.Status().Code)
+	}
+	writeError(w, status, err)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		klog.Errorf("Failed to write response: %v", err)
+	}
+}
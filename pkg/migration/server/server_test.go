@@ -0,0 +1,355 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+	"github.com/stretchr/testify/assert"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeControl runs no task, and only records the results of the calls.
+type fakeControl struct {
+	results map[types.UID]migration.Result
+	resumed []api.ResourceRef
+}
+
+var _ migration.Control = &fakeControl{}
+
+func (c *fakeControl) Submit(src api.ResourceRef, dst api.ResourceRef, opts migration.Options) (migration.Result, error) {
+	result := migration.Result{ID: types.UID(dst.Name), State: migration.MigrateExecuting}
+	c.results[result.ID] = result
+	return result, nil
+}
+
+func (c *fakeControl) Plan(src api.ResourceRef, dst api.ResourceRef, opts migration.Options, serverDryRun bool) (migration.Plan, error) {
+	return migration.Plan{}, nil
+}
+
+func (c *fakeControl) Resume(dst api.ResourceRef) (migration.Result, error) {
+	c.resumed = append(c.resumed, dst)
+	result := migration.Result{ID: types.UID(dst.Name), State: migration.MigrateExecuting}
+	c.results[result.ID] = result
+	return result, nil
+}
+
+func (c *fakeControl) Query(ID types.UID) (migration.Result, error) {
+	result, ok := c.results[ID]
+	if !ok {
+		return result, fmt.Errorf("not found ID %v", ID)
+	}
+	return result, nil
+}
+
+func (c *fakeControl) Abort(ID types.UID) (migration.Result, error) {
+	result, err := c.Query(ID)
+	result.State = migration.MigrateAborted
+	c.results[ID] = result
+	return result, err
+}
+
+func (c *fakeControl) Rollback(ID types.UID) (migration.Result, error) {
+	result, err := c.Query(ID)
+	result.State = migration.MigrateRollingBack
+	c.results[ID] = result
+	return result, err
+}
+
+// fakeAuthorizer allows the token "admin" to migrate any workload, and the token "viewer" to get any workload
+// but the private ones.
+type fakeAuthorizer struct{}
+
+func (a fakeAuthorizer) Authenticate(r *http.Request) (authenticationv1.UserInfo, error) {
+	switch r.Header.Get("Authorization") {
+	case "Bearer admin":
+		return authenticationv1.UserInfo{Username: "admin"}, nil
+	case "Bearer viewer":
+		return authenticationv1.UserInfo{Username: "viewer"}, nil
+	}
+	return authenticationv1.UserInfo{}, apierrors.NewUnauthorized("a bearer token is required")
+}
+
+func (a fakeAuthorizer) Authorize(ctx context.Context, user authenticationv1.UserInfo, verb string, refs ...api.ResourceRef) error {
+	if user.Username == "admin" {
+		return nil
+	}
+	for _, ref := range refs {
+		if verb != "get" || strings.HasPrefix(ref.Name, "private") {
+			return apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "deployments"}, ref.Name, fmt.Errorf("denied"))
+		}
+	}
+	return nil
+}
+
+func newCloneSet(name string, state migration.MigrateState) client.Object {
+	value, _ := json.Marshal(migration.State{
+		Src:    api.NewDeploymentRef("default", name),
+		Dst:    api.NewCloneSetRef("default", name),
+		Result: migration.Result{ID: types.UID(name), State: state},
+	})
+	return &appsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "default",
+		Name:        name,
+		Annotations: map[string]string{migration.StateAnnotation: string(value)},
+	}}
+}
+
+func newTestServer(objects ...client.Object) (*Server, *fakeControl) {
+	ctrl := &fakeControl{results: map[types.UID]migration.Result{}}
+	reader := fake.NewClientBuilder().WithScheme(api.GetScheme()).WithObjects(objects...).Build()
+	return New(reader, fakeAuthorizer{}, func(dst api.ResourceRef) (migration.Control, error) { return ctrl, nil }), ctrl
+}
+
+func TestResumeRunning(t *testing.T) {
+	s, ctrl := newTestServer(
+		newCloneSet("executing", migration.MigrateExecuting),
+		newCloneSet("rolling-back", migration.MigrateRollingBack),
+		newCloneSet("succeeded", migration.MigrateSucceeded),
+	)
+	assert.NoError(t, s.ResumeRunning(""))
+	assert.ElementsMatch(t, []api.ResourceRef{
+		api.NewCloneSetRef("default", "executing"),
+		api.NewCloneSetRef("default", "rolling-back"),
+	}, ctrl.resumed)
+}
+
+func TestMigrateAccesses(t *testing.T) {
+	testCases := []struct {
+		name     string
+		src      api.ResourceRef
+		dst      api.ResourceRef
+		expected []access
+	}{
+		{
+			name: "cloneset",
+			src:  api.NewDeploymentRef("default", "demo"),
+			dst:  api.NewCloneSetRef("default", "demo"),
+			expected: []access{
+				{verb: "update", refs: []api.ResourceRef{api.NewDeploymentRef("default", "demo"), api.NewCloneSetRef("default", "demo")}},
+			},
+		},
+		{
+			name: "advanced daemonset",
+			src:  api.NewDaemonSetRef("default", "demo"),
+			dst:  api.NewAdvancedDaemonSetRef("default", "demo"),
+			expected: []access{
+				{verb: "update", refs: []api.ResourceRef{api.NewDaemonSetRef("default", "demo"), api.NewAdvancedDaemonSetRef("default", "demo")}},
+				{verb: "patch", refs: []api.ResourceRef{{APIVersion: "v1", Kind: "Node"}}},
+			},
+		},
+		{
+			name: "advanced statefulset",
+			src:  api.NewStatefulSetRef("default", "demo"),
+			dst:  api.NewAdvancedStatefulSetRef("default", "demo"),
+			expected: []access{
+				{verb: "update", refs: []api.ResourceRef{api.NewStatefulSetRef("default", "demo"), api.NewAdvancedStatefulSetRef("default", "demo")}},
+				{verb: "update", refs: []api.ResourceRef{{APIVersion: "v1", Kind: "PersistentVolumeClaim", Namespace: "default"}}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, migrateAccesses(tc.src, tc.dst))
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s, _ := newTestServer(newCloneSet("recorded", migration.MigrateSucceeded), newCloneSet("private", migration.MigrateSucceeded))
+	handler := s.Handler()
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "submit without token",
+			method:         http.MethodPost,
+			path:           "/migrations",
+			body:           `{"src":{"apiVersion":"apps/v1","kind":"Deployment","namespace":"default","name":"demo"},"dst":{"apiVersion":"apps.kruise.io/v1alpha1","kind":"CloneSet","namespace":"default","name":"demo"}}`,
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"a bearer token is required"}`,
+		},
+		{
+			name:           "submit by a user not allowed to update the workloads",
+			method:         http.MethodPost,
+			path:           "/migrations",
+			token:          "viewer",
+			body:           `{"src":{"apiVersion":"apps/v1","kind":"Deployment","namespace":"default","name":"demo"},"dst":{"apiVersion":"apps.kruise.io/v1alpha1","kind":"CloneSet","namespace":"default","name":"demo"}}`,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"deployments.apps \"demo\" is forbidden: denied"}`,
+		},
+		{
+			name:           "submit",
+			method:         http.MethodPost,
+			path:           "/migrations",
+			token:          "admin",
+			body:           `{"src":{"apiVersion":"apps/v1","kind":"Deployment","namespace":"default","name":"demo"},"dst":{"apiVersion":"apps.kruise.io/v1alpha1","kind":"CloneSet","namespace":"default","name":"demo"},"options":{"maxSurge":2}}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"id":"demo","state":"Executing","srcMigratedReplicas":0,"dstMigratedReplicas":0}`,
+		},
+		{
+			name:           "submit across namespaces",
+			method:         http.MethodPost,
+			path:           "/migrations",
+			token:          "admin",
+			body:           `{"src":{"apiVersion":"apps/v1","kind":"Deployment","namespace":"default","name":"demo"},"dst":{"apiVersion":"apps.kruise.io/v1alpha1","kind":"CloneSet","namespace":"other","name":"demo"}}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"src and dst must be in the same namespace"}`,
+		},
+		{
+			name:           "query without token",
+			method:         http.MethodGet,
+			path:           "/migrations/default/demo",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"a bearer token is required"}`,
+		},
+		{
+			name:           "query a running task",
+			method:         http.MethodGet,
+			path:           "/migrations/default/demo",
+			token:          "viewer",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":"demo","state":"Executing","srcMigratedReplicas":0,"dstMigratedReplicas":0}`,
+		},
+		{
+			name:           "query a recorded task",
+			method:         http.MethodGet,
+			path:           "/migrations/default/recorded",
+			token:          "viewer",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":"recorded","state":"Succeeded","srcMigratedReplicas":0,"dstMigratedReplicas":0}`,
+		},
+		{
+			name:           "query an unknown task",
+			method:         http.MethodGet,
+			path:           "/migrations/default/unknown",
+			token:          "viewer",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"not found ID unknown"}`,
+		},
+		{
+			name:           "query a task by a user not allowed to get the workload",
+			method:         http.MethodGet,
+			path:           "/migrations/default/private",
+			token:          "viewer",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"deployments.apps \"private\" is forbidden: denied"}`,
+		},
+		{
+			name:           "list without token",
+			method:         http.MethodGet,
+			path:           "/migrations?namespace=default",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"a bearer token is required"}`,
+		},
+		{
+			name:           "list the tasks that the user may get",
+			method:         http.MethodGet,
+			path:           "/migrations?namespace=default",
+			token:          "viewer",
+			expectedStatus: http.StatusOK,
+			expectedBody: `[{"src":{"apiVersion":"apps/v1","kind":"Deployment","namespace":"default","name":"recorded"},` +
+				`"dst":{"apiVersion":"apps.kruise.io/v1alpha1","kind":"CloneSet","namespace":"default","name":"recorded"},` +
+				`"options":{},"result":{"id":"recorded","state":"Succeeded","srcMigratedReplicas":0,"dstMigratedReplicas":0},` +
+				`"creationTimestamp":null,"updateTimestamp":null}]`,
+		},
+		{
+			name:           "abort by a user not allowed to update the workloads",
+			method:         http.MethodPost,
+			path:           "/migrations/default/demo/abort",
+			token:          "viewer",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"deployments.apps \"demo\" is forbidden: denied"}`,
+		},
+		{
+			name:           "abort without token",
+			method:         http.MethodPost,
+			path:           "/migrations/default/demo/abort",
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"error":"a bearer token is required"}`,
+		},
+		{
+			name:           "abort",
+			method:         http.MethodPost,
+			path:           "/migrations/default/demo/abort",
+			token:          "admin",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":"demo","state":"Aborted","srcMigratedReplicas":0,"dstMigratedReplicas":0}`,
+		},
+		{
+			name:           "abort a task not running in the server",
+			method:         http.MethodPost,
+			path:           "/migrations/default/recorded/abort",
+			token:          "admin",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"error":"migration task recorded is not running in this server"}`,
+		},
+		{
+			name:           "roll back a recorded task by a user not allowed to update the workloads",
+			method:         http.MethodPost,
+			path:           "/migrations/default/recorded/rollback",
+			token:          "viewer",
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"deployments.apps \"recorded\" is forbidden: denied"}`,
+		},
+		{
+			name:           "roll back a recorded task",
+			method:         http.MethodPost,
+			path:           "/migrations/default/recorded/rollback",
+			token:          "admin",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":"recorded","state":"RollingBack","srcMigratedReplicas":0,"dstMigratedReplicas":0}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if len(tc.token) > 0 {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			assert.JSONEq(t, tc.expectedBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// StateAnnotation is set on the destination workload to record the migration task into it,
+// so that the task can be listed and resumed by another process.
+const StateAnnotation = "migration.kruise.io/state"
+
+// DstKinds are the kinds of workloads that may carry a StateAnnotation.
+var DstKinds = []schema.GroupVersionKind{
+	api.CloneSetKind,
+	api.DeploymentKind,
+	api.AdvancedStatefulSetKind,
+	api.AdvancedDaemonSetKind,
+}
+
+// State is the persisted form of a migration task.
+type State struct {
+	Src     api.ResourceRef `json:"src"`
+	Dst     api.ResourceRef `json:"dst"`
+	Options Options         `json:"options"`
+	Result  Result          `json:"result"`
+	// AbortedState is the state that an aborted task was aborted in.
+	AbortedState MigrateState `json:"abortedState,omitempty"`
+
+	CreationTimestamp metav1.Time `json:"creationTimestamp"`
+	UpdateTimestamp   metav1.Time `json:"updateTimestamp"`
+
+	// Extra is the progress specific to the control that runs the task.
+	Extra json.RawMessage `json:"extra,omitempty"`
+}
+
+// SaveState writes the state into the StateAnnotation of its destination workload.
+func SaveState(c client.Client, state *State) error {
+	state.UpdateTimestamp = metav1.Now()
+	value, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{StateAnnotation: string(value)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(state.Dst.GetGroupVersionKind())
+	obj.SetNamespace(state.Dst.Namespace)
+	obj.SetName(state.Dst.Name)
+	if err := c.Patch(context.TODO(), obj, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		return fmt.Errorf("failed to save migration state into %v: %v", state.Dst, err)
+	}
+	return nil
+}
+
+// LoadState reads the state from the StateAnnotation of the destination workload.
+func LoadState(reader client.Reader, dst api.ResourceRef) (*State, error) {
+	state, err := getRecordedState(reader, dst)
+	if err != nil {
+		return nil, err
+	} else if state == nil {
+		return nil, fmt.Errorf("no migration task recorded in %v", dst)
+	}
+	return state, nil
+}
+
+// CheckNotExecuting returns an error if dst records a task that is still running.
+func CheckNotExecuting(reader client.Reader, dst api.ResourceRef) error {
+	state, err := getRecordedState(reader, dst)
+	if err != nil {
+		return err
+	} else if state != nil && state.Result.State.IsRunning() {
+		return fmt.Errorf("migration task %v is still running for %v, resume it instead", state.Result.ID, dst)
+	}
+	return nil
+}
+
+// ListStates returns the states recorded in all destination workloads in the namespace.
+// An empty namespace lists all namespaces. Kinds that are not installed are skipped.
+func ListStates(reader client.Reader, namespace string) ([]State, error) {
+	var states []State
+	for _, gvk := range DstKinds {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := reader.List(context.TODO(), list, client.InNamespace(namespace)); err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to list %v: %v", gvk, err)
+		}
+
+		for i := range list.Items {
+			state, err := parseState(&list.Items[i])
+			if err != nil {
+				// a malformed state must not hide the other tasks
+				klog.Warningf("Skipped migration state: %v", err)
+			} else if state != nil {
+				states = append(states, *state)
+			}
+		}
+	}
+	return states, nil
+}
+
+// GetState returns the state of the task with the given ID in the namespace.
+func GetState(reader client.Reader, namespace string, ID types.UID) (*State, error) {
+	states, err := ListStates(reader, namespace)
+	if err != nil {
+		return nil, err
+	}
+	for i := range states {
+		if states[i].Result.ID == ID {
+			return &states[i], nil
+		}
+	}
+	return nil, fmt.Errorf("not found ID %v", ID)
+}
+
+func getRecordedState(reader client.Reader, dst api.ResourceRef) (*State, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(dst.GetGroupVersionKind())
+	if err := reader.Get(context.TODO(), dst.GetNamespacedName(), obj); err != nil {
+		return nil, fmt.Errorf("failed to get %v: %v", dst, err)
+	}
+	return parseState(obj)
+}
+
+func parseState(obj client.Object) (*State, error) {
+	value, ok := obj.GetAnnotations()[StateAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	state := &State{}
+	if err := json.Unmarshal([]byte(value), state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s of %s/%s: %v", StateAnnotation, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return state, nil
+}
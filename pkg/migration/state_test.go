@@ -0,0 +1,120 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"encoding/json"
+	"testing"
+
+	appsv1alpha1 "github.com/openkruise/kruise-api/apps/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseState(t *testing.T) {
+	replicas := int32(5)
+	state := State{
+		Src:     api.NewDeploymentRef("default", "nginx"),
+		Dst:     api.NewCloneSetRef("default", "nginx"),
+		Options: Options{Replicas: &replicas},
+		Result: Result{
+			ID:                  "1f8a5c62-0d1e-4b8c-9a61-3c2b7e0f4d55",
+			State:               MigrateExecuting,
+			SrcMigratedReplicas: 2,
+			DstMigratedReplicas: 3,
+		},
+		Extra: json.RawMessage(`{"srcReplicas":5}`),
+	}
+	value, err := json.Marshal(state)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    *State
+		expectErr   bool
+	}{
+		{
+			name:     "no state recorded",
+			expected: nil,
+		},
+		{
+			name:        "state recorded",
+			annotations: map[string]string{StateAnnotation: string(value)},
+			expected:    &state,
+		},
+		{
+			name:        "invalid state",
+			annotations: map[string]string{StateAnnotation: "{"},
+			expectErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &apps.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nginx", Annotations: tt.annotations}}
+			got, err := parseState(obj)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if tt.expected == nil {
+				assert.Nil(t, got)
+				return
+			}
+			assert.Equal(t, tt.expected.Src, got.Src)
+			assert.Equal(t, tt.expected.Dst, got.Dst)
+			assert.Equal(t, tt.expected.Options, got.Options)
+			assert.Equal(t, tt.expected.Result, got.Result)
+			assert.JSONEq(t, string(tt.expected.Extra), string(got.Extra))
+		})
+	}
+}
+
+func TestListStates(t *testing.T) {
+	state := State{
+		Src:    api.NewDeploymentRef("default", "nginx"),
+		Dst:    api.NewCloneSetRef("default", "nginx"),
+		Result: Result{ID: "1f8a5c62-0d1e-4b8c-9a61-3c2b7e0f4d55", State: MigrateExecuting},
+	}
+	value, err := json.Marshal(state)
+	assert.NoError(t, err)
+	c := fake.NewClientBuilder().WithScheme(api.GetScheme()).WithObjects(
+		&appsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nginx",
+			Annotations: map[string]string{StateAnnotation: string(value)}}},
+		&appsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "malformed",
+			Annotations: map[string]string{StateAnnotation: "{"}}},
+		&appsv1alpha1.CloneSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "unmigrated"}},
+	).Build()
+
+	// the malformed state is skipped
+	states, err := ListStates(c, "default")
+	assert.NoError(t, err)
+	if assert.Len(t, states, 1) {
+		assert.Equal(t, state.Dst, states[0].Dst)
+		assert.Equal(t, state.Result, states[0].Result)
+	}
+
+	got, err := GetState(c, "default", state.Result.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, state.Src, got.Src)
+}
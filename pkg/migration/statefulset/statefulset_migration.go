@@ -0,0 +1,435 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statefulset
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+	"github.com/openkruise/kruise-tools/pkg/utils"
+)
+
+// control migrates pods from a StatefulSet to an Advanced StatefulSet with the same name.
+// Pod and PVC names are derived from the workload name and the ordinal, so an ordinal can
+// never run in both workloads at the same time. Ordinals are handed over from the highest
+// one downwards: the source is scaled in first, and once its pods are gone the destination
+// takes the freed ordinals over by shrinking its reserveOrdinals. The pods of an ordinal are
+// always unavailable while being handed over, so the larger one of MaxSurge and MaxUnavailable
+// is used as the number of ordinals handed over in each step.
+type control struct {
+	*migration.Controller[taskExtra]
+}
+
+// task is a migration task from a StatefulSet to an Advanced StatefulSet.
+type task = migration.Task[taskExtra]
+
+// taskExtra is the part of a task that is recorded in migration.State.Extra.
+type taskExtra struct {
+	// SrcReplicas and StartOrdinal describe the ordinals [StartOrdinal, StartOrdinal+SrcReplicas)
+	// owned by the source when the task was submitted.
+	SrcReplicas  int32 `json:"srcReplicas"`
+	StartOrdinal int32 `json:"startOrdinal"`
+}
+
+var _ migration.Control = &control{}
+var _ migration.AutoscaledHandover[taskExtra] = &control{}
+
+func NewControl(cfg *rest.Config, stopChan <-chan struct{}, opts migration.ControlOptions) (migration.Control, error) {
+	c, informerCache, err := migration.NewClientAndCache(cfg)
+	if err != nil {
+		return nil, err
+	}
+	recorder, err := migration.StartEventRecorder(cfg, stopChan)
+	if err != nil {
+		return nil, err
+	}
+	ctrl := newControl(c, informerCache, informerCache, recorder, opts)
+	ctrl.Start(stopChan)
+	return ctrl, nil
+}
+
+func newControl(c client.Client, reader client.Reader, informers cache.Informers, recorder record.EventRecorder, opts migration.ControlOptions) *control {
+	ctrl := &control{}
+	ctrl.Controller = migration.NewController[taskExtra](c, reader, informers, recorder, "statefulset-migration-control", opts, ctrl)
+	return ctrl
+}
+
+func (c *control) Submit(src api.ResourceRef, dst api.ResourceRef, opts migration.Options) (migration.Result, error) {
+	t, err := c.newTask(src, dst, opts)
+	if err != nil {
+		return migration.Result{}, err
+	}
+	return c.StartTask(t)
+}
+
+func (c *control) Plan(src api.ResourceRef, dst api.ResourceRef, opts migration.Options, serverDryRun bool) (migration.Plan, error) {
+	t, err := c.newTask(src, dst, opts)
+	if err != nil {
+		return migration.Plan{}, err
+	}
+
+	steps, err := planSteps(t)
+	if err != nil {
+		return migration.Plan{}, err
+	}
+
+	if serverDryRun {
+		srcStatefulSet, dstStatefulSet, err := getStatefulSetObjects(c.Client, &t.Src, &t.Dst)
+		if err != nil {
+			return migration.Plan{}, err
+		}
+		srcReplicas := t.Extra.SrcReplicas - *t.Opts.Replicas
+		srcStatefulSet.Spec.Replicas = &srcReplicas
+		dstStatefulSet.Spec.Replicas = t.Opts.Replicas
+		dstStatefulSet.Spec.ReserveOrdinals = reservedOrdinals(t.Extra.StartOrdinal, t.Extra.StartOrdinal+srcReplicas-1)
+		if err := c.Client.Update(context.TODO(), srcStatefulSet, client.DryRunAll); err != nil {
+			return migration.Plan{}, fmt.Errorf("failed to scale %v: %v", t.Src, err)
+		}
+		if err := c.Client.Update(context.TODO(), dstStatefulSet, client.DryRunAll); err != nil {
+			return migration.Plan{}, fmt.Errorf("failed to scale %v: %v", t.Dst, err)
+		}
+	}
+
+	return migration.Plan{Src: t.Src, Dst: t.Dst, Options: t.Opts, Steps: steps}, nil
+}
+
+func (c *control) newTask(src api.ResourceRef, dst api.ResourceRef, opts migration.Options) (*task, error) {
+	if opts.Replicas != nil && *opts.Replicas <= 0 {
+		return nil, fmt.Errorf("invalid replicas %v", *opts.Replicas)
+	} else if src.GetGroupVersionKind() != api.StatefulSetKind {
+		return nil, fmt.Errorf("invalid src type, currently only support %v", api.StatefulSetKind.String())
+	} else if dst.GetGroupVersionKind() != api.AdvancedStatefulSetKind {
+		return nil, fmt.Errorf("invalid dst type, must be %v", api.AdvancedStatefulSetKind.String())
+	} else if src.Namespace != dst.Namespace || src.Name != dst.Name {
+		return nil, fmt.Errorf("dst %v must have the same namespace and name as src %v to keep pod and PVC names", dst, src)
+	}
+
+	if err := migration.CheckNotExecuting(c.Client, dst); err != nil {
+		return nil, err
+	}
+	srcStatefulSet, dstStatefulSet, err := getStatefulSetObjects(c.Client, &src, &dst)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateStatefulSets(srcStatefulSet, dstStatefulSet); err != nil {
+		return nil, err
+	}
+
+	if opts.Replicas == nil {
+		opts.Replicas = srcStatefulSet.Spec.Replicas
+	}
+	if *opts.Replicas > *srcStatefulSet.Spec.Replicas {
+		return nil, fmt.Errorf("replicas %v is more than %v replicas %v", *opts.Replicas, src, *srcStatefulSet.Spec.Replicas)
+	}
+
+	extra := taskExtra{SrcReplicas: *srcStatefulSet.Spec.Replicas}
+	if srcStatefulSet.Spec.Ordinals != nil {
+		extra.StartOrdinal = srcStatefulSet.Spec.Ordinals.Start
+	}
+	t, err := migration.NewTask(src, dst, opts, extra)
+	if err != nil {
+		return nil, err
+	}
+	t.SrcUpdatedGeneration = srcStatefulSet.Generation
+	t.DstUpdatedGeneration = dstStatefulSet.Generation
+	return t, nil
+}
+
+// Resume continues the task recorded in dst. The migrated replicas are recalculated from
+// the current replicas of both workloads, since the recorded result may lag behind them.
+func (c *control) Resume(dst api.ResourceRef) (migration.Result, error) {
+	return c.ResumeTask(dst, func(t *task) (int32, int32, error) {
+		srcStatefulSet, dstStatefulSet, err := getStatefulSetObjects(c.Client, &t.Src, &t.Dst)
+		if err != nil {
+			return 0, 0, err
+		}
+		t.SrcUpdatedGeneration = srcStatefulSet.Generation
+		t.DstUpdatedGeneration = dstStatefulSet.Generation
+		return utils.Int32Max(0, utils.Int32Min(t.Extra.SrcReplicas-*srcStatefulSet.Spec.Replicas, *t.Opts.Replicas)),
+			utils.Int32Max(0, utils.Int32Min(*dstStatefulSet.Spec.Replicas, *t.Opts.Replicas)), nil
+	})
+}
+
+// Reconcile hands the next batch of ordinals over to dst, or back to src when rolling back.
+func (c *control) Reconcile(task *task) error {
+	result := task.Result()
+	switch result.State {
+	case migration.MigrateExecuting:
+		if result.DstMigratedReplicas == *task.Opts.Replicas && result.SrcMigratedReplicas == *task.Opts.Replicas {
+			c.FinishTask(task, migration.MigrateSucceeded, "")
+			return nil
+		}
+	case migration.MigrateRollingBack:
+		if result.DstMigratedReplicas == 0 && result.SrcMigratedReplicas == 0 {
+			c.FinishTask(task, migration.MigrateRolledBack, "")
+			return nil
+		}
+	}
+
+	srcStatefulSet, dstStatefulSet, err := getStatefulSetObjects(c.Reader, &task.Src, &task.Dst)
+	if err != nil {
+		c.FinishTask(task, migration.MigrateFailed, err.Error())
+		return nil
+	}
+
+	if srcStatefulSet.Generation < task.SrcUpdatedGeneration || dstStatefulSet.Generation < task.DstUpdatedGeneration {
+		// cache has not synced
+		return nil
+	} else if srcStatefulSet.Generation != srcStatefulSet.Status.ObservedGeneration || dstStatefulSet.Generation != dstStatefulSet.Status.ObservedGeneration {
+		// workload controller has not reconciled
+		return nil
+	}
+
+	if result.State == migration.MigrateRollingBack {
+		return c.rollback(task, result, srcStatefulSet, dstStatefulSet)
+	}
+
+	// ordinals released by src need to be taken over by dst
+	if result.SrcMigratedReplicas > result.DstMigratedReplicas {
+		firstOrdinal := task.Extra.StartOrdinal + task.Extra.SrcReplicas - result.SrcMigratedReplicas
+		lastOrdinal := task.Extra.StartOrdinal + task.Extra.SrcReplicas - result.DstMigratedReplicas - 1
+
+		released, err := c.releaseOrdinals(srcStatefulSet, srcStatefulSet.Spec.VolumeClaimTemplates, firstOrdinal, lastOrdinal)
+		if err != nil {
+			return err
+		} else if !released {
+			// pods have not been deleted by src yet, wait for the next event
+			c.Requeue(task, time.Second)
+			return nil
+		}
+
+		dstReplicas := result.SrcMigratedReplicas
+		dstStatefulSet.Spec.Replicas = &dstReplicas
+		dstStatefulSet.Spec.ReserveOrdinals = reservedOrdinals(task.Extra.StartOrdinal, firstOrdinal-1)
+		if err := c.Client.Update(context.TODO(), dstStatefulSet); err != nil {
+			return err
+		}
+		task.DstUpdatedGeneration = dstStatefulSet.Generation
+		c.UpdateTask(task, 0, result.SrcMigratedReplicas-result.DstMigratedReplicas)
+		return nil
+	}
+
+	// src need scale in, but only after all pods in dst are available
+	if maxScaleIn := nextScaleIn(task, result, *srcStatefulSet.Spec.Replicas); maxScaleIn > 0 && *dstStatefulSet.Spec.Replicas == dstStatefulSet.Status.AvailableReplicas {
+		*srcStatefulSet.Spec.Replicas -= maxScaleIn
+		if err := c.Client.Update(context.TODO(), srcStatefulSet); err != nil {
+			return err
+		}
+		task.SrcUpdatedGeneration = srcStatefulSet.Generation
+		c.UpdateTask(task, maxScaleIn, 0)
+		return nil
+	}
+
+	return nil
+}
+
+// planSteps simulates reconcile from the initial replicas of the task, assuming that
+// all pods become available right after each step.
+func planSteps(t *task) ([]migration.Step, error) {
+	return migration.PlanSteps(t.Extra.SrcReplicas, 0, t.Opts, func(result migration.Result) (migration.StepAction, int32) {
+		if result.SrcMigratedReplicas > result.DstMigratedReplicas {
+			return migration.StepScaleOutDst, result.SrcMigratedReplicas - result.DstMigratedReplicas
+		}
+		return migration.StepScaleInSrc, nextScaleIn(t, result, t.Extra.SrcReplicas-result.SrcMigratedReplicas)
+	})
+}
+
+// nextScaleIn returns the replicas that src can scale in by, which is the number of ordinals
+// handed over to dst in the next step.
+func nextScaleIn(t *task, result migration.Result, srcReplicas int32) int32 {
+	deltaReplicas := *t.Opts.Replicas - result.SrcMigratedReplicas
+	return utils.Int32Max(0, utils.Int32Min(srcReplicas, deltaReplicas, batchSize(t)))
+}
+
+// batchSize returns the number of ordinals handed over in each step. The pods of an ordinal are always
+// unavailable while being handed over, so it is the larger one of MaxSurge and MaxUnavailable.
+func batchSize(t *task) int32 {
+	return utils.Int32Max(t.MaxSurge, t.MaxUnavailable)
+}
+
+// rollback hands the ordinals back from dst to src from the lowest one upwards, which is the
+// reverse of migration: dst is scaled in first by reserving its lowest ordinals, and once its
+// pods are gone src takes the freed ordinals back by scaling out.
+func (c *control) rollback(task *task, result migration.Result, srcStatefulSet *apps.StatefulSet, dstStatefulSet *appsv1beta1.StatefulSet) error {
+	// ordinals released by dst, or by src before dst took them over, need to be taken back by src
+	if result.SrcMigratedReplicas > result.DstMigratedReplicas {
+		firstOrdinal := task.Extra.StartOrdinal + task.Extra.SrcReplicas - result.SrcMigratedReplicas
+		lastOrdinal := task.Extra.StartOrdinal + task.Extra.SrcReplicas - result.DstMigratedReplicas - 1
+
+		released, err := c.releaseOrdinals(dstStatefulSet, dstStatefulSet.Spec.VolumeClaimTemplates, firstOrdinal, lastOrdinal)
+		if err != nil {
+			return err
+		} else if !released {
+			// pods have not been deleted by dst yet, wait for the next event
+			c.Requeue(task, time.Second)
+			return nil
+		}
+
+		srcReplicas := task.Extra.SrcReplicas - result.DstMigratedReplicas
+		srcStatefulSet.Spec.Replicas = &srcReplicas
+		if err := c.Client.Update(context.TODO(), srcStatefulSet); err != nil {
+			return err
+		}
+		task.SrcUpdatedGeneration = srcStatefulSet.Generation
+		c.UpdateTask(task, result.DstMigratedReplicas-result.SrcMigratedReplicas, 0)
+		return nil
+	}
+
+	// dst need scale in, but only after all pods in src are available
+	if result.DstMigratedReplicas > 0 && *srcStatefulSet.Spec.Replicas == srcStatefulSet.Status.AvailableReplicas {
+		if policy := dstStatefulSet.Spec.PersistentVolumeClaimRetentionPolicy; policy != nil &&
+			policy.WhenScaled == appsv1beta1.DeletePersistentVolumeClaimRetentionPolicyType {
+			c.FinishTask(task, migration.MigrateFailed, fmt.Sprintf("advanced statefulset %s/%s deletes PVCs when scaled, can not roll back",
+				dstStatefulSet.Namespace, dstStatefulSet.Name))
+			return nil
+		}
+
+		maxScaleIn := utils.Int32Min(*dstStatefulSet.Spec.Replicas, result.DstMigratedReplicas, batchSize(task))
+		if maxScaleIn > 0 {
+			dstReplicas := *dstStatefulSet.Spec.Replicas - maxScaleIn
+			dstStatefulSet.Spec.Replicas = &dstReplicas
+			dstStatefulSet.Spec.ReserveOrdinals = nil
+			if dstReplicas > 0 {
+				dstStatefulSet.Spec.ReserveOrdinals = reservedOrdinals(task.Extra.StartOrdinal, task.Extra.StartOrdinal+task.Extra.SrcReplicas-dstReplicas-1)
+			}
+			if err := c.Client.Update(context.TODO(), dstStatefulSet); err != nil {
+				return err
+			}
+			task.DstUpdatedGeneration = dstStatefulSet.Generation
+			c.UpdateTask(task, 0, -maxScaleIn)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// releaseOrdinals checks that the pods of the given ordinals have been deleted by owner, and removes
+// the owner references of owner from their PVCs, so that deleting owner later will not garbage collect
+// the volumes that the other workload adopts.
+func (c *control) releaseOrdinals(owner client.Object, templates []v1.PersistentVolumeClaim, firstOrdinal, lastOrdinal int32) (bool, error) {
+	for ordinal := firstOrdinal; ordinal <= lastOrdinal; ordinal++ {
+		pod := &v1.Pod{}
+		podName := fmt.Sprintf("%s-%d", owner.GetName(), ordinal)
+		if err := c.Client.Get(context.TODO(), types.NamespacedName{Namespace: owner.GetNamespace(), Name: podName}, pod); err == nil {
+			if metav1.IsControlledBy(pod, owner) {
+				return false, nil
+			}
+		} else if !errors.IsNotFound(err) {
+			return false, err
+		}
+
+		for i := range templates {
+			pvc := &v1.PersistentVolumeClaim{}
+			pvcName := fmt.Sprintf("%s-%s", templates[i].Name, podName)
+			if err := c.Client.Get(context.TODO(), types.NamespacedName{Namespace: owner.GetNamespace(), Name: pvcName}, pvc); err != nil {
+				if errors.IsNotFound(err) {
+					continue
+				}
+				return false, err
+			}
+
+			var ownerReferences []metav1.OwnerReference
+			for _, ref := range pvc.OwnerReferences {
+				if ref.UID != owner.GetUID() {
+					ownerReferences = append(ownerReferences, ref)
+				}
+			}
+			if len(ownerReferences) == len(pvc.OwnerReferences) {
+				continue
+			}
+			pvc.OwnerReferences = ownerReferences
+			if err := c.Client.Update(context.TODO(), pvc); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// AutoscalerTarget returns the workload that the autoscalers suspended by the task are retargeted to once it finished
+// with result, which is the one left with all the replicas, or nil to keep their targets.
+func (c *control) AutoscalerTarget(t *task, result migration.Result) *api.ResourceRef {
+	switch {
+	case result.State == migration.MigrateSucceeded && result.SrcMigratedReplicas >= t.Extra.SrcReplicas:
+		return &t.Dst
+	case result.State == migration.MigrateRolledBack:
+		return &t.Src
+	}
+	return nil
+}
+
+// validateStatefulSets checks that the ordinals of src can be handed over to dst one by one.
+func validateStatefulSets(src *apps.StatefulSet, dst *appsv1beta1.StatefulSet) error {
+	if policy := src.Spec.PersistentVolumeClaimRetentionPolicy; policy != nil && policy.WhenScaled == apps.DeletePersistentVolumeClaimRetentionPolicyType {
+		return fmt.Errorf("statefulset %s/%s deletes PVCs when scaled, set persistentVolumeClaimRetentionPolicy.whenScaled to Retain first", src.Namespace, src.Name)
+	}
+	if dst.Spec.Replicas == nil || *dst.Spec.Replicas != 0 {
+		return fmt.Errorf("advanced statefulset %s/%s must have zero replicas before migration", dst.Namespace, dst.Name)
+	}
+
+	var srcStart, dstStart int32
+	if src.Spec.Ordinals != nil {
+		srcStart = src.Spec.Ordinals.Start
+	}
+	if dst.Spec.Ordinals != nil {
+		dstStart = dst.Spec.Ordinals.Start
+	}
+	if srcStart != dstStart {
+		return fmt.Errorf("advanced statefulset %s/%s must start ordinals from %d like the statefulset", dst.Namespace, dst.Name, srcStart)
+	}
+	return nil
+}
+
+// reservedOrdinals returns the reserveOrdinals that keep dst away from [first, last].
+func reservedOrdinals(first, last int32) []intstr.IntOrString {
+	if last < first {
+		return nil
+	} else if last == first {
+		return []intstr.IntOrString{intstr.FromInt32(first)}
+	}
+	return []intstr.IntOrString{intstr.FromString(fmt.Sprintf("%d-%d", first, last))}
+}
+
+func getStatefulSetObjects(reader client.Reader, src, dst *api.ResourceRef) (*apps.StatefulSet, *appsv1beta1.StatefulSet, error) {
+	srcStatefulSet := apps.StatefulSet{}
+	if err := reader.Get(context.TODO(), src.GetNamespacedName(), &srcStatefulSet); err != nil {
+		return nil, nil, fmt.Errorf("failed to get %v: %v", src, err)
+	}
+
+	dstStatefulSet := appsv1beta1.StatefulSet{}
+	if err := reader.Get(context.TODO(), dst.GetNamespacedName(), &dstStatefulSet); err != nil {
+		return nil, nil, fmt.Errorf("failed to get %v: %v", dst, err)
+	}
+
+	return &srcStatefulSet, &dstStatefulSet, nil
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statefulset
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	appsv1beta1 "github.com/openkruise/kruise-api/apps/v1beta1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/openkruise/kruise-tools/pkg/migration"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReservedOrdinals(t *testing.T) {
+	testCases := []struct {
+		name     string
+		first    int32
+		last     int32
+		expected []intstr.IntOrString
+	}{
+		{
+			name:     "nothing to reserve",
+			first:    0,
+			last:     -1,
+			expected: nil,
+		},
+		{
+			name:     "single ordinal",
+			first:    2,
+			last:     2,
+			expected: []intstr.IntOrString{intstr.FromInt32(2)},
+		},
+		{
+			name:     "ordinal range",
+			first:    1,
+			last:     4,
+			expected: []intstr.IntOrString{intstr.FromString("1-4")},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, reservedOrdinals(tc.first, tc.last))
+		})
+	}
+}
+
+func TestValidateStatefulSets(t *testing.T) {
+	newStatefulSet := func() *apps.StatefulSet {
+		sts := &apps.StatefulSet{}
+		sts.Spec.Replicas = ptr.To[int32](3)
+		return sts
+	}
+	newAdvancedStatefulSet := func() *appsv1beta1.StatefulSet {
+		asts := &appsv1beta1.StatefulSet{}
+		asts.Spec.Replicas = ptr.To[int32](0)
+		return asts
+	}
+
+	testCases := []struct {
+		name        string
+		src         func() *apps.StatefulSet
+		dst         func() *appsv1beta1.StatefulSet
+		expectedErr bool
+	}{
+		{
+			name: "valid",
+			src:  newStatefulSet,
+			dst:  newAdvancedStatefulSet,
+		},
+		{
+			name: "src deletes PVCs when scaled",
+			src: func() *apps.StatefulSet {
+				sts := newStatefulSet()
+				sts.Spec.PersistentVolumeClaimRetentionPolicy = &apps.StatefulSetPersistentVolumeClaimRetentionPolicy{
+					WhenScaled: apps.DeletePersistentVolumeClaimRetentionPolicyType,
+				}
+				return sts
+			},
+			dst:         newAdvancedStatefulSet,
+			expectedErr: true,
+		},
+		{
+			name: "dst already has replicas",
+			src:  newStatefulSet,
+			dst: func() *appsv1beta1.StatefulSet {
+				asts := newAdvancedStatefulSet()
+				asts.Spec.Replicas = ptr.To[int32](1)
+				return asts
+			},
+			expectedErr: true,
+		},
+		{
+			name: "different start ordinals",
+			src: func() *apps.StatefulSet {
+				sts := newStatefulSet()
+				sts.Spec.Ordinals = &apps.StatefulSetOrdinals{Start: 1}
+				return sts
+			},
+			dst:         newAdvancedStatefulSet,
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateStatefulSets(tc.src(), tc.dst())
+			assert.Equal(t, tc.expectedErr, err != nil, "unexpected error: %v", err)
+		})
+	}
+}
+
+func TestPlanSteps(t *testing.T) {
+	steps, err := planSteps(&task{
+		Opts:     migration.Options{Replicas: ptr.To[int32](3)},
+		MaxSurge: 2,
+		Extra:    taskExtra{SrcReplicas: 3},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []migration.Step{
+		{Action: migration.StepScaleInSrc, Replicas: 2, SrcReplicas: 1, DstReplicas: 0},
+		{Action: migration.StepScaleOutDst, Replicas: 2, SrcReplicas: 1, DstReplicas: 2},
+		{Action: migration.StepScaleInSrc, Replicas: 1, SrcReplicas: 0, DstReplicas: 2},
+		{Action: migration.StepScaleOutDst, Replicas: 1, SrcReplicas: 0, DstReplicas: 3},
+	}, steps)
+}
+
+func TestRollback(t *testing.T) {
+	src, dst := api.NewStatefulSetRef("default", "web"), api.NewAdvancedStatefulSetRef("default", "web")
+	templates := []v1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: "data"}}}
+	srcStatefulSet := &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "src-uid"},
+		Spec:       apps.StatefulSetSpec{Replicas: ptr.To[int32](2), VolumeClaimTemplates: templates},
+		Status:     apps.StatefulSetStatus{AvailableReplicas: 2},
+	}
+	dstStatefulSet := &appsv1beta1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "dst-uid"},
+		Spec: appsv1beta1.StatefulSetSpec{
+			Replicas:             ptr.To[int32](2),
+			ReserveOrdinals:      []intstr.IntOrString{intstr.FromString("0-1")},
+			VolumeClaimTemplates: templates,
+		},
+		Status: appsv1beta1.StatefulSetStatus{AvailableReplicas: 2},
+	}
+
+	// ordinals 2 and 3 of 4 replicas have been handed over to dst
+	objects := []client.Object{srcStatefulSet, dstStatefulSet}
+	for ordinal := 0; ordinal < 4; ordinal++ {
+		ownerRef := *metav1.NewControllerRef(srcStatefulSet, api.StatefulSetKind)
+		if ordinal >= 2 {
+			ownerRef = *metav1.NewControllerRef(dstStatefulSet, api.AdvancedStatefulSetKind)
+		}
+		objects = append(objects,
+			&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: fmt.Sprintf("web-%d", ordinal),
+				OwnerReferences: []metav1.OwnerReference{ownerRef}}},
+			&v1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: fmt.Sprintf("data-web-%d", ordinal),
+				OwnerReferences: []metav1.OwnerReference{ownerRef}}},
+		)
+	}
+	c := fake.NewClientBuilder().WithScheme(api.GetScheme()).WithObjects(objects...).
+		WithStatusSubresource(&apps.StatefulSet{}, &appsv1beta1.StatefulSet{}).Build()
+	ctrl := newControl(c, c, &informertest.FakeInformers{Scheme: api.GetScheme()}, &record.FakeRecorder{}, migration.ControlOptions{})
+
+	task, err := migration.NewTask(src, dst, migration.Options{Replicas: ptr.To[int32](4)}, taskExtra{SrcReplicas: 4})
+	assert.NoError(t, err)
+	_, err = ctrl.StartTask(task)
+	assert.NoError(t, err)
+	ctrl.UpdateTask(task, 2, 2)
+	_, err = ctrl.Rollback(task.ID)
+	assert.NoError(t, err)
+
+	for i := 0; i < 10 && task.Result().State == migration.MigrateRollingBack; i++ {
+		assert.NoError(t, ctrl.Reconcile(task))
+
+		// dst deletes the pods of the ordinals it has given back, which src recreates and makes available
+		assert.NoError(t, c.Get(context.TODO(), src.GetNamespacedName(), srcStatefulSet))
+		assert.NoError(t, c.Get(context.TODO(), dst.GetNamespacedName(), dstStatefulSet))
+		for ordinal := 2; ordinal < 4-int(*dstStatefulSet.Spec.Replicas); ordinal++ {
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: fmt.Sprintf("web-%d", ordinal)}}
+			if err := c.Delete(context.TODO(), pod); err != nil && !errors.IsNotFound(err) {
+				assert.NoError(t, err)
+			}
+		}
+		srcStatefulSet.Status.AvailableReplicas = *srcStatefulSet.Spec.Replicas
+		assert.NoError(t, c.Status().Update(context.TODO(), srcStatefulSet))
+	}
+
+	assert.Equal(t, migration.MigrateRolledBack, task.Result().State)
+	assert.Equal(t, int32(4), *srcStatefulSet.Spec.Replicas)
+	assert.Equal(t, int32(0), *dstStatefulSet.Spec.Replicas)
+	assert.Nil(t, dstStatefulSet.Spec.ReserveOrdinals)
+	for ordinal := 0; ordinal < 4; ordinal++ {
+		pvc := &v1.PersistentVolumeClaim{}
+		assert.NoError(t, c.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("data-web-%d", ordinal)}, pvc))
+		for _, ref := range pvc.OwnerReferences {
+			assert.NotEqual(t, types.UID("dst-uid"), ref.UID, "%s is still owned by dst", pvc.Name)
+		}
+	}
+}
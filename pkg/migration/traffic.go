@@ -0,0 +1,178 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	policyv1alpha1 "github.com/openkruise/kruise-api/policy/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PodTarget is a workload together with the labels of the pods it creates.
+type PodTarget struct {
+	Ref    api.ResourceRef
+	Labels map[string]string
+}
+
+// CheckTraffic returns the problems found in the Services, PodDisruptionBudgets and PodUnavailableBudgets
+// in the namespace of src, which select the pods of src but not the pods of dst, so that the pods scaled
+// out by dst would receive no traffic or would not be protected from disruption.
+func CheckTraffic(reader client.Reader, src, dst PodTarget) ([]string, error) {
+	var problems []string
+
+	services, err := ServicesSelecting(reader, src.Ref.Namespace, src.Labels)
+	if err != nil {
+		return nil, err
+	}
+	for _, svc := range services {
+		if !labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(dst.Labels)) {
+			problems = append(problems, fmt.Sprintf("Service %s selects the pods of %s %s but not those of %s %s",
+				svc.Name, src.Ref.Kind, src.Ref.Name, dst.Ref.Kind, dst.Ref.Name))
+		}
+	}
+
+	pdbList := &policyv1.PodDisruptionBudgetList{}
+	if err := reader.List(context.TODO(), pdbList, client.InNamespace(src.Ref.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list PodDisruptionBudgets: %v", err)
+	}
+	for i := range pdbList.Items {
+		pdb := &pdbList.Items[i]
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse selector of PodDisruptionBudget %s: %v", pdb.Name, err)
+		}
+		if selector.Matches(labels.Set(src.Labels)) && !selector.Matches(labels.Set(dst.Labels)) {
+			problems = append(problems, fmt.Sprintf("PodDisruptionBudget %s selects the pods of %s %s but not those of %s %s",
+				pdb.Name, src.Ref.Kind, src.Ref.Name, dst.Ref.Kind, dst.Ref.Name))
+		}
+	}
+
+	pubList := &policyv1alpha1.PodUnavailableBudgetList{}
+	if err := reader.List(context.TODO(), pubList, client.InNamespace(src.Ref.Namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			// Kruise is installed without PodUnavailableBudget
+			return problems, nil
+		}
+		return nil, fmt.Errorf("failed to list PodUnavailableBudgets: %v", err)
+	}
+	var srcTargetedBy []string
+	var dstCovered bool
+	for i := range pubList.Items {
+		pub := &pubList.Items[i]
+		coversSrc, err := pubCovers(pub, src)
+		if err != nil {
+			return nil, err
+		}
+		coversDst, err := pubCovers(pub, dst)
+		if err != nil {
+			return nil, err
+		}
+		dstCovered = dstCovered || coversDst
+
+		if coversSrc && !coversDst {
+			if pub.Spec.TargetReference != nil {
+				srcTargetedBy = append(srcTargetedBy, pub.Name)
+				continue
+			}
+			problems = append(problems, fmt.Sprintf("PodUnavailableBudget %s selects the pods of %s %s but not those of %s %s",
+				pub.Name, src.Ref.Kind, src.Ref.Name, dst.Ref.Kind, dst.Ref.Name))
+		}
+	}
+	// a budget that targets src by reference can never cover dst, another one has to
+	if !dstCovered {
+		for _, name := range srcTargetedBy {
+			problems = append(problems, fmt.Sprintf("PodUnavailableBudget %s targets %s %s but no PodUnavailableBudget covers %s %s",
+				name, src.Ref.Kind, src.Ref.Name, dst.Ref.Kind, dst.Ref.Name))
+		}
+	}
+
+	return problems, nil
+}
+
+// pubCovers returns whether the PodUnavailableBudget protects the pods of target, by reference or by selector.
+func pubCovers(pub *policyv1alpha1.PodUnavailableBudget, target PodTarget) (bool, error) {
+	if ref := pub.Spec.TargetReference; ref != nil {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse targetRef of PodUnavailableBudget %s: %v", pub.Name, err)
+		}
+		gvk := target.Ref.GetGroupVersionKind()
+		return gv.Group == gvk.Group && ref.Kind == gvk.Kind && ref.Name == target.Ref.Name, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(pub.Spec.Selector)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse selector of PodUnavailableBudget %s: %v", pub.Name, err)
+	}
+	return selector.Matches(labels.Set(target.Labels)), nil
+}
+
+// ServicesSelecting returns the Services in the namespace whose selector matches the pod labels.
+// Services without selector are skipped, since their endpoints are not managed by Kubernetes.
+func ServicesSelecting(reader client.Reader, namespace string, podLabels map[string]string) ([]*v1.Service, error) {
+	svcList := &v1.ServiceList{}
+	if err := reader.List(context.TODO(), svcList, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list Services: %v", err)
+	}
+
+	var services []*v1.Service
+	for i := range svcList.Items {
+		svc := &svcList.Items[i]
+		if len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		if labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(podLabels)) {
+			services = append(services, svc)
+		}
+	}
+	return services, nil
+}
+
+// ReadyEndpointPods returns the names of the pods that are ready endpoints of the Service.
+func ReadyEndpointPods(reader client.Reader, svc *v1.Service) (sets.Set[string], error) {
+	sliceList := &discoveryv1.EndpointSliceList{}
+	if err := reader.List(context.TODO(), sliceList, client.InNamespace(svc.Namespace),
+		client.MatchingLabels{discoveryv1.LabelServiceName: svc.Name}); err != nil {
+		return nil, fmt.Errorf("failed to list EndpointSlices of Service %s: %v", svc.Name, err)
+	}
+
+	pods := sets.New[string]()
+	for i := range sliceList.Items {
+		for _, ep := range sliceList.Items[i].Endpoints {
+			if ep.TargetRef == nil || ep.TargetRef.Kind != "Pod" {
+				continue
+			}
+			// a nil ready condition is to be interpreted as ready
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				continue
+			}
+			pods.Insert(ep.TargetRef.Name)
+		}
+	}
+	return pods, nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"testing"
+
+	policyv1alpha1 "github.com/openkruise/kruise-api/policy/v1alpha1"
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCheckTraffic(t *testing.T) {
+	src := PodTarget{Ref: api.NewDeploymentRef("default", "demo"), Labels: map[string]string{"app": "demo", "kind": "deployment"}}
+	dst := PodTarget{Ref: api.NewCloneSetRef("default", "demo"), Labels: map[string]string{"app": "demo"}}
+
+	service := func(name string, selector map[string]string) client.Object {
+		return &v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}, Spec: v1.ServiceSpec{Selector: selector}}
+	}
+	pdb := func(name string, selector map[string]string) client.Object {
+		return &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: selector}}}
+	}
+	pub := func(name string, ref *api.ResourceRef) client.Object {
+		return &policyv1alpha1.PodUnavailableBudget{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: policyv1alpha1.PodUnavailableBudgetSpec{TargetReference: &policyv1alpha1.TargetReference{
+				APIVersion: ref.APIVersion, Kind: ref.Kind, Name: ref.Name}}}
+	}
+
+	testCases := []struct {
+		name             string
+		objects          []client.Object
+		expectedProblems []string
+	}{
+		{
+			name: "covered by selectors",
+			objects: []client.Object{
+				service("demo", map[string]string{"app": "demo"}),
+				service("headless", nil),
+				service("other", map[string]string{"app": "other"}),
+				pdb("demo", map[string]string{"app": "demo"}),
+			},
+		},
+		{
+			name: "not selected",
+			objects: []client.Object{
+				service("demo", map[string]string{"kind": "deployment"}),
+				pdb("demo", map[string]string{"kind": "deployment"}),
+			},
+			expectedProblems: []string{
+				"Service demo selects the pods of Deployment demo but not those of CloneSet demo",
+				"PodDisruptionBudget demo selects the pods of Deployment demo but not those of CloneSet demo",
+			},
+		},
+		{
+			name:    "targeted by reference",
+			objects: []client.Object{pub("deployment", &src.Ref)},
+			expectedProblems: []string{
+				"PodUnavailableBudget deployment targets Deployment demo but no PodUnavailableBudget covers CloneSet demo",
+			},
+		},
+		{
+			name:    "both targeted by reference",
+			objects: []client.Object{pub("deployment", &src.Ref), pub("cloneset", &dst.Ref)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := fake.NewClientBuilder().WithScheme(api.GetScheme()).WithObjects(tc.objects...).Build()
+			problems, err := CheckTraffic(reader, src, dst)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedProblems, problems)
+		})
+	}
+}
+
+func TestReadyEndpointPods(t *testing.T) {
+	svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"}}
+	endpoint := func(pod string, ready *bool) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{
+			Conditions: discoveryv1.EndpointConditions{Ready: ready},
+			TargetRef:  &v1.ObjectReference{Kind: "Pod", Name: pod},
+		}
+	}
+	slices := []client.Object{
+		&discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo-a", Labels: map[string]string{discoveryv1.LabelServiceName: "demo"}},
+			Endpoints:  []discoveryv1.Endpoint{endpoint("pod-a", nil), endpoint("pod-b", ptr.To(false))},
+		},
+		&discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo-b", Labels: map[string]string{discoveryv1.LabelServiceName: "demo"}},
+			Endpoints:  []discoveryv1.Endpoint{endpoint("pod-c", ptr.To(true)), {Addresses: []string{"10.0.0.1"}}},
+		},
+		&discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "other", Labels: map[string]string{discoveryv1.LabelServiceName: "other"}},
+			Endpoints:  []discoveryv1.Endpoint{endpoint("pod-d", nil)},
+		},
+	}
+
+	reader := fake.NewClientBuilder().WithScheme(api.GetScheme()).WithObjects(slices...).Build()
+	pods, err := ReadyEndpointPods(reader, svc)
+	assert.NoError(t, err)
+	assert.Equal(t, sets.New("pod-a", "pod-c"), pods)
+}
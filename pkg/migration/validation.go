@@ -0,0 +1,206 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// VerticalPodAutoscalerKind is the kind of the VerticalPodAutoscalers that may target the source,
+// which are read as unstructured objects since they are not always installed.
+var VerticalPodAutoscalerKind = schema.GroupVersionKind{Group: "autoscaling.k8s.io", Version: "v1", Kind: "VerticalPodAutoscaler"}
+
+// Validation is the result of the pre-flight validation of a task, before any scaling starts.
+type Validation struct {
+	// Errors refuse the task.
+	Errors []string `json:"errors,omitempty"`
+	// Warnings do not refuse the task, but should be looked at before it runs.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Err returns an error of all Errors, or nil if there is none.
+func (v *Validation) Err() error {
+	if len(v.Errors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("pre-flight validation failed: %s", strings.Join(v.Errors, "; "))
+}
+
+// Validator is implemented by the controls that validate a task before submitting it,
+// so that the warnings can be shown to the user.
+type Validator interface {
+	Validate(src api.ResourceRef, dst api.ResourceRef, opts Options) (Validation, error)
+}
+
+// Workload is a workload of a task as read by the validation.
+type Workload struct {
+	Ref            api.ResourceRef
+	UID            types.UID
+	Selector       *metav1.LabelSelector
+	TemplateLabels map[string]string
+}
+
+// ValidateWorkloads checks that the selector of dst selects no pod that another controller than src or dst
+// would fight over, that no pod template sets the label of the ReplicaSets of a Deployment, and flags
+// the autoscalers targeting src. partial is true if the task only migrates some of the replicas of src.
+func ValidateWorkloads(reader client.Reader, src, dst Workload, partial bool) (Validation, error) {
+	v := Validation{}
+	for _, w := range []Workload{src, dst} {
+		if _, ok := w.TemplateLabels[apps.DefaultDeploymentUniqueLabelKey]; ok && w.Ref.GetGroupVersionKind() != api.DeploymentKind {
+			v.Errors = append(v.Errors, fmt.Sprintf("pod template of %s %s sets the %s label, which collides with the pods of ReplicaSets",
+				w.Ref.Kind, w.Ref.Name, apps.DefaultDeploymentUniqueLabelKey))
+		}
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(dst.Selector)
+	if err != nil {
+		return v, fmt.Errorf("failed to parse selector of %s %s: %v", dst.Ref.Kind, dst.Ref.Name, err)
+	}
+	if selector.Empty() || !selector.Matches(labels.Set(dst.TemplateLabels)) {
+		v.Errors = append(v.Errors, fmt.Sprintf("selector of %s %s does not select its own pods", dst.Ref.Kind, dst.Ref.Name))
+		return v, nil
+	}
+	if !selector.Matches(labels.Set(src.TemplateLabels)) {
+		v.Warnings = append(v.Warnings, fmt.Sprintf("selector of %s %s does not select the pods of %s %s, so the migrated pods may not be served alike",
+			dst.Ref.Kind, dst.Ref.Name, src.Ref.Kind, src.Ref.Name))
+	}
+
+	problems, err := checkSelectedPods(reader, selector, src, dst)
+	if err != nil {
+		return v, err
+	}
+	v.Errors = append(v.Errors, problems...)
+
+	autoscalers, err := listAutoscalers(reader, src.Ref.Namespace)
+	if err != nil {
+		return v, err
+	}
+	for i := range autoscalers {
+		if !autoscalerTargets(&autoscalers[i], src.Ref) {
+			continue
+		} else if partial {
+			// the autoscaler is restored as it was, since src keeps some of the replicas
+			v.Warnings = append(v.Warnings, fmt.Sprintf("%s %s targets %s %s, it is suspended during the migration and resumed "+
+				"without being retargeted, since only some of the replicas are migrated to %s %s",
+				autoscalers[i].GetKind(), autoscalers[i].GetName(), src.Ref.Kind, src.Ref.Name, dst.Ref.Kind, dst.Ref.Name))
+		} else {
+			v.Warnings = append(v.Warnings, fmt.Sprintf("%s %s targets %s %s, it is suspended during the migration and retargeted to %s %s once it succeeds",
+				autoscalers[i].GetKind(), autoscalers[i].GetName(), src.Ref.Kind, src.Ref.Name, dst.Ref.Kind, dst.Ref.Name))
+		}
+	}
+	vpas, err := listVerticalPodAutoscalers(reader, src.Ref)
+	if err != nil {
+		return v, err
+	}
+	for _, name := range vpas {
+		v.Warnings = append(v.Warnings, fmt.Sprintf("VerticalPodAutoscaler %s targets %s %s and needs to be retargeted to %s %s",
+			name, src.Ref.Kind, src.Ref.Name, dst.Ref.Kind, dst.Ref.Name))
+	}
+	return v, nil
+}
+
+// checkSelectedPods returns the problems of the pods selected by the selector of dst that are controlled
+// neither by src nor by dst, either directly or through their ReplicaSets.
+func checkSelectedPods(reader client.Reader, selector labels.Selector, src, dst Workload) ([]string, error) {
+	owners := sets.New[types.UID](src.UID, dst.UID)
+	rsList := &apps.ReplicaSetList{}
+	if err := reader.List(context.TODO(), rsList, client.InNamespace(dst.Ref.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list ReplicaSets: %v", err)
+	}
+	for i := range rsList.Items {
+		if owner := metav1.GetControllerOf(&rsList.Items[i]); owner != nil && owners.Has(owner.UID) {
+			owners.Insert(rsList.Items[i].UID)
+		}
+	}
+
+	podList := &v1.PodList{}
+	if err := reader.List(context.TODO(), podList, client.InNamespace(dst.Ref.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+	var orphans []string
+	others := make(map[string][]string)
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		owner := metav1.GetControllerOf(pod)
+		if owner == nil {
+			orphans = append(orphans, pod.Name)
+		} else if !owners.Has(owner.UID) {
+			controller := fmt.Sprintf("%s %s", owner.Kind, owner.Name)
+			others[controller] = append(others[controller], pod.Name)
+		}
+	}
+
+	var problems []string
+	if len(orphans) > 0 {
+		sort.Strings(orphans)
+		problems = append(problems, fmt.Sprintf("%s %s would adopt the pods with no controller: %s",
+			dst.Ref.Kind, dst.Ref.Name, strings.Join(orphans, ", ")))
+	}
+	controllers := make([]string, 0, len(others))
+	for controller := range others {
+		controllers = append(controllers, controller)
+	}
+	sort.Strings(controllers)
+	for _, controller := range controllers {
+		sort.Strings(others[controller])
+		problems = append(problems, fmt.Sprintf("selector of %s %s overlaps with %s, the controllers would fight over the pods: %s",
+			dst.Ref.Kind, dst.Ref.Name, controller, strings.Join(others[controller], ", ")))
+	}
+	return problems, nil
+}
+
+// listVerticalPodAutoscalers returns the VerticalPodAutoscalers targeting the workload,
+// or none if they are not installed.
+func listVerticalPodAutoscalers(reader client.Reader, ref api.ResourceRef) ([]string, error) {
+	vpaList := &unstructured.UnstructuredList{}
+	vpaList.SetGroupVersionKind(VerticalPodAutoscalerKind.GroupVersion().WithKind(VerticalPodAutoscalerKind.Kind + "List"))
+	if err := reader.List(context.TODO(), vpaList, client.InNamespace(ref.Namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list VerticalPodAutoscalers: %v", err)
+	}
+	var names []string
+	for _, vpa := range vpaList.Items {
+		apiVersion, _, _ := unstructured.NestedString(vpa.Object, "spec", "targetRef", "apiVersion")
+		kind, _, _ := unstructured.NestedString(vpa.Object, "spec", "targetRef", "kind")
+		name, _, _ := unstructured.NestedString(vpa.Object, "spec", "targetRef", "name")
+		if targetsRef(ref, apiVersion, kind, name) {
+			names = append(names, vpa.GetName())
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2020 The Kruise Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"testing"
+
+	"github.com/openkruise/kruise-tools/pkg/api"
+	"github.com/stretchr/testify/assert"
+
+	apps "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestValidateWorkloads(t *testing.T) {
+	labels := map[string]string{"app": "demo"}
+	src := Workload{Ref: api.NewDeploymentRef("default", "demo"), UID: "deployment-uid",
+		Selector: &metav1.LabelSelector{MatchLabels: labels}, TemplateLabels: labels}
+	dst := Workload{Ref: api.NewCloneSetRef("default", "demo"), UID: "cloneset-uid",
+		Selector: &metav1.LabelSelector{MatchLabels: labels}, TemplateLabels: labels}
+
+	controllerRef := func(kind, name string, uid types.UID) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: kind, Name: name, UID: uid, Controller: ptr.To(true)}}
+	}
+	replicaSet := &apps.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo-abc", UID: "rs-uid",
+		OwnerReferences: controllerRef("Deployment", "demo", src.UID)}}
+	pod := func(name string, owners []metav1.OwnerReference) client.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name, Labels: labels, OwnerReferences: owners}}
+	}
+
+	testCases := []struct {
+		name               string
+		dst                *Workload
+		partial            bool
+		objects            []client.Object
+		expectedValidation Validation
+	}{
+		{
+			name: "pods of src and dst",
+			objects: []client.Object{
+				replicaSet,
+				pod("demo-abc-1", controllerRef("ReplicaSet", "demo-abc", "rs-uid")),
+				pod("demo-2", controllerRef("CloneSet", "demo", dst.UID)),
+			},
+		},
+		{
+			name: "selector overlaps",
+			objects: []client.Object{
+				pod("orphan-2", nil),
+				pod("orphan-1", nil),
+				pod("other-1", controllerRef("ReplicaSet", "other-abc", "other-uid")),
+			},
+			expectedValidation: Validation{Errors: []string{
+				"CloneSet demo would adopt the pods with no controller: orphan-1, orphan-2",
+				"selector of CloneSet demo overlaps with ReplicaSet other-abc, the controllers would fight over the pods: other-1",
+			}},
+		},
+		{
+			name: "pod-template-hash label",
+			dst: &Workload{Ref: dst.Ref, UID: dst.UID, Selector: dst.Selector,
+				TemplateLabels: map[string]string{"app": "demo", apps.DefaultDeploymentUniqueLabelKey: "abc"}},
+			expectedValidation: Validation{Errors: []string{
+				"pod template of CloneSet demo sets the pod-template-hash label, which collides with the pods of ReplicaSets",
+			}},
+		},
+		{
+			name: "selector does not select own pods",
+			dst: &Workload{Ref: dst.Ref, UID: dst.UID, Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "other"}},
+				TemplateLabels: labels},
+			expectedValidation: Validation{Errors: []string{"selector of CloneSet demo does not select its own pods"}},
+		},
+		{
+			name: "selector does not select pods of src",
+			dst: &Workload{Ref: dst.Ref, UID: dst.UID, Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo", "kind": "cloneset"}},
+				TemplateLabels: map[string]string{"app": "demo", "kind": "cloneset"}},
+			expectedValidation: Validation{Warnings: []string{
+				"selector of CloneSet demo does not select the pods of Deployment demo, so the migrated pods may not be served alike",
+			}},
+		},
+		{
+			name: "autoscaler targets src",
+			objects: []client.Object{
+				&autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"},
+					Spec: autoscalingv2.HorizontalPodAutoscalerSpec{ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+						APIVersion: "apps/v1", Kind: "Deployment", Name: "demo"}}},
+				&autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "other"},
+					Spec: autoscalingv2.HorizontalPodAutoscalerSpec{ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+						APIVersion: "apps/v1", Kind: "Deployment", Name: "other"}}},
+			},
+			expectedValidation: Validation{Warnings: []string{
+				"HorizontalPodAutoscaler demo targets Deployment demo, it is suspended during the migration and retargeted to CloneSet demo once it succeeds",
+			}},
+		},
+		{
+			name:    "autoscaler targets src of partial migration",
+			partial: true,
+			objects: []client.Object{
+				&autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"},
+					Spec: autoscalingv2.HorizontalPodAutoscalerSpec{ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+						APIVersion: "apps/v1", Kind: "Deployment", Name: "demo"}}},
+			},
+			expectedValidation: Validation{Warnings: []string{
+				"HorizontalPodAutoscaler demo targets Deployment demo, it is suspended during the migration and resumed " +
+					"without being retargeted, since only some of the replicas are migrated to CloneSet demo",
+			}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := fake.NewClientBuilder().WithScheme(api.GetScheme()).WithObjects(tc.objects...).Build()
+			dstWorkload := dst
+			if tc.dst != nil {
+				dstWorkload = *tc.dst
+			}
+			validation, err := ValidateWorkloads(reader, src, dstWorkload, tc.partial)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedValidation, validation)
+		})
+	}
+}
@@ -25,3 +25,13 @@ func Int32Min(a int32, items ...int32) int32 {
 	}
 	return min
 }
+
+func Int32Max(a int32, items ...int32) int32 {
+	max := a
+	for _, i := range items {
+		if max < i {
+			max = i
+		}
+	}
+	return max
+}
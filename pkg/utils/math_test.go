@@ -71,3 +71,45 @@ func TestInt32Min(t *testing.T) {
 		})
 	}
 }
+
+func TestInt32Max(t *testing.T) {
+	testCases := []struct {
+		name     string
+		a        int32
+		items    []int32
+		expected int32
+	}{
+		{
+			name:     "No extra items",
+			a:        10,
+			items:    []int32{},
+			expected: 10,
+		},
+		{
+			name:     "All positive numbers",
+			a:        10,
+			items:    []int32{5, 20, 12},
+			expected: 20,
+		},
+		{
+			name:     "With negative numbers",
+			a:        -5,
+			items:    []int32{-10, -2, -7},
+			expected: -2,
+		},
+		{
+			name:     "Initial value 'a' is the maximum",
+			a:        30,
+			items:    []int32{10, 5, 8},
+			expected: 30,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Int32Max(tc.a, tc.items...); got != tc.expected {
+				t.Errorf("Int32Max() = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}